@@ -8,14 +8,28 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/log"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/config"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/handler/http"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/backend"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/mfa"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/policies"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/rbac"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tenant"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tokenhook"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/user"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/webauthn"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
 	"github.com/yokeTH/yoketh-backend-oss/pkg/httpserver"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/policy"
 )
 
 func main() {
@@ -37,21 +51,182 @@ func main() {
 
 	queries := db.New(sqlDB)
 
-	keyWrapper, err := key.NewLocalWrapperFromEnv()
+	keyWrapper, err := envelope.NewLocalWrapperFromEnv("KEK_HEX")
 	if err != nil {
 		panic(err)
 	}
-	keyManager := key.NewManager(sqlDB, queries, keyWrapper, "auth-service")
+	keyStore, err := backend.Open(ctx, cfg.KeyStore, sqlDB, queries)
+	if err != nil {
+		panic(err)
+	}
+	keyManager := key.NewManager(keyStore, keyWrapper, cfg.OAuth.Issuer)
+	if cfg.CA.Enabled {
+		if err := keyManager.InitCA(ctx, cfg.CA); err != nil {
+			panic(err)
+		}
+	}
 	if err := keyManager.Init(ctx); err != nil {
 		panic(err)
 	}
 
-	httpHandler := http.NewHandler(keyManager)
+	redisOpts, err := redis.ParseURL(cfg.Redis.Addr)
+	if err != nil {
+		panic(err)
+	}
+	rdb := redis.NewClient(redisOpts)
+	defer rdb.Close()
+
+	if cfg.OAuth.DPoP.NonceSecret == "" {
+		log.Warn("OAUTH_DPOP_NONCE_SECRET not set, DPoP nonces will not be valid across instances or restarts")
+	}
+	oauthOpts := []oauth.Option{
+		oauth.WithReplayCache(oauth.NewRedisReplayCache(rdb)),
+		oauth.WithDeviceStore(oauth.NewRedisDeviceStore(rdb)),
+		oauth.WithKVStore(oauth.NewRedisKVStore(rdb)),
+	}
+	rbacService := rbac.NewService(queries)
+	if cfg.Policies.URL != "" {
+		policiesClient, err := policies.NewClient(cfg.Policies)
+		if err != nil {
+			panic(err)
+		}
+		defer policiesClient.Close()
+		oauthOpts = append(oauthOpts, oauth.WithPermissionsProvider(policiesClient))
+	} else {
+		oauthOpts = append(oauthOpts, oauth.WithPermissionsProvider(rbacService))
+	}
+	oauthOpts = append(oauthOpts, oauth.WithRolesProvider(rbacService))
+	if cfg.Hook.URL != "" {
+		hook, err := tokenhook.NewClient(cfg.Hook)
+		if err != nil {
+			panic(err)
+		}
+		defer hook.Close()
+		oauthOpts = append(oauthOpts, oauth.WithTokenHook(hook, cfg.Hook.Failure))
+	}
+	oauthService, err := oauth.NewService(cfg.OAuth, queries, keyManager, oauthOpts...)
+	if err != nil {
+		panic(err)
+	}
+	// Logout tokens are stored signed, so the root issuer's service
+	// retries the back-channel logouts of every tenant.
+	go oauthService.RetryLogouts(ctx)
+	userService := user.NewService(queries)
+	tenantService := tenant.NewService(queries, cfg.OAuth.BaseURL)
+	issuers := tenant.NewRegistry(tenantService, keyManager, queries, cfg.OAuth, oauthOpts...)
 
-	s := httpserver.New()
+	var authz policy.Decider
+	if cfg.Authz.PolicyFile != "" {
+		if authz, err = policy.LoadFile(cfg.Authz.PolicyFile); err != nil {
+			panic(err)
+		}
+	}
 
-	wellKnown := s.Group("/.well-known")
-	wellKnown.Get("/jwks.json", httpHandler.HandleJWKS)
+	sessions := session.NewManager(cfg.Session, session.NewFallbackStore(session.NewRedisStore(rdb), session.NewPostgresStore(queries)))
+	mfaService := mfa.NewService(cfg.MFA, queries, keyWrapper, oauth.NewRedisKVStore(rdb))
+	webauthnService, err := webauthn.NewService(cfg.WebAuthn, cfg.OAuth.BaseURL, queries, oauth.NewRedisKVStore(rdb))
+	if err != nil {
+		panic(err)
+	}
+	httpHandler := http.NewHandler(keyManager, oauthService, userService, rbacService, tenantService, issuers, sessions, mfaService, webauthnService, authz)
+
+	s := httpserver.New(httpserver.WithTLS(cfg.Server.TLS))
+
+	if authz == nil {
+		log.Warn("AUTHZ_POLICY_FILE not set, the authorization decision endpoint is disabled")
+	}
+	if cfg.OAuth.Registration.InitialAccessToken == "" {
+		log.Warn("OAUTH_REGISTRATION_INITIAL_ACCESS_TOKEN not set, client registration with the root issuer is disabled")
+	}
+
+	// The root issuer and every tenant's serve the same routes, the latter
+	// below /t/{tenant}.
+	issuerRoutes := func(r fiber.Router) {
+		wellKnown := r.Group("/.well-known")
+		wellKnown.Get("/jwks.json", httpHandler.HandleJWKS)
+		wellKnown.Get("/paserk.json", httpHandler.HandlePASERK)
+		wellKnown.Get("/oauth-authorization-server", httpHandler.HandleMetadata)
+
+		r.Get(oauth.AuthorizePath, httpHandler.HandleAuthorize)
+		r.Post(oauth.AuthorizePath, httpHandler.HandleAuthorizeDecision)
+		r.Get(oauth.EndSessionPath, httpHandler.HandleEndSession)
+		r.Post(oauth.EndSessionPath, httpHandler.HandleEndSession)
+		r.Post(oauth.PARPath, httpHandler.HandlePAR)
+		r.Post(oauth.TokenPath, httpHandler.HandleToken)
+		r.Post(oauth.DeviceAuthorizationPath, httpHandler.HandleDeviceAuthorization)
+		r.Get(oauth.DeviceVerificationPath, httpHandler.HandleDevicePage)
+		r.Post(oauth.DeviceVerificationPath, httpHandler.HandleDeviceDecision)
+		r.Get(webauthn.ScriptPath, httpHandler.HandleWebAuthnScript)
+		r.Get(webauthn.RegisterPath, httpHandler.HandleWebAuthnPage)
+		r.Post(webauthn.RegisterOptionsPath, httpHandler.HandleWebAuthnRegisterOptions)
+		r.Post(webauthn.RegisterPath, httpHandler.HandleWebAuthnRegister)
+		r.Post(webauthn.LoginOptionsPath, httpHandler.HandleWebAuthnLoginOptions)
+
+		if authz != nil {
+			r.Post(policy.CheckPath, httpHandler.HandleAuthzCheck)
+		}
+
+		// Registration needs the issuer's initial access token, which
+		// tenants may be issued at any time.
+		r.Post(oauth.RegistrationPath, httpHandler.HandleRegisterClient)
+		r.Get(oauth.RegistrationPath+"/:client_id", httpHandler.HandleReadClient)
+		r.Put(oauth.RegistrationPath+"/:client_id", httpHandler.HandleUpdateClient)
+		r.Delete(oauth.RegistrationPath+"/:client_id", httpHandler.HandleDeleteClient)
+	}
+	issuerRoutes(s)
+	issuerRoutes(s.Group(tenant.PathPrefix+":tenant", httpHandler.ResolveTenant))
+	// RFC 8414 section 3.1 inserts the well-known path before the issuer's.
+	s.Get(oauth.MetadataPath+tenant.PathPrefix+":tenant", httpHandler.ResolveTenant, httpHandler.HandleMetadata)
+
+	if cfg.Admin.Token != "" {
+		admin := s.Group("/admin", http.AdminAuth(cfg.Admin.Token))
+		admin.Get("/tenants", httpHandler.HandleListTenants)
+		admin.Post("/tenants", httpHandler.HandleCreateTenant)
+		admin.Get("/tenants/:tenant", httpHandler.HandleGetTenant)
+		admin.Put("/tenants/:tenant", httpHandler.HandleUpdateTenant)
+		admin.Post("/tenants/:tenant/registration-token", httpHandler.HandleIssueRegistrationToken)
+		admin.Delete("/tenants/:tenant/registration-token", httpHandler.HandleDisableRegistration)
+
+		// Signing keys, users, roles, audiences, resource servers and
+		// client token settings belong to an issuer: the root issuer's are
+		// managed below /admin, a tenant's below /admin/tenants/{tenant}.
+		issuerAdminRoutes := func(r fiber.Router) {
+			r.Post("/keys/import", httpHandler.HandleImportKey)
+			r.Post("/keys/:kid/retire", httpHandler.HandleRetireKey)
+			r.Post("/users", httpHandler.HandleCreateUser)
+			r.Get("/users/:id/mfa", httpHandler.HandleGetMFA)
+			r.Post("/users/:id/totp", httpHandler.HandleEnrollTOTP)
+			r.Post("/users/:id/totp/confirm", httpHandler.HandleConfirmTOTP)
+			r.Delete("/users/:id/totp", httpHandler.HandleDeleteTOTP)
+			r.Post("/users/:id/recovery-codes", httpHandler.HandleRegenerateRecoveryCodes)
+			r.Get("/users/:id/webauthn-credentials", httpHandler.HandleListWebAuthnCredentials)
+			r.Delete("/users/:id/webauthn-credentials/:credential_id", httpHandler.HandleDeleteWebAuthnCredential)
+			r.Post("/resource-servers", httpHandler.HandleRegisterResourceServer)
+			r.Delete("/resource-servers", httpHandler.HandleDeleteResourceServer)
+			r.Post("/audiences", httpHandler.HandleSetAudience)
+			r.Delete("/audiences", httpHandler.HandleDeleteAudience)
+			r.Get("/permissions", httpHandler.HandleListPermissions)
+			r.Post("/permissions", httpHandler.HandleCreatePermission)
+			r.Delete("/permissions/:name", httpHandler.HandleDeletePermission)
+			r.Get("/roles", httpHandler.HandleListRoles)
+			r.Post("/roles", httpHandler.HandleCreateRole)
+			r.Get("/roles/:name", httpHandler.HandleGetRole)
+			r.Delete("/roles/:name", httpHandler.HandleDeleteRole)
+			r.Put("/roles/:name/permissions/:permission", httpHandler.HandleGrantPermission)
+			r.Delete("/roles/:name/permissions/:permission", httpHandler.HandleRevokePermission)
+			r.Get("/role-bindings", httpHandler.HandleListRoleBindings)
+			r.Post("/role-bindings", httpHandler.HandleCreateRoleBinding)
+			r.Delete("/role-bindings", httpHandler.HandleDeleteRoleBinding)
+			r.Get("/clients/:client_id/token-settings", httpHandler.HandleGetClientTokenSettings)
+			r.Put("/clients/:client_id/token-settings", httpHandler.HandleSetClientTokenSettings)
+		}
+		issuerAdminRoutes(admin)
+		// Registered after the tenant routes above, which do not need the
+		// tenant's issuer.
+		issuerAdminRoutes(admin.Group("/tenants/:tenant", httpHandler.ResolveTenant))
+	} else {
+		log.Warn("ADMIN_TOKEN not set, admin routes are disabled")
+	}
 
 	s.Start(ctx, stop)
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/config"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/backend"
+)
+
+func main() {
+	out := flag.String("out", "", "write the PEM certificate to this file instead of stdout")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Warnf("Unable to load .env file: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	cfg := config.NewFromEnv()
+	dbCfg := cfg.DBConfig
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", dbCfg.Host, dbCfg.Port, dbCfg.User, dbCfg.Password, dbCfg.DBName, dbCfg.SSLMode)
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	queries := db.New(sqlDB)
+
+	keyStore, err := backend.Open(ctx, cfg.KeyStore, sqlDB, queries)
+	if err != nil {
+		panic(err)
+	}
+	// Exporting the certificate never unseals the CA key, so no KEK is needed.
+	keyManager := key.NewManager(keyStore, nil, cfg.OAuth.Issuer)
+
+	cert, err := keyManager.CACertificate(ctx)
+	if err != nil {
+		log.Fatalf("Unable to load CA certificate: %s", err)
+	}
+
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if *out == "" {
+		_, _ = os.Stdout.Write(block)
+		return
+	}
+	if err := os.WriteFile(*out, block, 0o644); err != nil {
+		log.Fatalf("Unable to write CA certificate: %s", err)
+	}
+	log.Infof("Wrote CA certificate %q to %s", cert.Subject.CommonName, *out)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/config"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/backend"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tenant"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
+)
+
+func main() {
+	file := flag.String("file", "", "path to the private key (PEM, JWK or PKCS#12)")
+	format := flag.String("format", "", "key format: pem, jwk or pkcs12 (default: from file extension)")
+	kid := flag.String("kid", "", "kid to store the key under (default: JWK kid or generated)")
+	alg := flag.String("alg", "", "signing alg (default: derived from the key)")
+	status := flag.String("status", key.StatusActive, "key status: ACTIVE, RETIRING or RETIRED")
+	tenantID := flag.String("tenant", "", "tenant to import the key for (default: the root issuer)")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Warnf("Unable to load .env file: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		panic(err)
+	}
+
+	f := key.ImportFormat(*format)
+	if f == "" {
+		f = formatFromExt(*file)
+	}
+
+	cfg := config.NewFromEnv()
+	dbCfg := cfg.DBConfig
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", dbCfg.Host, dbCfg.Port, dbCfg.User, dbCfg.Password, dbCfg.DBName, dbCfg.SSLMode)
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	queries := db.New(sqlDB)
+
+	keyWrapper, err := envelope.NewLocalWrapperFromEnv("KEK_HEX")
+	if err != nil {
+		panic(err)
+	}
+	keyStore, err := backend.Open(ctx, cfg.KeyStore, sqlDB, queries)
+	if err != nil {
+		panic(err)
+	}
+	keyManager := key.NewManager(keyStore, keyWrapper, cfg.OAuth.Issuer)
+	if cfg.CA.Enabled {
+		if err := keyManager.InitCA(ctx, cfg.CA); err != nil {
+			panic(err)
+		}
+	}
+	if *tenantID != "" {
+		t, err := tenant.NewService(queries, cfg.OAuth.BaseURL).Get(ctx, *tenantID)
+		if err != nil {
+			log.Fatalf("Unable to find tenant %s: %s", *tenantID, err)
+		}
+		keyManager = keyManager.ForTenant(t.ID, t.Issuer)
+	}
+
+	importedKID, importedAlg, err := keyManager.Import(ctx, key.ImportParams{
+		KID:      *kid,
+		Alg:      *alg,
+		Status:   strings.ToUpper(*status),
+		Format:   f,
+		Data:     data,
+		Password: os.Getenv("KEY_PASSWORD"),
+	})
+	if err != nil {
+		log.Fatalf("Unable to import key: %s", err)
+	}
+
+	log.Infof("Imported key %s (%s) as %s", importedKID, importedAlg, strings.ToUpper(*status))
+}
+
+func formatFromExt(path string) key.ImportFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".jwk":
+		return key.FormatJWK
+	case ".p12", ".pfx":
+		return key.FormatPKCS12
+	default:
+		return key.FormatPEM
+	}
+}
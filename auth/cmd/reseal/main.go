@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/config"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/backend"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Warnf("Unable to load .env file: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	cfg := config.NewFromEnv()
+	dbCfg := cfg.DBConfig
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", dbCfg.Host, dbCfg.Port, dbCfg.User, dbCfg.Password, dbCfg.DBName, dbCfg.SSLMode)
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	queries := db.New(sqlDB)
+
+	keyWrapper, err := envelope.NewLocalWrapperFromEnv("KEK_HEX")
+	if err != nil {
+		panic(err)
+	}
+	keyStore, err := backend.Open(ctx, cfg.KeyStore, sqlDB, queries)
+	if err != nil {
+		panic(err)
+	}
+	keyManager := key.NewManager(keyStore, keyWrapper, cfg.OAuth.Issuer)
+
+	n, err := keyManager.UpgradeEnvelopes(ctx)
+	if err != nil {
+		log.Fatalf("Unable to upgrade key envelopes: %s", err)
+	}
+	log.Infof("Upgraded %d key(s) to envelope version %d", n, key.CurrentEnvelopeVersion)
+
+	stop()
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v3/log"
 	"github.com/joho/godotenv"
@@ -14,6 +15,9 @@ import (
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/config"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/backend"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tenant"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
 )
 
 func main() {
@@ -35,16 +39,50 @@ func main() {
 
 	queries := db.New(sqlDB)
 
-	keyWrapper, err := key.NewLocalWrapperFromEnv()
+	keyWrapper, err := envelope.NewLocalWrapperFromEnv("KEK_HEX")
 	if err != nil {
 		panic(err)
 	}
-	keyManager := key.NewManager(sqlDB, queries, keyWrapper, "auth-service")
+	keyStore, err := backend.Open(ctx, cfg.KeyStore, sqlDB, queries)
+	if err != nil {
+		panic(err)
+	}
+	keyManager := key.NewManager(keyStore, keyWrapper, cfg.OAuth.Issuer)
+	if cfg.CA.Enabled {
+		if err := keyManager.InitCA(ctx, cfg.CA); err != nil {
+			panic(err)
+		}
+	}
 	if err := keyManager.Init(ctx); err != nil {
 		panic(err)
 	}
 
-	keyManager.Rotate(ctx)
+	// Every issuer rotates on its own schedule; a failing one does not
+	// hold back the others.
+	failed := false
+	rotate := func(name string, m *key.Manager, period time.Duration) {
+		kids, err := m.RotateDue(ctx, period)
+		for _, kid := range kids {
+			log.Infof("Rotated the keys of %s, new kid %s", name, kid)
+		}
+		if err != nil {
+			log.Errorf("Unable to rotate the keys of %s: %s", name, err)
+			failed = true
+		}
+	}
+
+	rotate("the root issuer", keyManager, cfg.KeyStore.RotationPeriod)
+
+	tenants, err := tenant.NewService(queries, cfg.OAuth.BaseURL).List(ctx)
+	if err != nil {
+		log.Fatalf("Unable to list tenants: %s", err)
+	}
+	for _, t := range tenants {
+		rotate("tenant "+t.ID, keyManager.ForTenant(t.ID, t.Issuer), t.RotationPeriod())
+	}
 
 	stop()
+	if failed {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,7 @@
+-- fails while version 1 rows exist, since their priv_nonce is NULL
+ALTER TABLE jwk_keys
+ALTER COLUMN priv_nonce
+SET NOT NULL;
+
+ALTER TABLE jwk_keys
+DROP COLUMN IF EXISTS envelope_version;
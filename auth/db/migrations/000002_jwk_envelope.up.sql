@@ -0,0 +1,9 @@
+-- envelope_version 0: priv_ciphertext/priv_nonce are raw AES-GCM( DEK, pkcs8, aad="PRIV:"+kid )
+-- envelope_version 1: priv_ciphertext is a JSON envelope {v, suite, nonce, ct} whose
+--                     aad binds v, suite, kid, alg and kek_ref; priv_nonce is NULL
+ALTER TABLE jwk_keys
+ADD COLUMN envelope_version SMALLINT NOT NULL DEFAULT 0;
+
+ALTER TABLE jwk_keys
+ALTER COLUMN priv_nonce
+DROP NOT NULL;
@@ -0,0 +1,10 @@
+-- internal CA issuing x5c certificates for signing keys, sealed like jwk_keys rows
+CREATE TABLE IF NOT EXISTS jwk_ca (
+  id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id), -- at most one CA
+  cert_der BYTEA NOT NULL,
+  priv_ciphertext BYTEA NOT NULL,
+  wrapped_dek BYTEA NOT NULL,
+  kek_ref TEXT,
+  envelope_version SMALLINT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,13 @@
+-- OAuth 2.0 clients allowed to call the token endpoint
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  client_id TEXT PRIMARY KEY,
+  client_secret_hash TEXT, -- bcrypt, NULL for public clients
+  name TEXT NOT NULL DEFAULT '',
+  grant_types TEXT[] NOT NULL DEFAULT '{client_credentials}',
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  token_endpoint_auth_method TEXT NOT NULL DEFAULT 'client_secret_basic' CHECK (
+    token_endpoint_auth_method IN ('client_secret_basic', 'client_secret_post', 'none')
+  ),
+  dpop_bound_access_tokens BOOLEAN NOT NULL DEFAULT FALSE, -- RFC 9449 section 5.2
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
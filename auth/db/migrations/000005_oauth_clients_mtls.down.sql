@@ -0,0 +1,20 @@
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS tls_client_certificate_bound_access_tokens,
+DROP COLUMN IF EXISTS jwks,
+DROP COLUMN IF EXISTS tls_client_auth_san_email,
+DROP COLUMN IF EXISTS tls_client_auth_san_ip,
+DROP COLUMN IF EXISTS tls_client_auth_san_uri,
+DROP COLUMN IF EXISTS tls_client_auth_san_dns,
+DROP COLUMN IF EXISTS tls_client_auth_subject_dn;
+
+DELETE FROM oauth_clients
+WHERE
+  token_endpoint_auth_method IN ('tls_client_auth', 'self_signed_tls_client_auth');
+
+ALTER TABLE oauth_clients
+DROP CONSTRAINT IF EXISTS oauth_clients_token_endpoint_auth_method_check;
+
+ALTER TABLE oauth_clients
+ADD CONSTRAINT oauth_clients_token_endpoint_auth_method_check CHECK (
+  token_endpoint_auth_method IN ('client_secret_basic', 'client_secret_post', 'none')
+);
@@ -0,0 +1,23 @@
+-- mutual-TLS client authentication and certificate-bound tokens (RFC 8705)
+ALTER TABLE oauth_clients
+DROP CONSTRAINT IF EXISTS oauth_clients_token_endpoint_auth_method_check;
+
+ALTER TABLE oauth_clients
+ADD CONSTRAINT oauth_clients_token_endpoint_auth_method_check CHECK (
+  token_endpoint_auth_method IN (
+    'client_secret_basic',
+    'client_secret_post',
+    'tls_client_auth',
+    'self_signed_tls_client_auth',
+    'none'
+  )
+);
+
+ALTER TABLE oauth_clients
+ADD COLUMN tls_client_auth_subject_dn TEXT,
+ADD COLUMN tls_client_auth_san_dns TEXT,
+ADD COLUMN tls_client_auth_san_uri TEXT,
+ADD COLUMN tls_client_auth_san_ip TEXT,
+ADD COLUMN tls_client_auth_san_email TEXT,
+ADD COLUMN jwks JSONB NOT NULL DEFAULT '{"keys": []}', -- x5c of self-signed client certificates
+ADD COLUMN tls_client_certificate_bound_access_tokens BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS oauth_trusted_issuers;
+
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS jwks_uri;
+
+DELETE FROM oauth_clients
+WHERE
+  token_endpoint_auth_method = 'private_key_jwt';
+
+ALTER TABLE oauth_clients
+DROP CONSTRAINT IF EXISTS oauth_clients_token_endpoint_auth_method_check;
+
+ALTER TABLE oauth_clients
+ADD CONSTRAINT oauth_clients_token_endpoint_auth_method_check CHECK (
+  token_endpoint_auth_method IN (
+    'client_secret_basic',
+    'client_secret_post',
+    'tls_client_auth',
+    'self_signed_tls_client_auth',
+    'none'
+  )
+);
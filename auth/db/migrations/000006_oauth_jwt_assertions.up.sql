@@ -0,0 +1,27 @@
+-- private_key_jwt client authentication and the jwt-bearer grant (RFC 7523)
+ALTER TABLE oauth_clients
+DROP CONSTRAINT IF EXISTS oauth_clients_token_endpoint_auth_method_check;
+
+ALTER TABLE oauth_clients
+ADD CONSTRAINT oauth_clients_token_endpoint_auth_method_check CHECK (
+  token_endpoint_auth_method IN (
+    'client_secret_basic',
+    'client_secret_post',
+    'private_key_jwt',
+    'tls_client_auth',
+    'self_signed_tls_client_auth',
+    'none'
+  )
+);
+
+ALTER TABLE oauth_clients
+ADD COLUMN jwks_uri TEXT; -- fetched when jwks has no matching key
+
+-- federated issuers whose assertions are accepted by the jwt-bearer grant
+CREATE TABLE IF NOT EXISTS oauth_trusted_issuers (
+  issuer TEXT PRIMARY KEY,
+  jwks_uri TEXT,
+  jwks JSONB NOT NULL DEFAULT '{"keys": []}',
+  scopes TEXT[] NOT NULL DEFAULT '{}', -- upper bound for scopes granted to its subjects
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,7 @@
+-- end users who approve device codes and, later, sign in through the browser
+CREATE TABLE IF NOT EXISTS users (
+  id TEXT PRIMARY KEY,
+  username TEXT NOT NULL UNIQUE,
+  password_hash TEXT NOT NULL, -- bcrypt
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
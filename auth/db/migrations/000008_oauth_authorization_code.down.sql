@@ -0,0 +1,3 @@
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS require_pushed_authorization_requests,
+DROP COLUMN IF EXISTS redirect_uris;
@@ -0,0 +1,4 @@
+-- authorization code flow, pushed authorization requests (RFC 9126)
+ALTER TABLE oauth_clients
+ADD COLUMN redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN require_pushed_authorization_requests BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS updated_at,
+DROP COLUMN IF EXISTS registration_access_token_hash;
@@ -0,0 +1,4 @@
+-- dynamic client registration and management (RFC 7591, RFC 7592)
+ALTER TABLE oauth_clients
+ADD COLUMN registration_access_token_hash TEXT, -- SHA-256, NULL for clients not registered dynamically
+ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
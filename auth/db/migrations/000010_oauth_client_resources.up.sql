@@ -0,0 +1,3 @@
+-- resource indicators (RFC 8707) a client may request tokens for
+ALTER TABLE oauth_clients
+ADD COLUMN resources TEXT[] NOT NULL DEFAULT '{}';
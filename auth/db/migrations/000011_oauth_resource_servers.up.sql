@@ -0,0 +1,8 @@
+-- resource servers that receive encrypted (JWE) access tokens
+CREATE TABLE IF NOT EXISTS oauth_resource_servers (
+  resource TEXT PRIMARY KEY, -- resource indicator (RFC 8707), the aud of its tokens
+  encryption_jwk JSONB NOT NULL, -- public key tokens for this resource are encrypted to
+  encryption_alg TEXT NOT NULL CHECK (encryption_alg IN ('ECDH-ES+A256KW', 'RSA-OAEP-256')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS oauth_audiences;
+
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS access_token_format;
+
+DELETE FROM jwk_keys
+WHERE
+  purpose <> 'jwt';
+
+ALTER TABLE jwk_keys
+DROP COLUMN IF EXISTS purpose;
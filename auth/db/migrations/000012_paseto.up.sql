@@ -0,0 +1,15 @@
+-- PASETO v4.public access tokens: Ed25519 keys live next to the JWT signing
+-- keys and rotate separately, selected by purpose
+ALTER TABLE jwk_keys
+ADD COLUMN purpose TEXT NOT NULL DEFAULT 'jwt' CHECK (purpose IN ('jwt', 'paseto'));
+
+ALTER TABLE oauth_clients
+ADD COLUMN access_token_format TEXT NOT NULL DEFAULT 'jwt' CHECK (access_token_format IN ('jwt', 'paseto'));
+
+-- token settings of an audience (resource indicator), overriding the client's
+CREATE TABLE IF NOT EXISTS oauth_audiences (
+  audience TEXT PRIMARY KEY,
+  access_token_format TEXT NOT NULL CHECK (access_token_format IN ('jwt', 'paseto')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS rbac_role_bindings;
+
+DROP TABLE IF EXISTS rbac_role_permissions;
+
+DROP TABLE IF EXISTS rbac_roles;
+
+DROP TABLE IF EXISTS rbac_permissions;
@@ -0,0 +1,31 @@
+-- built-in role based access control, the source of the permissions claim
+-- when no policies service is configured
+CREATE TABLE IF NOT EXISTS rbac_permissions (
+  name TEXT PRIMARY KEY, -- as carried in the permissions claim, e.g. orders:read
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS rbac_roles (
+  name TEXT PRIMARY KEY,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS rbac_role_permissions (
+  role TEXT NOT NULL REFERENCES rbac_roles (name) ON DELETE CASCADE,
+  permission TEXT NOT NULL REFERENCES rbac_permissions (name) ON DELETE CASCADE,
+  PRIMARY KEY (role, permission)
+);
+
+CREATE INDEX IF NOT EXISTS rbac_role_permissions_permission_idx ON rbac_role_permissions (permission);
+
+CREATE TABLE IF NOT EXISTS rbac_role_bindings (
+  subject TEXT NOT NULL, -- user id or client id, the sub of the tokens
+  role TEXT NOT NULL REFERENCES rbac_roles (name) ON DELETE CASCADE,
+  resource TEXT NOT NULL DEFAULT '', -- audience or tenant the binding is limited to, '' for any
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (subject, role, resource)
+);
+
+CREATE INDEX IF NOT EXISTS rbac_role_bindings_role_idx ON rbac_role_bindings (role);
@@ -0,0 +1,15 @@
+DELETE FROM oauth_clients
+WHERE
+  tenant <> '';
+
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS tenant;
+
+DELETE FROM jwk_keys
+WHERE
+  tenant <> '';
+
+ALTER TABLE jwk_keys
+DROP COLUMN IF EXISTS tenant;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,19 @@
+-- customer tenants, each an issuer of its own at BASE_URL/t/{id} with its
+-- own signing keys and clients; rows with tenant '' belong to the root issuer
+CREATE TABLE IF NOT EXISTS tenants (
+  id TEXT PRIMARY KEY CHECK (id ~ '^[a-z0-9][a-z0-9-]{0,62}$'),
+  name TEXT NOT NULL DEFAULT '',
+  key_rotation_period BIGINT NOT NULL DEFAULT 0 CHECK (key_rotation_period >= 0), -- seconds, 0 rotates on every run of cmd/rotate
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE jwk_keys
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS jwk_keys_tenant_purpose_status_idx ON jwk_keys (tenant, purpose, status);
+
+ALTER TABLE oauth_clients
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS oauth_clients_tenant_idx ON oauth_clients (tenant);
@@ -0,0 +1,17 @@
+DELETE FROM oauth_audiences
+WHERE
+  access_token_format = '';
+
+ALTER TABLE oauth_audiences
+DROP COLUMN IF EXISTS claims_template,
+DROP COLUMN IF EXISTS id_token_ttl,
+DROP COLUMN IF EXISTS refresh_token_ttl,
+DROP COLUMN IF EXISTS access_token_ttl,
+DROP CONSTRAINT IF EXISTS oauth_audiences_access_token_format_check,
+ADD CONSTRAINT oauth_audiences_access_token_format_check CHECK (access_token_format IN ('jwt', 'paseto'));
+
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS claims_template,
+DROP COLUMN IF EXISTS id_token_ttl,
+DROP COLUMN IF EXISTS refresh_token_ttl,
+DROP COLUMN IF EXISTS access_token_ttl;
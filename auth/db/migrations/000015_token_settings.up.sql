@@ -0,0 +1,18 @@
+-- token lifetimes and claim templates of clients and audiences; lifetimes
+-- are in seconds, 0 leaving the choice to the next level (audience, then
+-- client, then the service config)
+ALTER TABLE oauth_clients
+ADD COLUMN access_token_ttl BIGINT NOT NULL DEFAULT 0 CHECK (access_token_ttl >= 0),
+ADD COLUMN refresh_token_ttl BIGINT NOT NULL DEFAULT 0 CHECK (refresh_token_ttl >= 0),
+ADD COLUMN id_token_ttl BIGINT NOT NULL DEFAULT 0 CHECK (id_token_ttl >= 0),
+ADD COLUMN claims_template JSONB NOT NULL DEFAULT '{}';
+
+-- an audience may now set lifetimes or claims only, leaving the token format
+-- to the client
+ALTER TABLE oauth_audiences
+DROP CONSTRAINT IF EXISTS oauth_audiences_access_token_format_check,
+ADD CONSTRAINT oauth_audiences_access_token_format_check CHECK (access_token_format IN ('', 'jwt', 'paseto')),
+ADD COLUMN access_token_ttl BIGINT NOT NULL DEFAULT 0 CHECK (access_token_ttl >= 0),
+ADD COLUMN refresh_token_ttl BIGINT NOT NULL DEFAULT 0 CHECK (refresh_token_ttl >= 0),
+ADD COLUMN id_token_ttl BIGINT NOT NULL DEFAULT 0 CHECK (id_token_ttl >= 0),
+ADD COLUMN claims_template JSONB NOT NULL DEFAULT '{}';
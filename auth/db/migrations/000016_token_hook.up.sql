@@ -0,0 +1,5 @@
+-- what happens to a client's tokens when the token hook fails: 'open'
+-- issues them without the hook's claims, 'closed' fails the request and ''
+-- follows TOKEN_HOOK_FAILURE
+ALTER TABLE oauth_clients
+ADD COLUMN token_hook_failure TEXT NOT NULL DEFAULT '' CHECK (token_hook_failure IN ('', 'open', 'closed'));
@@ -0,0 +1,19 @@
+-- browser sign-in sessions, kept in Redis and here for when Redis is
+-- unavailable or lost them; the session cookie holds id and a secret whose
+-- SHA-256 is secret_hash, and id is the sid claim of tokens
+CREATE TABLE IF NOT EXISTS sessions (
+  id TEXT PRIMARY KEY,
+  secret_hash TEXT NOT NULL,
+  tenant TEXT NOT NULL DEFAULT '',
+  subject TEXT NOT NULL,
+  username TEXT NOT NULL DEFAULT '',
+  auth_time TIMESTAMPTZ NOT NULL,
+  amr TEXT[] NOT NULL DEFAULT '{}',
+  acr TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_tenant_subject_idx ON sessions (tenant, subject);
+
+CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions (expires_at);
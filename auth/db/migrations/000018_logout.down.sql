@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS logout_deliveries;
+
+DROP TABLE IF EXISTS session_clients;
+
+ALTER TABLE oauth_clients
+DROP COLUMN IF EXISTS frontchannel_logout_uri,
+DROP COLUMN IF EXISTS backchannel_logout_uri,
+DROP COLUMN IF EXISTS post_logout_redirect_uris;
@@ -0,0 +1,32 @@
+-- OpenID Connect logout metadata of clients: where the user agent may be
+-- sent after RP-initiated logout, where logout tokens are posted
+-- (back-channel) and the page loaded in an iframe (front-channel)
+ALTER TABLE oauth_clients
+ADD COLUMN post_logout_redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN backchannel_logout_uri TEXT NOT NULL DEFAULT '',
+ADD COLUMN frontchannel_logout_uri TEXT NOT NULL DEFAULT '';
+
+-- clients a session was used to sign in to, told when it ends
+CREATE TABLE IF NOT EXISTS session_clients (
+  session_id TEXT NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+  client_id TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (session_id, client_id)
+);
+
+-- back-channel logout tokens waiting to be delivered; a delivery is retried
+-- with backoff until it succeeds or its token expires
+CREATE TABLE IF NOT EXISTS logout_deliveries (
+  id TEXT PRIMARY KEY,
+  tenant TEXT NOT NULL DEFAULT '',
+  client_id TEXT NOT NULL,
+  uri TEXT NOT NULL,
+  logout_token TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT NOT NULL DEFAULT '',
+  next_attempt_at TIMESTAMPTZ NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS logout_deliveries_next_attempt_at_idx ON logout_deliveries (next_attempt_at);
@@ -0,0 +1,25 @@
+-- TOTP authenticators of users (RFC 6238). The shared secret is sealed like
+-- jwk_keys private keys: an envelope.Seal document under a per-row DEK
+-- wrapped by the KEK named by kek_ref, with the AAD binding user_id.
+-- confirmed_at is null until the user entered a first code, and last_step
+-- is the time step of the last accepted code, so no code is accepted twice
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id TEXT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  secret_ciphertext BYTEA NOT NULL,
+  wrapped_dek BYTEA NOT NULL,
+  kek_ref TEXT NOT NULL,
+  envelope_version SMALLINT NOT NULL DEFAULT 1,
+  last_step BIGINT NOT NULL DEFAULT 0,
+  confirmed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- single-use recovery codes for users who lost their authenticator; only
+-- the SHA-256 of each code is kept
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+  user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,20 @@
+-- WebAuthn credentials (passkeys and security keys) users sign in with.
+-- public_key is the COSE_Key the authenticator returned at registration and
+-- sign_count the last signature counter it reported, which must grow unless
+-- the authenticator keeps none. amr is hwk when a trusted packed attestation
+-- showed the key cannot leave its hardware, swk otherwise
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+  id BYTEA PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  public_key BYTEA NOT NULL,
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  aaguid BYTEA NOT NULL,
+  attestation_format TEXT NOT NULL,
+  amr TEXT NOT NULL,
+  backup_eligible BOOLEAN NOT NULL DEFAULT false,
+  transports TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
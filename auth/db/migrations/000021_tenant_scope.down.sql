@@ -0,0 +1,99 @@
+DELETE FROM oauth_trusted_issuers
+WHERE
+  tenant <> '';
+
+ALTER TABLE oauth_trusted_issuers
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE oauth_trusted_issuers
+ADD PRIMARY KEY (issuer);
+
+DELETE FROM oauth_resource_servers
+WHERE
+  tenant <> '';
+
+ALTER TABLE oauth_resource_servers
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE oauth_resource_servers
+ADD PRIMARY KEY (resource);
+
+DELETE FROM oauth_audiences
+WHERE
+  tenant <> '';
+
+ALTER TABLE oauth_audiences
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE oauth_audiences
+ADD PRIMARY KEY (audience);
+
+-- dropping tenant drops the keys and foreign keys it is part of
+DELETE FROM rbac_role_bindings
+WHERE
+  tenant <> '';
+
+DELETE FROM rbac_role_permissions
+WHERE
+  tenant <> '';
+
+DELETE FROM rbac_roles
+WHERE
+  tenant <> '';
+
+DELETE FROM rbac_permissions
+WHERE
+  tenant <> '';
+
+ALTER TABLE rbac_role_bindings
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE rbac_role_permissions
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE rbac_roles
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE rbac_permissions
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE rbac_permissions
+ADD PRIMARY KEY (name);
+
+ALTER TABLE rbac_roles
+ADD PRIMARY KEY (name);
+
+ALTER TABLE rbac_role_permissions
+ADD PRIMARY KEY (role, permission),
+ADD FOREIGN KEY (role) REFERENCES rbac_roles (name) ON DELETE CASCADE,
+ADD FOREIGN KEY (permission) REFERENCES rbac_permissions (name) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS rbac_role_permissions_permission_idx ON rbac_role_permissions (permission);
+
+ALTER TABLE rbac_role_bindings
+ADD PRIMARY KEY (subject, role, resource),
+ADD FOREIGN KEY (role) REFERENCES rbac_roles (name) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS rbac_role_bindings_role_idx ON rbac_role_bindings (role);
+
+-- the users of tenants take their authenticators with them
+DELETE FROM users
+WHERE
+  tenant <> '';
+
+ALTER TABLE webauthn_credentials
+DROP COLUMN IF EXISTS tenant;
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+ALTER TABLE user_recovery_codes
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE user_totp
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE users
+ADD CONSTRAINT users_username_key UNIQUE (username);
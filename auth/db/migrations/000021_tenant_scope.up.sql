@@ -0,0 +1,91 @@
+-- users with their authenticators, roles and the audiences, resource servers
+-- and trusted issuers tokens are issued for belong to a tenant like clients
+-- do; existing rows stay with the root issuer ('')
+ALTER TABLE users
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS users_username_key,
+ADD CONSTRAINT users_tenant_username_key UNIQUE (tenant, username);
+
+ALTER TABLE user_totp
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE user_recovery_codes
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE webauthn_credentials
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS webauthn_credentials_user_id_idx;
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_tenant_user_id_idx ON webauthn_credentials (tenant, user_id);
+
+-- role names and permissions are unique per tenant, and roles only grant
+-- permissions of their own tenant
+ALTER TABLE rbac_role_permissions
+DROP CONSTRAINT IF EXISTS rbac_role_permissions_role_fkey,
+DROP CONSTRAINT IF EXISTS rbac_role_permissions_permission_fkey;
+
+ALTER TABLE rbac_role_bindings
+DROP CONSTRAINT IF EXISTS rbac_role_bindings_role_fkey;
+
+ALTER TABLE rbac_permissions
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE rbac_permissions
+DROP CONSTRAINT IF EXISTS rbac_permissions_pkey,
+ADD PRIMARY KEY (tenant, name);
+
+ALTER TABLE rbac_roles
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE rbac_roles
+DROP CONSTRAINT IF EXISTS rbac_roles_pkey,
+ADD PRIMARY KEY (tenant, name);
+
+ALTER TABLE rbac_role_permissions
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE rbac_role_permissions
+DROP CONSTRAINT IF EXISTS rbac_role_permissions_pkey,
+ADD PRIMARY KEY (tenant, role, permission),
+ADD FOREIGN KEY (tenant, role) REFERENCES rbac_roles (tenant, name) ON DELETE CASCADE,
+ADD FOREIGN KEY (tenant, permission) REFERENCES rbac_permissions (tenant, name) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS rbac_role_permissions_permission_idx;
+
+CREATE INDEX IF NOT EXISTS rbac_role_permissions_tenant_permission_idx ON rbac_role_permissions (tenant, permission);
+
+ALTER TABLE rbac_role_bindings
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE rbac_role_bindings
+DROP CONSTRAINT IF EXISTS rbac_role_bindings_pkey,
+ADD PRIMARY KEY (tenant, subject, role, resource),
+ADD FOREIGN KEY (tenant, role) REFERENCES rbac_roles (tenant, name) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS rbac_role_bindings_role_idx;
+
+CREATE INDEX IF NOT EXISTS rbac_role_bindings_tenant_role_idx ON rbac_role_bindings (tenant, role);
+
+ALTER TABLE oauth_audiences
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE oauth_audiences
+DROP CONSTRAINT IF EXISTS oauth_audiences_pkey,
+ADD PRIMARY KEY (tenant, audience);
+
+ALTER TABLE oauth_resource_servers
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE oauth_resource_servers
+DROP CONSTRAINT IF EXISTS oauth_resource_servers_pkey,
+ADD PRIMARY KEY (tenant, resource);
+
+ALTER TABLE oauth_trusted_issuers
+ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE oauth_trusted_issuers
+DROP CONSTRAINT IF EXISTS oauth_trusted_issuers_pkey,
+ADD PRIMARY KEY (tenant, issuer);
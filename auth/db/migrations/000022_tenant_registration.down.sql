@@ -0,0 +1,2 @@
+ALTER TABLE tenants
+DROP COLUMN IF EXISTS registration_token_hash;
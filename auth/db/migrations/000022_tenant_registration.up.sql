@@ -0,0 +1,4 @@
+-- each tenant's own initial access token for dynamic client registration,
+-- stored hashed; '' disables registration at the tenant
+ALTER TABLE tenants
+ADD COLUMN IF NOT EXISTS registration_token_hash TEXT NOT NULL DEFAULT '';
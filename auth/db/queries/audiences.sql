@@ -0,0 +1,44 @@
+-- name: DeleteAudience :execrows
+DELETE FROM oauth_audiences
+WHERE
+  audience = $1
+  AND tenant = $2;
+
+-- name: GetAudience :one
+SELECT
+  audience,
+  access_token_format,
+  created_at,
+  updated_at,
+  access_token_ttl,
+  refresh_token_ttl,
+  id_token_ttl,
+  claims_template,
+  tenant
+FROM
+  oauth_audiences
+WHERE
+  audience = $1
+  AND tenant = $2;
+
+-- name: UpsertAudience :exec
+INSERT INTO
+  oauth_audiences (
+    audience,
+    access_token_format,
+    access_token_ttl,
+    refresh_token_ttl,
+    id_token_ttl,
+    claims_template,
+    tenant
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (tenant, audience) DO UPDATE
+SET
+  access_token_format = EXCLUDED.access_token_format,
+  access_token_ttl = EXCLUDED.access_token_ttl,
+  refresh_token_ttl = EXCLUDED.refresh_token_ttl,
+  id_token_ttl = EXCLUDED.id_token_ttl,
+  claims_template = EXCLUDED.claims_template,
+  updated_at = now();
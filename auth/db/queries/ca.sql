@@ -0,0 +1,26 @@
+-- name: CreateCA :execrows
+INSERT INTO
+  jwk_ca (
+    cert_der,
+    priv_ciphertext,
+    wrapped_dek,
+    kek_ref,
+    envelope_version,
+    created_at
+  )
+VALUES
+  ($1, $2, $3, $4, $5, now())
+ON CONFLICT (id) DO NOTHING;
+
+-- name: GetCA :one
+SELECT
+  cert_der,
+  priv_ciphertext,
+  wrapped_dek,
+  kek_ref,
+  envelope_version,
+  created_at
+FROM
+  jwk_ca
+LIMIT
+  1;
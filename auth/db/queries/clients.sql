@@ -0,0 +1,148 @@
+-- name: CreateClient :exec
+INSERT INTO
+  oauth_clients (
+    client_id,
+    client_secret_hash,
+    name,
+    grant_types,
+    scopes,
+    token_endpoint_auth_method,
+    dpop_bound_access_tokens,
+    tls_client_auth_subject_dn,
+    tls_client_auth_san_dns,
+    tls_client_auth_san_uri,
+    tls_client_auth_san_ip,
+    tls_client_auth_san_email,
+    jwks,
+    tls_client_certificate_bound_access_tokens,
+    jwks_uri,
+    redirect_uris,
+    require_pushed_authorization_requests,
+    registration_access_token_hash,
+    access_token_format,
+    tenant,
+    post_logout_redirect_uris,
+    backchannel_logout_uri,
+    frontchannel_logout_uri
+  )
+VALUES
+  (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15,
+    $16,
+    $17,
+    $18,
+    $19,
+    $20,
+    $21,
+    $22,
+    $23
+  );
+
+-- name: DeleteClient :execrows
+DELETE FROM oauth_clients
+WHERE
+  client_id = $1
+  AND tenant = $2;
+
+-- name: GetClient :one
+SELECT
+  client_id,
+  client_secret_hash,
+  name,
+  grant_types,
+  scopes,
+  token_endpoint_auth_method,
+  dpop_bound_access_tokens,
+  created_at,
+  tls_client_auth_subject_dn,
+  tls_client_auth_san_dns,
+  tls_client_auth_san_uri,
+  tls_client_auth_san_ip,
+  tls_client_auth_san_email,
+  jwks,
+  tls_client_certificate_bound_access_tokens,
+  jwks_uri,
+  redirect_uris,
+  require_pushed_authorization_requests,
+  registration_access_token_hash,
+  updated_at,
+  resources,
+  access_token_format,
+  tenant,
+  access_token_ttl,
+  refresh_token_ttl,
+  id_token_ttl,
+  claims_template,
+  token_hook_failure,
+  post_logout_redirect_uris,
+  backchannel_logout_uri,
+  frontchannel_logout_uri
+FROM
+  oauth_clients
+WHERE
+  client_id = $1
+  AND tenant = $2;
+
+-- name: UpdateClient :execrows
+UPDATE oauth_clients
+SET
+  client_secret_hash = $2,
+  name = $3,
+  grant_types = $4,
+  scopes = $5,
+  token_endpoint_auth_method = $6,
+  dpop_bound_access_tokens = $7,
+  tls_client_auth_subject_dn = $8,
+  tls_client_auth_san_dns = $9,
+  tls_client_auth_san_uri = $10,
+  tls_client_auth_san_ip = $11,
+  tls_client_auth_san_email = $12,
+  jwks = $13,
+  tls_client_certificate_bound_access_tokens = $14,
+  jwks_uri = $15,
+  redirect_uris = $16,
+  require_pushed_authorization_requests = $17,
+  registration_access_token_hash = $18,
+  access_token_format = $19,
+  post_logout_redirect_uris = $21,
+  backchannel_logout_uri = $22,
+  frontchannel_logout_uri = $23,
+  updated_at = now()
+WHERE
+  client_id = $1
+  AND tenant = $20;
+
+-- name: UpdateClientRegistrationToken :execrows
+UPDATE oauth_clients
+SET
+  registration_access_token_hash = $2
+WHERE
+  client_id = $1
+  AND tenant = $3;
+
+-- name: UpdateClientTokenSettings :execrows
+UPDATE oauth_clients
+SET
+  access_token_ttl = $3,
+  refresh_token_ttl = $4,
+  id_token_ttl = $5,
+  claims_template = $6,
+  token_hook_failure = $7,
+  updated_at = now()
+WHERE
+  client_id = $1
+  AND tenant = $2;
@@ -0,0 +1,13 @@
+-- name: GetTrustedIssuer :one
+SELECT
+  issuer,
+  jwks_uri,
+  jwks,
+  scopes,
+  created_at,
+  tenant
+FROM
+  oauth_trusted_issuers
+WHERE
+  issuer = $1
+  AND tenant = $2;
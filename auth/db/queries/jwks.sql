@@ -5,15 +5,18 @@ INSERT INTO
     alg,
     public_jwk,
     status,
+    purpose,
     priv_ciphertext,
     priv_nonce,
     wrapped_dek,
     kek_ref,
+    envelope_version,
+    tenant,
     created_at,
     rotated_at
   )
 VALUES
-  ($1, $2, $3, 'ACTIVE', $4, $5, $6, $7, now(), NULL);
+  ($1, $2, $3, 'ACTIVE', $4, $5, $6, $7, $8, $9, $10, now(), NULL);
 
 -- name: GetJWK :one
 SELECT
@@ -21,16 +24,21 @@ SELECT
   alg,
   public_jwk,
   status,
+  purpose,
   priv_ciphertext,
   priv_nonce,
   wrapped_dek,
   kek_ref,
+  envelope_version,
+  tenant,
   created_at,
   rotated_at
 FROM
   jwk_keys
 WHERE
   status IN ('ACTIVE', 'RETIRING')
+  AND purpose = $1
+  AND tenant = $2
 ORDER BY
   CASE status
     WHEN 'ACTIVE' THEN 0
@@ -46,14 +54,18 @@ SET
   status = 'RETIRING',
   rotated_at = now()
 WHERE
-  status = 'ACTIVE';
+  status = 'ACTIVE'
+  AND purpose = $1
+  AND tenant = $2;
 
 -- name: UpdateJWKToRetired :exec
 UPDATE jwk_keys
 SET
   status = 'RETIRED'
 WHERE
-  status = 'RETIRING';
+  status = 'RETIRING'
+  AND purpose = $1
+  AND tenant = $2;
 
 -- name: GetPubJWK :many
 SELECT
@@ -63,6 +75,8 @@ FROM
   jwk_keys
 WHERE
   status IN ('ACTIVE', 'RETIRING')
+  AND purpose = $1
+  AND tenant = $2
 ORDER BY
   created_at DESC;
 
@@ -72,4 +86,81 @@ SELECT
 FROM
   jwk_keys
 WHERE
-  status IN ('ACTIVE', 'RETIRING');
+  status IN ('ACTIVE', 'RETIRING')
+  AND tenant = $1;
+
+-- name: ImportJWK :exec
+INSERT INTO
+  jwk_keys (
+    kid,
+    alg,
+    public_jwk,
+    status,
+    purpose,
+    priv_ciphertext,
+    priv_nonce,
+    wrapped_dek,
+    kek_ref,
+    envelope_version,
+    tenant,
+    created_at,
+    rotated_at
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, now(), NULL);
+
+-- name: ExistsJWK :one
+SELECT
+  EXISTS (
+    SELECT
+      1
+    FROM
+      jwk_keys
+    WHERE
+      kid = $1
+  );
+
+-- name: ListOutdatedJWK :many
+SELECT
+  kid,
+  alg,
+  priv_ciphertext,
+  priv_nonce,
+  wrapped_dek,
+  kek_ref,
+  envelope_version
+FROM
+  jwk_keys
+WHERE
+  envelope_version < $1
+ORDER BY
+  created_at
+FOR UPDATE;
+
+-- name: UpdateJWKEnvelope :exec
+UPDATE jwk_keys
+SET
+  priv_ciphertext = $2,
+  priv_nonce = NULL,
+  wrapped_dek = $3,
+  kek_ref = $4,
+  envelope_version = $5
+WHERE
+  kid = $1;
+
+-- name: UpdateJWKPublic :execrows
+UPDATE jwk_keys
+SET
+  public_jwk = $3
+WHERE
+  kid = $1
+  AND tenant = $2
+  AND status IN ('ACTIVE', 'RETIRING');
+
+-- name: RetireJWK :execrows
+UPDATE jwk_keys
+SET
+  status = 'RETIRED'
+WHERE
+  kid = $1
+  AND tenant = $2;
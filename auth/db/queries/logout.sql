@@ -0,0 +1,61 @@
+-- name: ClaimLogoutDeliveries :many
+UPDATE logout_deliveries
+SET
+  next_attempt_at = $1
+WHERE
+  id IN (
+    SELECT
+      id
+    FROM
+      logout_deliveries
+    WHERE
+      next_attempt_at <= now()
+      AND expires_at > now()
+    ORDER BY
+      next_attempt_at
+    LIMIT
+      $2
+    FOR UPDATE
+      SKIP LOCKED
+  )
+RETURNING
+  id,
+  tenant,
+  client_id,
+  uri,
+  logout_token,
+  attempts,
+  expires_at;
+
+-- name: CreateLogoutDelivery :exec
+INSERT INTO
+  logout_deliveries (
+    id,
+    tenant,
+    client_id,
+    uri,
+    logout_token,
+    next_attempt_at,
+    expires_at
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: DeleteExpiredLogoutDeliveries :execrows
+DELETE FROM logout_deliveries
+WHERE
+  expires_at <= now();
+
+-- name: DeleteLogoutDelivery :exec
+DELETE FROM logout_deliveries
+WHERE
+  id = $1;
+
+-- name: RetryLogoutDelivery :exec
+UPDATE logout_deliveries
+SET
+  attempts = attempts + 1,
+  last_error = $2,
+  next_attempt_at = $3
+WHERE
+  id = $1;
@@ -0,0 +1,108 @@
+-- name: ConfirmTOTP :execrows
+UPDATE user_totp
+SET
+  confirmed_at = now(),
+  last_step = $2
+WHERE
+  user_id = $1
+  AND tenant = $3
+  AND confirmed_at IS NULL;
+
+-- name: CountRecoveryCodes :one
+SELECT
+  count(*)
+FROM
+  user_recovery_codes
+WHERE
+  user_id = $1
+  AND tenant = $2
+  AND used_at IS NULL;
+
+-- name: CreateTOTP :execrows
+INSERT INTO
+  user_totp (
+    user_id,
+    secret_ciphertext,
+    wrapped_dek,
+    kek_ref,
+    envelope_version,
+    tenant,
+    created_at
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, now())
+ON CONFLICT (user_id) DO UPDATE
+SET
+  secret_ciphertext = EXCLUDED.secret_ciphertext,
+  wrapped_dek = EXCLUDED.wrapped_dek,
+  kek_ref = EXCLUDED.kek_ref,
+  envelope_version = EXCLUDED.envelope_version,
+  last_step = 0,
+  created_at = EXCLUDED.created_at
+WHERE
+  user_totp.confirmed_at IS NULL
+  AND user_totp.tenant = EXCLUDED.tenant;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes
+WHERE
+  user_id = $1
+  AND tenant = $2;
+
+-- name: DeleteTOTP :execrows
+DELETE FROM user_totp
+WHERE
+  user_id = $1
+  AND tenant = $2;
+
+-- name: GetTOTP :one
+SELECT
+  user_id,
+  secret_ciphertext,
+  wrapped_dek,
+  kek_ref,
+  envelope_version,
+  last_step,
+  confirmed_at,
+  created_at,
+  tenant
+FROM
+  user_totp
+WHERE
+  user_id = $1
+  AND tenant = $2;
+
+-- name: ReplaceRecoveryCodes :exec
+WITH
+  deleted AS (
+    DELETE FROM user_recovery_codes
+    WHERE
+      user_id = sqlc.arg(user_id)
+      AND tenant = sqlc.arg(tenant)
+  )
+INSERT INTO
+  user_recovery_codes (user_id, tenant, code_hash)
+SELECT
+  sqlc.arg(user_id),
+  sqlc.arg(tenant),
+  unnest(sqlc.arg(code_hashes)::TEXT[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes
+SET
+  used_at = now()
+WHERE
+  user_id = $1
+  AND code_hash = $2
+  AND tenant = $3
+  AND used_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET
+  last_step = $2
+WHERE
+  user_id = $1
+  AND tenant = $3
+  AND confirmed_at IS NOT NULL
+  AND last_step < $2;
@@ -0,0 +1,144 @@
+-- name: AddRolePermission :exec
+INSERT INTO
+  rbac_role_permissions (role, permission, tenant)
+VALUES
+  ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: CreatePermission :exec
+INSERT INTO
+  rbac_permissions (name, description, tenant)
+VALUES
+  ($1, $2, $3);
+
+-- name: CreateRole :exec
+INSERT INTO
+  rbac_roles (name, description, tenant)
+VALUES
+  ($1, $2, $3);
+
+-- name: CreateRoleBinding :exec
+INSERT INTO
+  rbac_role_bindings (subject, role, resource, tenant)
+VALUES
+  ($1, $2, $3, $4);
+
+-- name: DeletePermission :execrows
+DELETE FROM rbac_permissions
+WHERE
+  name = $1
+  AND tenant = $2;
+
+-- name: DeleteRole :execrows
+DELETE FROM rbac_roles
+WHERE
+  name = $1
+  AND tenant = $2;
+
+-- name: DeleteRoleBinding :execrows
+DELETE FROM rbac_role_bindings
+WHERE
+  subject = $1
+  AND role = $2
+  AND resource = $3
+  AND tenant = $4;
+
+-- name: GetRole :one
+SELECT
+  name,
+  description,
+  created_at
+FROM
+  rbac_roles
+WHERE
+  name = $1
+  AND tenant = $2;
+
+-- name: ListEffectivePermissions :many
+SELECT DISTINCT
+  rp.permission
+FROM
+  rbac_role_bindings b
+  JOIN rbac_role_permissions rp ON rp.tenant = b.tenant
+  AND rp.role = b.role
+WHERE
+  b.tenant = sqlc.arg(tenant)
+  AND b.subject = sqlc.arg(subject)
+  AND (
+    b.resource = ''
+    OR b.resource = ANY (sqlc.arg(resources)::TEXT[])
+  )
+ORDER BY
+  rp.permission;
+
+-- name: ListEffectiveRoles :many
+SELECT DISTINCT
+  role
+FROM
+  rbac_role_bindings
+WHERE
+  tenant = sqlc.arg(tenant)
+  AND subject = sqlc.arg(subject)
+  AND (
+    resource = ''
+    OR resource = ANY (sqlc.arg(resources)::TEXT[])
+  )
+ORDER BY
+  role;
+
+-- name: ListPermissions :many
+SELECT
+  name,
+  description,
+  created_at
+FROM
+  rbac_permissions
+WHERE
+  tenant = $1
+ORDER BY
+  name;
+
+-- name: ListRoleBindings :many
+SELECT
+  subject,
+  role,
+  resource,
+  created_at
+FROM
+  rbac_role_bindings
+WHERE
+  subject = $1
+  AND tenant = $2
+ORDER BY
+  role,
+  resource;
+
+-- name: ListRolePermissions :many
+SELECT
+  permission
+FROM
+  rbac_role_permissions
+WHERE
+  role = $1
+  AND tenant = $2
+ORDER BY
+  permission;
+
+-- name: ListRoles :many
+SELECT
+  name,
+  description,
+  created_at
+FROM
+  rbac_roles
+WHERE
+  tenant = $1
+ORDER BY
+  name;
+
+-- name: RemoveRolePermission :execrows
+DELETE FROM rbac_role_permissions
+WHERE
+  role = $1
+  AND permission = $2
+  AND tenant = $3;
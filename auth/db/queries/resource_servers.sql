@@ -0,0 +1,30 @@
+-- name: DeleteResourceServer :execrows
+DELETE FROM oauth_resource_servers
+WHERE
+  resource = $1
+  AND tenant = $2;
+
+-- name: GetResourceServer :one
+SELECT
+  resource,
+  encryption_jwk,
+  encryption_alg,
+  created_at,
+  updated_at,
+  tenant
+FROM
+  oauth_resource_servers
+WHERE
+  resource = $1
+  AND tenant = $2;
+
+-- name: UpsertResourceServer :exec
+INSERT INTO
+  oauth_resource_servers (resource, encryption_jwk, encryption_alg, tenant)
+VALUES
+  ($1, $2, $3, $4)
+ON CONFLICT (tenant, resource) DO UPDATE
+SET
+  encryption_jwk = EXCLUDED.encryption_jwk,
+  encryption_alg = EXCLUDED.encryption_alg,
+  updated_at = now();
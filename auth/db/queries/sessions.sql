@@ -0,0 +1,61 @@
+-- name: AddSessionClient :exec
+INSERT INTO
+  session_clients (session_id, client_id)
+VALUES
+  ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: CreateSession :exec
+INSERT INTO
+  sessions (
+    id,
+    secret_hash,
+    tenant,
+    subject,
+    username,
+    auth_time,
+    amr,
+    acr,
+    created_at,
+    expires_at
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE
+  expires_at <= now();
+
+-- name: DeleteSession :execrows
+DELETE FROM sessions
+WHERE
+  id = $1;
+
+-- name: GetSession :one
+SELECT
+  id,
+  secret_hash,
+  tenant,
+  subject,
+  username,
+  auth_time,
+  amr,
+  acr,
+  created_at,
+  expires_at
+FROM
+  sessions
+WHERE
+  id = $1
+  AND expires_at > now();
+
+-- name: ListSessionClients :many
+SELECT
+  client_id
+FROM
+  session_clients
+WHERE
+  session_id = $1
+ORDER BY
+  created_at;
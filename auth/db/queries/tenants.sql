@@ -0,0 +1,56 @@
+-- name: CreateTenant :exec
+INSERT INTO
+  tenants (id, name, key_rotation_period)
+VALUES
+  ($1, $2, $3);
+
+-- name: GetTenant :one
+SELECT
+  id,
+  name,
+  key_rotation_period,
+  created_at,
+  updated_at,
+  registration_token_hash
+FROM
+  tenants
+WHERE
+  id = $1;
+
+-- name: GetTenantRegistrationTokenHash :one
+SELECT
+  registration_token_hash
+FROM
+  tenants
+WHERE
+  id = $1;
+
+-- name: ListTenants :many
+SELECT
+  id,
+  name,
+  key_rotation_period,
+  created_at,
+  updated_at,
+  registration_token_hash
+FROM
+  tenants
+ORDER BY
+  id;
+
+-- name: UpdateTenant :execrows
+UPDATE tenants
+SET
+  name = $2,
+  key_rotation_period = $3,
+  updated_at = now()
+WHERE
+  id = $1;
+
+-- name: UpdateTenantRegistrationTokenHash :execrows
+UPDATE tenants
+SET
+  registration_token_hash = $2,
+  updated_at = now()
+WHERE
+  id = $1;
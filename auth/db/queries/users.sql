@@ -0,0 +1,31 @@
+-- name: CreateUser :exec
+INSERT INTO
+  users (id, username, password_hash, tenant, created_at)
+VALUES
+  ($1, $2, $3, $4, now());
+
+-- name: GetUser :one
+SELECT
+  id,
+  username,
+  password_hash,
+  created_at,
+  tenant
+FROM
+  users
+WHERE
+  id = $1
+  AND tenant = $2;
+
+-- name: GetUserByUsername :one
+SELECT
+  id,
+  username,
+  password_hash,
+  created_at,
+  tenant
+FROM
+  users
+WHERE
+  username = $1
+  AND tenant = $2;
@@ -0,0 +1,82 @@
+-- name: CreateWebAuthnCredential :exec
+INSERT INTO
+  webauthn_credentials (
+    id,
+    user_id,
+    public_key,
+    sign_count,
+    aaguid,
+    attestation_format,
+    amr,
+    backup_eligible,
+    transports,
+    tenant,
+    created_at
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, now());
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE
+  id = $1
+  AND user_id = $2
+  AND tenant = $3;
+
+-- name: GetWebAuthnCredential :one
+SELECT
+  id,
+  user_id,
+  public_key,
+  sign_count,
+  aaguid,
+  attestation_format,
+  amr,
+  backup_eligible,
+  transports,
+  created_at,
+  last_used_at,
+  tenant
+FROM
+  webauthn_credentials
+WHERE
+  id = $1
+  AND tenant = $2;
+
+-- name: ListWebAuthnCredentials :many
+SELECT
+  id,
+  user_id,
+  public_key,
+  sign_count,
+  aaguid,
+  attestation_format,
+  amr,
+  backup_eligible,
+  transports,
+  created_at,
+  last_used_at,
+  tenant
+FROM
+  webauthn_credentials
+WHERE
+  user_id = $1
+  AND tenant = $2
+ORDER BY
+  created_at;
+
+-- name: UpdateWebAuthnSignCount :execrows
+UPDATE webauthn_credentials
+SET
+  sign_count = sqlc.arg(sign_count),
+  last_used_at = now()
+WHERE
+  id = sqlc.arg(id)
+  AND tenant = sqlc.arg(tenant)
+  AND (
+    sign_count < sqlc.arg(sign_count)
+    OR (
+      sign_count = 0
+      AND sqlc.arg(sign_count) = 0
+    )
+  );
@@ -0,0 +1,7 @@
+package config
+
+type AdminConfig struct {
+	// Token is the bearer token required on /admin routes. Admin routes are
+	// not mounted when it is empty.
+	Token string `env:"TOKEN"`
+}
@@ -0,0 +1,7 @@
+package config
+
+type AuthzConfig struct {
+	// PolicyFile is the JSON document of the policies evaluated by the
+	// decision endpoint. The endpoint is not mounted when it is empty.
+	PolicyFile string `env:"POLICY_FILE"`
+}
@@ -4,12 +4,31 @@ import (
 	"github.com/caarlos0/env/v11"
 	"github.com/gofiber/fiber/v3/log"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/mfa"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/policies"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tokenhook"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/webauthn"
 	"github.com/yokeTH/yoketh-backend-oss/pkg/httpserver"
 )
 
 type config struct {
 	Server   httpserver.Config `envPrefix:"SERVER_"`
 	DBConfig db.Config         `envPrefix:"DATABASE_"`
+	Admin    AdminConfig       `envPrefix:"ADMIN_"`
+	Authz    AuthzConfig       `envPrefix:"AUTHZ_"`
+	KeyStore keystore.Config   `envPrefix:"KEYSTORE_"`
+	CA       key.CAConfig      `envPrefix:"CA_"`
+	Redis    RedisConfig       `envPrefix:"REDIS_"`
+	OAuth    oauth.Config      `envPrefix:"OAUTH_"`
+	Policies policies.Config   `envPrefix:"POLICIES_"`
+	Hook     tokenhook.Config  `envPrefix:"TOKEN_HOOK_"`
+	Session  session.Config    `envPrefix:"SESSION_"`
+	MFA      mfa.Config        `envPrefix:"MFA_"`
+	WebAuthn webauthn.Config   `envPrefix:"WEBAUTHN_"`
 }
 
 func NewFromEnv() *config {
@@ -0,0 +1,6 @@
+package config
+
+type RedisConfig struct {
+	// Addr is a redis:// or rediss:// URL.
+	Addr string `env:"ADDR" envDefault:"redis://localhost:6379"`
+}
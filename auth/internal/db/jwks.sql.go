@@ -19,10 +19,11 @@ FROM
   jwk_keys
 WHERE
   status IN ('ACTIVE', 'RETIRING')
+  AND tenant = $1
 `
 
-func (q *Queries) CountJWK(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countJWK)
+func (q *Queries) CountJWK(ctx context.Context, tenant string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countJWK, tenant)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -35,25 +36,31 @@ INSERT INTO
     alg,
     public_jwk,
     status,
+    purpose,
     priv_ciphertext,
     priv_nonce,
     wrapped_dek,
     kek_ref,
+    envelope_version,
+    tenant,
     created_at,
     rotated_at
   )
 VALUES
-  ($1, $2, $3, 'ACTIVE', $4, $5, $6, $7, now(), NULL)
+  ($1, $2, $3, 'ACTIVE', $4, $5, $6, $7, $8, $9, $10, now(), NULL)
 `
 
 type CreateJWKParams struct {
-	KID            string
-	ALG            string
-	PublicJWK      json.RawMessage
-	PrivCiphertext []byte
-	PrivNonce      []byte
-	WrappedDEK     []byte
-	KEKRef         sql.NullString
+	KID             string
+	ALG             string
+	PublicJWK       json.RawMessage
+	Purpose         string
+	PrivCiphertext  []byte
+	PrivNonce       []byte
+	WrappedDEK      []byte
+	KEKRef          sql.NullString
+	EnvelopeVersion int16
+	Tenant          string
 }
 
 func (q *Queries) CreateJWK(ctx context.Context, arg CreateJWKParams) error {
@@ -61,30 +68,57 @@ func (q *Queries) CreateJWK(ctx context.Context, arg CreateJWKParams) error {
 		arg.KID,
 		arg.ALG,
 		arg.PublicJWK,
+		arg.Purpose,
 		arg.PrivCiphertext,
 		arg.PrivNonce,
 		arg.WrappedDEK,
 		arg.KEKRef,
+		arg.EnvelopeVersion,
+		arg.Tenant,
 	)
 	return err
 }
 
+const existsJWK = `-- name: ExistsJWK :one
+SELECT
+  EXISTS (
+    SELECT
+      1
+    FROM
+      jwk_keys
+    WHERE
+      kid = $1
+  )
+`
+
+func (q *Queries) ExistsJWK(ctx context.Context, kid string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, existsJWK, kid)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const getJWK = `-- name: GetJWK :one
 SELECT
   kid,
   alg,
   public_jwk,
   status,
+  purpose,
   priv_ciphertext,
   priv_nonce,
   wrapped_dek,
   kek_ref,
+  envelope_version,
+  tenant,
   created_at,
   rotated_at
 FROM
   jwk_keys
 WHERE
   status IN ('ACTIVE', 'RETIRING')
+  AND purpose = $1
+  AND tenant = $2
 ORDER BY
   CASE status
     WHEN 'ACTIVE' THEN 0
@@ -95,31 +129,42 @@ LIMIT
   1
 `
 
+type GetJWKParams struct {
+	Purpose string
+	Tenant  string
+}
+
 type GetJWKRow struct {
-	KID            string
-	ALG            string
-	PublicJWK      json.RawMessage
-	Status         string
-	PrivCiphertext []byte
-	PrivNonce      []byte
-	WrappedDEK     []byte
-	KEKRef         sql.NullString
-	CreatedAt      time.Time
-	RotatedAt      sql.NullTime
-}
-
-func (q *Queries) GetJWK(ctx context.Context) (GetJWKRow, error) {
-	row := q.db.QueryRowContext(ctx, getJWK)
+	KID             string
+	ALG             string
+	PublicJWK       json.RawMessage
+	Status          string
+	Purpose         string
+	PrivCiphertext  []byte
+	PrivNonce       []byte
+	WrappedDEK      []byte
+	KEKRef          sql.NullString
+	EnvelopeVersion int16
+	Tenant          string
+	CreatedAt       time.Time
+	RotatedAt       sql.NullTime
+}
+
+func (q *Queries) GetJWK(ctx context.Context, arg GetJWKParams) (GetJWKRow, error) {
+	row := q.db.QueryRowContext(ctx, getJWK, arg.Purpose, arg.Tenant)
 	var i GetJWKRow
 	err := row.Scan(
 		&i.KID,
 		&i.ALG,
 		&i.PublicJWK,
 		&i.Status,
+		&i.Purpose,
 		&i.PrivCiphertext,
 		&i.PrivNonce,
 		&i.WrappedDEK,
 		&i.KEKRef,
+		&i.EnvelopeVersion,
+		&i.Tenant,
 		&i.CreatedAt,
 		&i.RotatedAt,
 	)
@@ -134,17 +179,24 @@ FROM
   jwk_keys
 WHERE
   status IN ('ACTIVE', 'RETIRING')
+  AND purpose = $1
+  AND tenant = $2
 ORDER BY
   created_at DESC
 `
 
+type GetPubJWKParams struct {
+	Purpose string
+	Tenant  string
+}
+
 type GetPubJWKRow struct {
 	KID       string
 	PublicJWK json.RawMessage
 }
 
-func (q *Queries) GetPubJWK(ctx context.Context) ([]GetPubJWKRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPubJWK)
+func (q *Queries) GetPubJWK(ctx context.Context, arg GetPubJWKParams) ([]GetPubJWKRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPubJWK, arg.Purpose, arg.Tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -166,16 +218,211 @@ func (q *Queries) GetPubJWK(ctx context.Context) ([]GetPubJWKRow, error) {
 	return items, nil
 }
 
+const importJWK = `-- name: ImportJWK :exec
+INSERT INTO
+  jwk_keys (
+    kid,
+    alg,
+    public_jwk,
+    status,
+    purpose,
+    priv_ciphertext,
+    priv_nonce,
+    wrapped_dek,
+    kek_ref,
+    envelope_version,
+    tenant,
+    created_at,
+    rotated_at
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, now(), NULL)
+`
+
+type ImportJWKParams struct {
+	KID             string
+	ALG             string
+	PublicJWK       json.RawMessage
+	Status          string
+	Purpose         string
+	PrivCiphertext  []byte
+	PrivNonce       []byte
+	WrappedDEK      []byte
+	KEKRef          sql.NullString
+	EnvelopeVersion int16
+	Tenant          string
+}
+
+func (q *Queries) ImportJWK(ctx context.Context, arg ImportJWKParams) error {
+	_, err := q.db.ExecContext(ctx, importJWK,
+		arg.KID,
+		arg.ALG,
+		arg.PublicJWK,
+		arg.Status,
+		arg.Purpose,
+		arg.PrivCiphertext,
+		arg.PrivNonce,
+		arg.WrappedDEK,
+		arg.KEKRef,
+		arg.EnvelopeVersion,
+		arg.Tenant,
+	)
+	return err
+}
+
+const listOutdatedJWK = `-- name: ListOutdatedJWK :many
+SELECT
+  kid,
+  alg,
+  priv_ciphertext,
+  priv_nonce,
+  wrapped_dek,
+  kek_ref,
+  envelope_version
+FROM
+  jwk_keys
+WHERE
+  envelope_version < $1
+ORDER BY
+  created_at
+FOR UPDATE
+`
+
+type ListOutdatedJWKRow struct {
+	KID             string
+	ALG             string
+	PrivCiphertext  []byte
+	PrivNonce       []byte
+	WrappedDEK      []byte
+	KEKRef          sql.NullString
+	EnvelopeVersion int16
+}
+
+func (q *Queries) ListOutdatedJWK(ctx context.Context, envelopeVersion int16) ([]ListOutdatedJWKRow, error) {
+	rows, err := q.db.QueryContext(ctx, listOutdatedJWK, envelopeVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOutdatedJWKRow
+	for rows.Next() {
+		var i ListOutdatedJWKRow
+		if err := rows.Scan(
+			&i.KID,
+			&i.ALG,
+			&i.PrivCiphertext,
+			&i.PrivNonce,
+			&i.WrappedDEK,
+			&i.KEKRef,
+			&i.EnvelopeVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const retireJWK = `-- name: RetireJWK :execrows
+UPDATE jwk_keys
+SET
+  status = 'RETIRED'
+WHERE
+  kid = $1
+  AND tenant = $2
+`
+
+type RetireJWKParams struct {
+	KID    string
+	Tenant string
+}
+
+func (q *Queries) RetireJWK(ctx context.Context, arg RetireJWKParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, retireJWK, arg.KID, arg.Tenant)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateJWKEnvelope = `-- name: UpdateJWKEnvelope :exec
+UPDATE jwk_keys
+SET
+  priv_ciphertext = $2,
+  priv_nonce = NULL,
+  wrapped_dek = $3,
+  kek_ref = $4,
+  envelope_version = $5
+WHERE
+  kid = $1
+`
+
+type UpdateJWKEnvelopeParams struct {
+	KID             string
+	PrivCiphertext  []byte
+	WrappedDEK      []byte
+	KEKRef          sql.NullString
+	EnvelopeVersion int16
+}
+
+func (q *Queries) UpdateJWKEnvelope(ctx context.Context, arg UpdateJWKEnvelopeParams) error {
+	_, err := q.db.ExecContext(ctx, updateJWKEnvelope,
+		arg.KID,
+		arg.PrivCiphertext,
+		arg.WrappedDEK,
+		arg.KEKRef,
+		arg.EnvelopeVersion,
+	)
+	return err
+}
+
+const updateJWKPublic = `-- name: UpdateJWKPublic :execrows
+UPDATE jwk_keys
+SET
+  public_jwk = $3
+WHERE
+  kid = $1
+  AND tenant = $2
+  AND status IN ('ACTIVE', 'RETIRING')
+`
+
+type UpdateJWKPublicParams struct {
+	KID       string
+	Tenant    string
+	PublicJWK json.RawMessage
+}
+
+func (q *Queries) UpdateJWKPublic(ctx context.Context, arg UpdateJWKPublicParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateJWKPublic, arg.KID, arg.Tenant, arg.PublicJWK)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateJWKToRetired = `-- name: UpdateJWKToRetired :exec
 UPDATE jwk_keys
 SET
   status = 'RETIRED'
 WHERE
   status = 'RETIRING'
+  AND purpose = $1
+  AND tenant = $2
 `
 
-func (q *Queries) UpdateJWKToRetired(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, updateJWKToRetired)
+type UpdateJWKToRetiredParams struct {
+	Purpose string
+	Tenant  string
+}
+
+func (q *Queries) UpdateJWKToRetired(ctx context.Context, arg UpdateJWKToRetiredParams) error {
+	_, err := q.db.ExecContext(ctx, updateJWKToRetired, arg.Purpose, arg.Tenant)
 	return err
 }
 
@@ -186,9 +433,16 @@ SET
   rotated_at = now()
 WHERE
   status = 'ACTIVE'
+  AND purpose = $1
+  AND tenant = $2
 `
 
-func (q *Queries) UpdateJWKToRetiring(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, updateJWKToRetiring)
+type UpdateJWKToRetiringParams struct {
+	Purpose string
+	Tenant  string
+}
+
+func (q *Queries) UpdateJWKToRetiring(ctx context.Context, arg UpdateJWKToRetiringParams) error {
+	_, err := q.db.ExecContext(ctx, updateJWKToRetiring, arg.Purpose, arg.Tenant)
 	return err
 }
@@ -10,17 +10,206 @@ import (
 	"time"
 )
 
+type JwkCa struct {
+	ID              bool
+	CertDER         []byte
+	PrivCiphertext  []byte
+	WrappedDEK      []byte
+	KEKRef          sql.NullString
+	EnvelopeVersion int16
+	CreatedAt       time.Time
+}
+
 type JwkKey struct {
-	KID            string
-	ALG            string
-	PublicJWK      json.RawMessage
-	Status         string
-	PrivCiphertext []byte
-	PrivNonce      []byte
-	WrappedDEK     []byte
-	KEKRef         sql.NullString
-	CreatedAt      time.Time
-	RotatedAt      sql.NullTime
-	NotBefore      sql.NullTime
-	NotAfter       sql.NullTime
+	KID             string
+	ALG             string
+	PublicJWK       json.RawMessage
+	Status          string
+	PrivCiphertext  []byte
+	PrivNonce       []byte
+	WrappedDEK      []byte
+	KEKRef          sql.NullString
+	CreatedAt       time.Time
+	RotatedAt       sql.NullTime
+	NotBefore       sql.NullTime
+	NotAfter        sql.NullTime
+	EnvelopeVersion int16
+	Purpose         string
+	Tenant          string
+}
+
+type LogoutDelivery struct {
+	ID            string
+	Tenant        string
+	ClientID      string
+	URI           string
+	LogoutToken   string
+	Attempts      int32
+	LastError     string
+	NextAttemptAt time.Time
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+}
+
+type OauthAudience struct {
+	Audience          string
+	AccessTokenFormat string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	AccessTokenTTL    int64
+	RefreshTokenTTL   int64
+	IDTokenTTL        int64
+	ClaimsTemplate    json.RawMessage
+	Tenant            string
+}
+
+type OauthClient struct {
+	ClientID                              string
+	ClientSecretHash                      sql.NullString
+	Name                                  string
+	GrantTypes                            []string
+	Scopes                                []string
+	TokenEndpointAuthMethod               string
+	DPoPBoundAccessTokens                 bool
+	CreatedAt                             time.Time
+	TLSClientAuthSubjectDN                sql.NullString
+	TLSClientAuthSANDNS                   sql.NullString
+	TLSClientAuthSANURI                   sql.NullString
+	TLSClientAuthSANIP                    sql.NullString
+	TLSClientAuthSANEmail                 sql.NullString
+	JWKS                                  json.RawMessage
+	TLSClientCertificateBoundAccessTokens bool
+	JWKSURI                               sql.NullString
+	RedirectURIs                          []string
+	RequirePushedAuthorizationRequests    bool
+	RegistrationAccessTokenHash           sql.NullString
+	UpdatedAt                             time.Time
+	Resources                             []string
+	AccessTokenFormat                     string
+	Tenant                                string
+	AccessTokenTTL                        int64
+	RefreshTokenTTL                       int64
+	IDTokenTTL                            int64
+	ClaimsTemplate                        json.RawMessage
+	TokenHookFailure                      string
+	PostLogoutRedirectURIs                []string
+	BackchannelLogoutURI                  string
+	FrontchannelLogoutURI                 string
+}
+
+type OauthResourceServer struct {
+	Resource      string
+	EncryptionJWK json.RawMessage
+	EncryptionAlg string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Tenant        string
+}
+
+type OauthTrustedIssuer struct {
+	Issuer    string
+	JWKSURI   sql.NullString
+	JWKS      json.RawMessage
+	Scopes    []string
+	CreatedAt time.Time
+	Tenant    string
+}
+
+type RbacPermission struct {
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	Tenant      string
+}
+
+type RbacRole struct {
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	Tenant      string
+}
+
+type RbacRoleBinding struct {
+	Subject   string
+	Role      string
+	Resource  string
+	CreatedAt time.Time
+	Tenant    string
+}
+
+type RbacRolePermission struct {
+	Role       string
+	Permission string
+	Tenant     string
+}
+
+type Session struct {
+	ID         string
+	SecretHash string
+	Tenant     string
+	Subject    string
+	Username   string
+	AuthTime   time.Time
+	AMR        []string
+	ACR        string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+type SessionClient struct {
+	SessionID string
+	ClientID  string
+	CreatedAt time.Time
+}
+
+type Tenant struct {
+	ID                    string
+	Name                  string
+	KeyRotationPeriod     int64
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	RegistrationTokenHash string
+}
+
+type User struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	CreatedAt    time.Time
+	Tenant       string
+}
+
+type UserRecoveryCode struct {
+	UserID    string
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+	Tenant    string
+}
+
+type UserTotp struct {
+	UserID           string
+	SecretCiphertext []byte
+	WrappedDEK       []byte
+	KEKRef           string
+	EnvelopeVersion  int16
+	LastStep         int64
+	ConfirmedAt      sql.NullTime
+	CreatedAt        time.Time
+	Tenant           string
+}
+
+type WebauthnCredential struct {
+	ID                []byte
+	UserID            string
+	PublicKey         []byte
+	SignCount         int64
+	AAGUID            []byte
+	AttestationFormat string
+	AMR               string
+	BackupEligible    bool
+	Transports        []string
+	CreatedAt         time.Time
+	LastUsedAt        sql.NullTime
+	Tenant            string
 }
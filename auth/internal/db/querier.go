@@ -9,12 +9,82 @@ import (
 )
 
 type Querier interface {
-	CountJWK(ctx context.Context) (int64, error)
+	AddRolePermission(ctx context.Context, arg AddRolePermissionParams) error
+	AddSessionClient(ctx context.Context, arg AddSessionClientParams) error
+	ClaimLogoutDeliveries(ctx context.Context, arg ClaimLogoutDeliveriesParams) ([]ClaimLogoutDeliveriesRow, error)
+	ConfirmTOTP(ctx context.Context, arg ConfirmTOTPParams) (int64, error)
+	CountJWK(ctx context.Context, tenant string) (int64, error)
+	CountRecoveryCodes(ctx context.Context, arg CountRecoveryCodesParams) (int64, error)
+	CreateCA(ctx context.Context, arg CreateCAParams) (int64, error)
+	CreateClient(ctx context.Context, arg CreateClientParams) error
 	CreateJWK(ctx context.Context, arg CreateJWKParams) error
-	GetJWK(ctx context.Context) (GetJWKRow, error)
-	GetPubJWK(ctx context.Context) ([]GetPubJWKRow, error)
-	UpdateJWKToRetired(ctx context.Context) error
-	UpdateJWKToRetiring(ctx context.Context) error
+	CreateLogoutDelivery(ctx context.Context, arg CreateLogoutDeliveryParams) error
+	CreatePermission(ctx context.Context, arg CreatePermissionParams) error
+	CreateRole(ctx context.Context, arg CreateRoleParams) error
+	CreateRoleBinding(ctx context.Context, arg CreateRoleBindingParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) error
+	CreateTOTP(ctx context.Context, arg CreateTOTPParams) (int64, error)
+	CreateTenant(ctx context.Context, arg CreateTenantParams) error
+	CreateUser(ctx context.Context, arg CreateUserParams) error
+	CreateWebAuthnCredential(ctx context.Context, arg CreateWebAuthnCredentialParams) error
+	DeleteAudience(ctx context.Context, arg DeleteAudienceParams) (int64, error)
+	DeleteClient(ctx context.Context, arg DeleteClientParams) (int64, error)
+	DeleteExpiredLogoutDeliveries(ctx context.Context) (int64, error)
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+	DeleteLogoutDelivery(ctx context.Context, id string) error
+	DeletePermission(ctx context.Context, arg DeletePermissionParams) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, arg DeleteRecoveryCodesParams) error
+	DeleteResourceServer(ctx context.Context, arg DeleteResourceServerParams) (int64, error)
+	DeleteRole(ctx context.Context, arg DeleteRoleParams) (int64, error)
+	DeleteRoleBinding(ctx context.Context, arg DeleteRoleBindingParams) (int64, error)
+	DeleteSession(ctx context.Context, id string) (int64, error)
+	DeleteTOTP(ctx context.Context, arg DeleteTOTPParams) (int64, error)
+	DeleteWebAuthnCredential(ctx context.Context, arg DeleteWebAuthnCredentialParams) (int64, error)
+	ExistsJWK(ctx context.Context, kid string) (bool, error)
+	GetAudience(ctx context.Context, arg GetAudienceParams) (OauthAudience, error)
+	GetCA(ctx context.Context) (GetCARow, error)
+	GetClient(ctx context.Context, arg GetClientParams) (OauthClient, error)
+	GetJWK(ctx context.Context, arg GetJWKParams) (GetJWKRow, error)
+	GetPubJWK(ctx context.Context, arg GetPubJWKParams) ([]GetPubJWKRow, error)
+	GetResourceServer(ctx context.Context, arg GetResourceServerParams) (OauthResourceServer, error)
+	GetRole(ctx context.Context, arg GetRoleParams) (GetRoleRow, error)
+	GetSession(ctx context.Context, id string) (Session, error)
+	GetTOTP(ctx context.Context, arg GetTOTPParams) (UserTotp, error)
+	GetTenant(ctx context.Context, id string) (Tenant, error)
+	GetTenantRegistrationTokenHash(ctx context.Context, id string) (string, error)
+	GetTrustedIssuer(ctx context.Context, arg GetTrustedIssuerParams) (OauthTrustedIssuer, error)
+	GetUser(ctx context.Context, arg GetUserParams) (User, error)
+	GetUserByUsername(ctx context.Context, arg GetUserByUsernameParams) (User, error)
+	GetWebAuthnCredential(ctx context.Context, arg GetWebAuthnCredentialParams) (WebauthnCredential, error)
+	ImportJWK(ctx context.Context, arg ImportJWKParams) error
+	ListEffectivePermissions(ctx context.Context, arg ListEffectivePermissionsParams) ([]string, error)
+	ListEffectiveRoles(ctx context.Context, arg ListEffectiveRolesParams) ([]string, error)
+	ListOutdatedJWK(ctx context.Context, envelopeVersion int16) ([]ListOutdatedJWKRow, error)
+	ListPermissions(ctx context.Context, tenant string) ([]ListPermissionsRow, error)
+	ListRoleBindings(ctx context.Context, arg ListRoleBindingsParams) ([]ListRoleBindingsRow, error)
+	ListRolePermissions(ctx context.Context, arg ListRolePermissionsParams) ([]string, error)
+	ListRoles(ctx context.Context, tenant string) ([]ListRolesRow, error)
+	ListSessionClients(ctx context.Context, sessionID string) ([]string, error)
+	ListTenants(ctx context.Context) ([]Tenant, error)
+	ListWebAuthnCredentials(ctx context.Context, arg ListWebAuthnCredentialsParams) ([]WebauthnCredential, error)
+	RemoveRolePermission(ctx context.Context, arg RemoveRolePermissionParams) (int64, error)
+	ReplaceRecoveryCodes(ctx context.Context, arg ReplaceRecoveryCodesParams) error
+	RetireJWK(ctx context.Context, arg RetireJWKParams) (int64, error)
+	RetryLogoutDelivery(ctx context.Context, arg RetryLogoutDeliveryParams) error
+	UpdateClient(ctx context.Context, arg UpdateClientParams) (int64, error)
+	UpdateClientRegistrationToken(ctx context.Context, arg UpdateClientRegistrationTokenParams) (int64, error)
+	UpdateClientTokenSettings(ctx context.Context, arg UpdateClientTokenSettingsParams) (int64, error)
+	UpdateJWKEnvelope(ctx context.Context, arg UpdateJWKEnvelopeParams) error
+	UpdateJWKPublic(ctx context.Context, arg UpdateJWKPublicParams) (int64, error)
+	UpdateJWKToRetired(ctx context.Context, arg UpdateJWKToRetiredParams) error
+	UpdateJWKToRetiring(ctx context.Context, arg UpdateJWKToRetiringParams) error
+	UpdateTenant(ctx context.Context, arg UpdateTenantParams) (int64, error)
+	UpdateTenantRegistrationTokenHash(ctx context.Context, arg UpdateTenantRegistrationTokenHashParams) (int64, error)
+	UpdateWebAuthnSignCount(ctx context.Context, arg UpdateWebAuthnSignCountParams) (int64, error)
+	UpsertAudience(ctx context.Context, arg UpsertAudienceParams) error
+	UpsertResourceServer(ctx context.Context, arg UpsertResourceServerParams) error
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,291 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/user"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+type importKeyRequest struct {
+	KID    string `json:"kid"`
+	Alg    string `json:"alg"`
+	Status string `json:"status"`
+	Format string `json:"format"`
+	// Key is the PEM text, the JWK JSON document or the base64 encoded
+	// PKCS#12 bundle depending on Format.
+	Key      string `json:"key"`
+	Password string `json:"password"`
+}
+
+type importKeyResponse struct {
+	KID    string `json:"kid"`
+	Alg    string `json:"alg"`
+	Status string `json:"status"`
+}
+
+func (h *Handler) HandleImportKey(ctx fiber.Ctx) error {
+	var req importKeyRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	format := key.ImportFormat(req.Format)
+	data := []byte(req.Key)
+	if format == key.FormatPKCS12 {
+		raw, err := base64.StdEncoding.DecodeString(req.Key)
+		if err != nil {
+			return apperror.BadRequestError(err, "pkcs12 key must be base64 encoded", apperror.StatusKeyImportError)
+		}
+		data = raw
+	}
+
+	status := req.Status
+	if status == "" {
+		status = key.StatusActive
+	}
+
+	kid, alg, err := h.keyManager(ctx).Import(ctx, key.ImportParams{
+		KID:      req.KID,
+		Alg:      req.Alg,
+		Status:   status,
+		Format:   format,
+		Data:     data,
+		Password: req.Password,
+	})
+	switch {
+	case errors.Is(err, key.ErrKIDExists):
+		return apperror.ConflictError(err, "kid already exists", apperror.StatusKeyImportError)
+	case err != nil && isKeyValidationError(err):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusKeyImportError)
+	case err != nil:
+		return apperror.InternalServerError(err, "import key error", apperror.StatusKeyImportError)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(importKeyResponse{
+		KID:    kid,
+		Alg:    alg,
+		Status: status,
+	})
+}
+
+// isKeyValidationError reports whether err was caused by the submitted key
+// rather than by storage.
+func isKeyValidationError(err error) bool {
+	for _, target := range []error{
+		key.ErrInvalidKey,
+		key.ErrUnsupportedKey,
+		key.ErrWeakKey,
+		key.ErrAlgMismatch,
+		key.ErrKeyMismatch,
+		key.ErrNoPrivateKey,
+		key.ErrUnknownFormat,
+		key.ErrInvalidStatus,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) HandleRetireKey(ctx fiber.Ctx) error {
+	kid := ctx.Params("kid")
+	err := h.keyManager(ctx).Retire(ctx, kid)
+	switch {
+	case errors.Is(err, keystore.ErrNotFound):
+		return apperror.NotFoundError(err, "key not found", apperror.StatusJWKError)
+	case err != nil:
+		return apperror.InternalServerError(err, "retire key error", apperror.StatusJWKError)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+type createUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type createUserResponse struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+func (h *Handler) HandleCreateUser(ctx fiber.Ctx) error {
+	var req createUserRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	u, err := h.Users.Create(ctx, tenantID(ctx), req.Username, req.Password)
+	switch {
+	case errors.Is(err, user.ErrUsernameTaken):
+		return apperror.ConflictError(err, "username already taken", apperror.StatusUserError)
+	case errors.Is(err, user.ErrInvalidUsername), errors.Is(err, user.ErrWeakPassword):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusUserError)
+	case err != nil:
+		return apperror.InternalServerError(err, "create user error", apperror.StatusUserError)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createUserResponse{
+		ID:       u.ID,
+		Username: u.Username,
+	})
+}
+
+type registerResourceServerRequest struct {
+	Resource      string     `json:"resource"`
+	EncryptionJWK verify.JWK `json:"encryption_jwk"`
+	EncryptionAlg string     `json:"encryption_alg"`
+}
+
+// HandleRegisterResourceServer sets the key tokens for a resource are
+// encrypted to.
+func (h *Handler) HandleRegisterResourceServer(ctx fiber.Ctx) error {
+	var req registerResourceServerRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	rs, err := h.oauthService(ctx).RegisterResourceServer(ctx, req.Resource, req.EncryptionJWK, req.EncryptionAlg)
+	switch {
+	case err != nil && isResourceServerValidationError(err):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusResourceServerError)
+	case err != nil:
+		return apperror.InternalServerError(err, "register resource server error", apperror.StatusResourceServerError)
+	}
+
+	return ctx.JSON(rs)
+}
+
+// HandleDeleteResourceServer stops encrypting tokens for the resource named
+// by the resource query parameter.
+func (h *Handler) HandleDeleteResourceServer(ctx fiber.Ctx) error {
+	err := h.oauthService(ctx).DeleteResourceServer(ctx, ctx.Query("resource"))
+	switch {
+	case errors.Is(err, oauth.ErrResourceServerNotFound):
+		return apperror.NotFoundError(err, "resource server not found", apperror.StatusResourceServerError)
+	case err != nil:
+		return apperror.InternalServerError(err, "delete resource server error", apperror.StatusResourceServerError)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func isResourceServerValidationError(err error) bool {
+	for _, target := range []error{
+		oauth.ErrInvalidResource,
+		oauth.ErrPrivateEncryptionKey,
+		oauth.ErrEncryptionKeyUse,
+		key.ErrUnsupportedEncryptionKey,
+		key.ErrWeakKey,
+		key.ErrAlgMismatch,
+		oauth.ErrEncryptedPASETO,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type setAudienceRequest struct {
+	Audience string `json:"audience"`
+	// AccessTokenFormat may be left empty to follow the client's.
+	AccessTokenFormat string `json:"access_token_format"`
+	oauth.TokenSettings
+}
+
+// HandleSetAudience sets the token settings of an audience.
+func (h *Handler) HandleSetAudience(ctx fiber.Ctx) error {
+	var req setAudienceRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	aud, err := h.oauthService(ctx).SetAudience(ctx, oauth.Audience{
+		Audience:          req.Audience,
+		AccessTokenFormat: req.AccessTokenFormat,
+		TokenSettings:     req.TokenSettings,
+	})
+	switch {
+	case errors.Is(err, oauth.ErrInvalidResource), errors.Is(err, oauth.ErrInvalidTokenFormat), errors.Is(err, oauth.ErrEncryptedPASETO),
+		isTokenSettingsError(err):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusAudienceError)
+	case err != nil:
+		return apperror.InternalServerError(err, "set audience error", apperror.StatusAudienceError)
+	}
+
+	return ctx.JSON(aud)
+}
+
+// HandleDeleteAudience drops the settings of the audience named by the
+// audience query parameter.
+func (h *Handler) HandleDeleteAudience(ctx fiber.Ctx) error {
+	err := h.oauthService(ctx).DeleteAudience(ctx, ctx.Query("audience"))
+	switch {
+	case errors.Is(err, oauth.ErrAudienceNotFound):
+		return apperror.NotFoundError(err, "audience not found", apperror.StatusAudienceError)
+	case err != nil:
+		return apperror.InternalServerError(err, "delete audience error", apperror.StatusAudienceError)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleGetClientTokenSettings returns the token lifetimes and claim template
+// of a client of the root issuer, or of the tenant in the path.
+func (h *Handler) HandleGetClientTokenSettings(ctx fiber.Ctx) error {
+	settings, err := h.oauthService(ctx).ClientTokenSettings(ctx, ctx.Params("client_id"))
+	switch {
+	case errors.Is(err, oauth.ErrClientNotFound):
+		return apperror.NotFoundError(err, "client not found", apperror.StatusClientError)
+	case err != nil:
+		return apperror.InternalServerError(err, "get client token settings error", apperror.StatusClientError)
+	}
+
+	return ctx.JSON(settings)
+}
+
+// HandleSetClientTokenSettings replaces the token lifetimes, claim template
+// and token hook failure policy of a client.
+func (h *Handler) HandleSetClientTokenSettings(ctx fiber.Ctx) error {
+	var req oauth.ClientTokenSettings
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	settings, err := h.oauthService(ctx).SetClientTokenSettings(ctx, ctx.Params("client_id"), req)
+	switch {
+	case isTokenSettingsError(err):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusClientError)
+	case errors.Is(err, oauth.ErrClientNotFound):
+		return apperror.NotFoundError(err, "client not found", apperror.StatusClientError)
+	case err != nil:
+		return apperror.InternalServerError(err, "set client token settings error", apperror.StatusClientError)
+	}
+
+	return ctx.JSON(settings)
+}
+
+func isTokenSettingsError(err error) bool {
+	for _, target := range []error{
+		oauth.ErrInvalidTokenTTL,
+		oauth.ErrReservedClaim,
+		oauth.ErrInvalidClaimTemplate,
+		oauth.ErrClaimTemplateSize,
+		oauth.ErrInvalidTokenHookFailure,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
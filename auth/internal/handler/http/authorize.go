@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/mfa"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/user"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/webauthn"
+)
+
+type authorizePage struct {
+	Action        string
+	Script        string
+	LoginOptions  string
+	Authorization *oauth.PendingAuthorization
+	// SignedInAs is the username of the user's session when it may serve
+	// the request, in which case the user only has to decide.
+	SignedInAs string
+	// Challenge is the ID of the MFA challenge of a user who entered the
+	// right password and is asked for a code.
+	Challenge string
+	Message   string
+}
+
+// HandleAuthorize is the authorization endpoint (RFC 6749 section 3.1). It
+// validates the request and asks the user to sign in, or only to decide
+// when the user's session may serve it. prompt=none requests are answered
+// right away.
+func (h *Handler) HandleAuthorize(ctx fiber.Ctx) error {
+	params := url.Values{}
+	for k, v := range ctx.RequestCtx().QueryArgs().All() {
+		params.Add(string(k), string(v))
+	}
+
+	sess := h.currentSession(ctx)
+	pending, err := h.oauthService(ctx).Authorize(ctx, params, sess.Authentication())
+	if err != nil {
+		return authorizeError(ctx, h.keyManager(ctx).Issuer, err)
+	}
+	if pending.Redirect != "" {
+		h.joinSession(ctx, pending.Session.SID, pending.Request.ClientID)
+		ctx.Set(fiber.HeaderCacheControl, "no-store")
+		return ctx.Redirect().Status(fiber.StatusSeeOther).To(pending.Redirect)
+	}
+
+	page := authorizePage{
+		Action:        h.path(ctx, oauth.AuthorizePath),
+		Script:        h.path(ctx, webauthn.ScriptPath),
+		LoginOptions:  h.path(ctx, webauthn.LoginOptionsPath),
+		Authorization: pending,
+	}
+	if pending.Session != nil {
+		page.SignedInAs = sess.Username
+	}
+	return renderPage(ctx, "authorize.html", fiber.StatusOK, page)
+}
+
+// HandleAuthorizeDecision signs the user in, unless the user's session
+// serves the request, and redirects back to the client with a code or an
+// access_denied error. Users with an authenticator are asked for a code
+// after their password. The "switch" decision drops the session for this
+// request and asks for a username and password instead. A passkey signs the
+// user in without a username, and without a second factor.
+func (h *Handler) HandleAuthorizeDecision(ctx fiber.Ctx) error {
+	page := authorizePage{
+		Action:       h.path(ctx, oauth.AuthorizePath),
+		Script:       h.path(ctx, webauthn.ScriptPath),
+		LoginOptions: h.path(ctx, webauthn.LoginOptionsPath),
+	}
+	id := ctx.FormValue("authorization_id")
+	decision := ctx.FormValue("decision")
+
+	sess := h.currentSession(ctx)
+	pending, err := h.oauthService(ctx).PendingAuthorization(ctx, id, sess.Authentication())
+	if errors.Is(err, oauth.ErrAuthorizationNotFound) {
+		page.Message = "This sign-in request has expired. Return to the application and try again."
+		return renderPage(ctx, "authorize.html", fiber.StatusBadRequest, page)
+	}
+	if err != nil {
+		return err
+	}
+	page.Authorization = pending
+
+	if decision == "switch" {
+		return renderPage(ctx, "authorize.html", fiber.StatusOK, page)
+	}
+
+	authn := pending.Session
+	switch challenge := ctx.FormValue("mfa_challenge"); {
+	case challenge != "":
+		u, amr, err := h.answerChallenge(ctx, challenge)
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			page.Challenge = challenge
+			page.Message = "Incorrect or already used code."
+			return renderPage(ctx, "authorize.html", fiber.StatusUnauthorized, page)
+		}
+		if challengeOver(err) {
+			page.Message = "Your sign-in has expired or had too many incorrect codes. Sign in again."
+			return renderPage(ctx, "authorize.html", fiber.StatusUnauthorized, page)
+		}
+		if err != nil {
+			return err
+		}
+		started, err := h.startSession(ctx, u, []string{session.AMRPassword, amr}, sess)
+		if err != nil {
+			return err
+		}
+		authn = started.Authentication()
+	case ctx.FormValue("webauthn_response") != "":
+		login, err := h.finishPasskeyLogin(ctx, ctx.FormValue("webauthn_response"))
+		if err != nil {
+			if !passkeyRejected(err) {
+				return err
+			}
+			page.Message = "Your passkey could not be verified. Try again or use your password."
+			return renderPage(ctx, "authorize.html", fiber.StatusUnauthorized, page)
+		}
+		started, err := h.startSession(ctx, &user.User{ID: login.UserID, Username: login.Username}, []string{login.AMR}, sess)
+		if err != nil {
+			return err
+		}
+		authn = started.Authentication()
+	case authn == nil || ctx.FormValue("username") != "":
+		u, err := h.Users.Authenticate(ctx, tenantID(ctx), ctx.FormValue("username"), ctx.FormValue("password"))
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			page.Message = "Incorrect username or password."
+			return renderPage(ctx, "authorize.html", fiber.StatusUnauthorized, page)
+		}
+		if err != nil {
+			return err
+		}
+		if page.Challenge, err = h.startChallenge(ctx, u); err != nil {
+			return err
+		}
+		if page.Challenge != "" {
+			return renderPage(ctx, "authorize.html", fiber.StatusOK, page)
+		}
+		started, err := h.startSession(ctx, u, []string{session.AMRPassword}, sess)
+		if err != nil {
+			return err
+		}
+		authn = started.Authentication()
+	}
+
+	location, err := h.oauthService(ctx).CompleteAuthorization(ctx, id, *authn, decision == "approve")
+	if errors.Is(err, oauth.ErrAuthorizationNotFound) {
+		page.Authorization = nil
+		page.Message = "This sign-in request has expired. Return to the application and try again."
+		return renderPage(ctx, "authorize.html", fiber.StatusBadRequest, page)
+	}
+	if err != nil {
+		return err
+	}
+	if decision == "approve" {
+		h.joinSession(ctx, authn.SID, pending.Request.ClientID)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.Redirect().Status(fiber.StatusSeeOther).To(location)
+}
+
+// HandlePAR is the pushed authorization request endpoint (RFC 9126 section
+// 2).
+func (h *Handler) HandlePAR(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	params := url.Values{}
+	for k, v := range ctx.RequestCtx().PostArgs().All() {
+		params.Add(string(k), string(v))
+	}
+	for _, name := range []string{"client_secret", "client_assertion", "client_assertion_type"} {
+		params.Del(name)
+	}
+
+	res, err := h.oauthService(ctx).PushAuthorizationRequest(ctx, clientCredentials(ctx), params)
+	if err != nil {
+		return oauthError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(res)
+}
+
+// authorizeError redirects errors the client can be told about and shows the
+// rest to the user, since the redirect URI could not be trusted.
+func authorizeError(ctx fiber.Ctx, iss string, err error) error {
+	var rerr *oauth.RedirectError
+	if errors.As(err, &rerr) {
+		ctx.Set(fiber.HeaderCacheControl, "no-store")
+		return ctx.Redirect().Status(fiber.StatusSeeOther).To(rerr.Location(iss))
+	}
+
+	var oerr *oauth.Error
+	if !errors.As(err, &oerr) {
+		return err
+	}
+	if oerr.Code == oauth.CodeServerError {
+		log.Errorf("authorization endpoint: %v", oerr)
+	}
+
+	message := "The application sent an invalid sign-in request."
+	if oerr.Description != "" {
+		message += " " + oerr.Description + "."
+	}
+	return renderPage(ctx, "authorize.html", oerr.Status, authorizePage{Message: message})
+}
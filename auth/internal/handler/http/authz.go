@@ -0,0 +1,35 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/policy"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+// HandleAuthzCheck decides whether the holder of the access token in the
+// body may perform action on resource. The policies see the claims of the
+// token, which must have been issued by this service. A denial is a
+// decision, answered with 200 like an approval.
+func (h *Handler) HandleAuthzCheck(ctx fiber.Ctx) error {
+	var in policy.Input
+	if err := ctx.Bind().JSON(&in); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+	if in.Token == "" {
+		return apperror.BadRequestError(verify.ErrMissingToken, "token is required", apperror.StatusAuthzError)
+	}
+
+	claims, err := h.oauthService(ctx).VerifyAccessToken(ctx, in.Token)
+	if err != nil {
+		return apperror.UnauthorizedError(err, "invalid access token", apperror.StatusUnauthorized)
+	}
+	in.Claims = claims.Map()
+
+	d, err := h.Authz.Decide(ctx, in)
+	if err != nil {
+		return apperror.InternalServerError(err, "authorization error", apperror.StatusAuthzError)
+	}
+
+	return ctx.JSON(d)
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/mfa"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/user"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+type devicePage struct {
+	Action string
+	Device *oauth.DeviceInfo
+	// Challenge is the ID of the MFA challenge of a user who entered the
+	// right password and is asked for a code.
+	Challenge string
+	Message   string
+	Done      bool
+}
+
+// HandleDeviceAuthorization is the device authorization endpoint (RFC 8628
+// section 3.1).
+func (h *Handler) HandleDeviceAuthorization(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	req := oauth.DeviceAuthorizationRequest{
+		Client: clientCredentials(ctx),
+		Scope:  ctx.FormValue("scope"),
+	}
+	res, err := h.oauthService(ctx).DeviceAuthorization(ctx, req)
+	if err != nil {
+		return oauthError(ctx, err)
+	}
+
+	return ctx.JSON(res)
+}
+
+// HandleDevicePage asks the user for a user code and shows what the device
+// is requesting.
+func (h *Handler) HandleDevicePage(ctx fiber.Ctx) error {
+	page := devicePage{Action: h.path(ctx, oauth.DeviceVerificationPath)}
+
+	if code := ctx.Query("user_code"); code != "" {
+		device, err := h.oauthService(ctx).LookupDevice(ctx, code)
+		switch {
+		case errors.Is(err, oauth.ErrDeviceNotFound):
+			page.Message = "That code is invalid or has expired."
+		case err != nil:
+			return err
+		default:
+			page.Device = device
+		}
+	}
+
+	return renderPage(ctx, "device.html", fiber.StatusOK, page)
+}
+
+// HandleDeviceDecision signs the user in, with a code after the password
+// when the user has an authenticator, and records their decision.
+func (h *Handler) HandleDeviceDecision(ctx fiber.Ctx) error {
+	page := devicePage{Action: h.path(ctx, oauth.DeviceVerificationPath)}
+	code := ctx.FormValue("user_code")
+
+	device, err := h.oauthService(ctx).LookupDevice(ctx, code)
+	if errors.Is(err, oauth.ErrDeviceNotFound) {
+		page.Message = "That code is invalid or has expired."
+		return renderPage(ctx, "device.html", fiber.StatusBadRequest, page)
+	}
+	if err != nil {
+		return err
+	}
+
+	var u *user.User
+	if challenge := ctx.FormValue("mfa_challenge"); challenge != "" {
+		// The device grant keeps no authentication methods.
+		u, _, err = h.answerChallenge(ctx, challenge)
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			page.Device = device
+			page.Challenge = challenge
+			page.Message = "Incorrect or already used code."
+			return renderPage(ctx, "device.html", fiber.StatusUnauthorized, page)
+		}
+		if challengeOver(err) {
+			page.Device = device
+			page.Message = "Your sign-in has expired or had too many incorrect codes. Sign in again."
+			return renderPage(ctx, "device.html", fiber.StatusUnauthorized, page)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		u, err = h.Users.Authenticate(ctx, tenantID(ctx), ctx.FormValue("username"), ctx.FormValue("password"))
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			page.Device = device
+			page.Message = "Incorrect username or password."
+			return renderPage(ctx, "device.html", fiber.StatusUnauthorized, page)
+		}
+		if err != nil {
+			return err
+		}
+		if page.Challenge, err = h.startChallenge(ctx, u); err != nil {
+			return err
+		}
+		if page.Challenge != "" {
+			page.Device = device
+			return renderPage(ctx, "device.html", fiber.StatusOK, page)
+		}
+	}
+
+	approve := ctx.FormValue("decision") == "approve"
+	if err := h.oauthService(ctx).DecideDevice(ctx, code, u.ID, approve); err != nil {
+		if errors.Is(err, oauth.ErrDeviceNotFound) {
+			page.Message = "That code is invalid or has expired."
+			return renderPage(ctx, "device.html", fiber.StatusBadRequest, page)
+		}
+		return err
+	}
+
+	page.Done = true
+	page.Message = "Access denied. You can close this window."
+	if approve {
+		page.Message = "Device approved. You can return to your device."
+	}
+	return renderPage(ctx, "device.html", fiber.StatusOK, page)
+}
+
+func renderPage(ctx fiber.Ctx, name string, status int, page any) error {
+	return renderFramingPage(ctx, name, status, page, nil)
+}
+
+// renderFramingPage renders a page that loads iframes from frameOrigins.
+func renderFramingPage(ctx fiber.Ctx, name string, status int, page any, frameOrigins []string) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, page); err != nil {
+		return err
+	}
+
+	// Pages only run the passkey script, which calls back to this server.
+	csp := "default-src 'none'; script-src 'self'; connect-src 'self'; form-action 'self'; frame-ancestors 'none'"
+	if len(frameOrigins) > 0 {
+		csp += "; frame-src " + strings.Join(frameOrigins, " ")
+	}
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(fiber.HeaderXFrameOptions, "DENY")
+	ctx.Set(fiber.HeaderContentSecurityPolicy, csp)
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.Status(status).Send(buf.Bytes())
+}
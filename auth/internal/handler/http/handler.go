@@ -1,26 +1,121 @@
 package http
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v3"
 	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/mfa"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/rbac"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tenant"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/user"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/webauthn"
 	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/policy"
 )
 
+type issuerKey struct{}
+
 type Handler struct {
-	Mgr *key.Manager
+	// Mgr and OAuth are the root issuer's. Routes below ResolveTenant
+	// serve the tenant's issuer instead.
+	Mgr     *key.Manager
+	OAuth   *oauth.Service
+	Users   *user.Service
+	RBAC    *rbac.Service
+	Tenants *tenant.Service
+	Issuers *tenant.Registry
+	// Sessions keeps the users' browser sign-ins at every issuer.
+	Sessions *session.Manager
+	// MFA asks users with an authenticator for a code after the password.
+	MFA *mfa.Service
+	// WebAuthn signs users in with passkeys.
+	WebAuthn *webauthn.Service
+	// Authz evaluates the policies of the decision endpoint.
+	Authz policy.Decider
 }
 
-func NewHandler(mgr *key.Manager) *Handler {
+func NewHandler(mgr *key.Manager, oauth *oauth.Service, users *user.Service, rbac *rbac.Service, tenants *tenant.Service, issuers *tenant.Registry, sessions *session.Manager, mfa *mfa.Service, webauthn *webauthn.Service, authz policy.Decider) *Handler {
 	return &Handler{
-		Mgr: mgr,
+		Mgr:      mgr,
+		OAuth:    oauth,
+		Users:    users,
+		RBAC:     rbac,
+		Tenants:  tenants,
+		Issuers:  issuers,
+		Sessions: sessions,
+		MFA:      mfa,
+		WebAuthn: webauthn,
+		Authz:    authz,
+	}
+}
+
+// ResolveTenant makes the routes after it serve the issuer of the tenant
+// named by the :tenant route parameter.
+func (h *Handler) ResolveTenant(ctx fiber.Ctx) error {
+	iss, err := h.Issuers.Issuer(ctx, ctx.Params("tenant"))
+	if errors.Is(err, tenant.ErrNotFound) {
+		return apperror.NotFoundError(err, "tenant not found", apperror.StatusTenantError)
+	}
+	if err != nil {
+		return apperror.InternalServerError(err, "get tenant error", apperror.StatusTenantError)
+	}
+
+	ctx.Locals(issuerKey{}, iss)
+	return ctx.Next()
+}
+
+// oauthService returns the token service of the issuer ctx is for.
+func (h *Handler) oauthService(ctx fiber.Ctx) *oauth.Service {
+	if iss := fiber.Locals[*tenant.Issuer](ctx, issuerKey{}); iss != nil {
+		return iss.OAuth
+	}
+	return h.OAuth
+}
+
+// keyManager returns the key manager of the issuer ctx is for.
+func (h *Handler) keyManager(ctx fiber.Ctx) *key.Manager {
+	if iss := fiber.Locals[*tenant.Issuer](ctx, issuerKey{}); iss != nil {
+		return iss.Keys
+	}
+	return h.Mgr
+}
+
+// path returns the path of route p of the issuer ctx is for.
+func (h *Handler) path(ctx fiber.Ctx, p string) string {
+	if iss := fiber.Locals[*tenant.Issuer](ctx, issuerKey{}); iss != nil {
+		return tenant.PathPrefix + iss.Tenant.ID + p
 	}
+	return p
+}
+
+// HandleMetadata publishes the authorization server metadata (RFC 8414).
+func (h *Handler) HandleMetadata(ctx fiber.Ctx) error {
+	md, err := h.oauthService(ctx).Metadata(ctx)
+	if err != nil {
+		return apperror.InternalServerError(err, "get metadata error", apperror.StatusInternalServerError)
+	}
+	return ctx.JSON(md)
 }
 
 func (h *Handler) HandleJWKS(ctx fiber.Ctx) error {
-	jwks, err := h.Mgr.JWKS(ctx)
+	jwks, err := h.keyManager(ctx).JWKS(ctx)
 	if err != nil {
 		return apperror.InternalServerError(err, "get jwks error", apperror.StatusJWKError)
 	}
 
 	return ctx.JSON(jwks)
 }
+
+// HandlePASERK publishes the public PASETO keys as k4.public PASERKs with
+// their k4.pid identifiers.
+func (h *Handler) HandlePASERK(ctx fiber.Ctx) error {
+	keys, err := h.keyManager(ctx).PASERKs(ctx)
+	if err != nil {
+		return apperror.InternalServerError(err, "get paserk error", apperror.StatusJWKError)
+	}
+
+	return ctx.JSON(keys)
+}
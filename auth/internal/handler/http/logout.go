@@ -0,0 +1,107 @@
+package http
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+type logoutPage struct {
+	Action string
+	// Confirm is the request to post again when the user must confirm.
+	Confirm    *logoutForm
+	SignedInAs string
+	// SignedOut shows the result, loading FrontchannelURLs in iframes to
+	// sign the user out of the clients too.
+	SignedOut        bool
+	FrontchannelURLs []string
+	RedirectURI      string
+	Message          string
+}
+
+type logoutForm struct {
+	IDTokenHint           string
+	ClientID              string
+	PostLogoutRedirectURI string
+	State                 string
+}
+
+// HandleEndSession is the end session endpoint (OpenID Connect RP-Initiated
+// Logout 1.0 section 2). It ends the user's session, tells the clients the
+// session was used with, and sends the user agent to the client's
+// post_logout_redirect_uri, if any. Unless id_token_hint names the session,
+// the user confirms first, since any site could send the user here.
+func (h *Handler) HandleEndSession(ctx fiber.Ctx) error {
+	args := ctx.RequestCtx().QueryArgs()
+	if ctx.Method() == fiber.MethodPost {
+		args = ctx.RequestCtx().PostArgs()
+	}
+	params := url.Values{}
+	for k, v := range args.All() {
+		params.Add(string(k), string(v))
+	}
+
+	page := logoutPage{Action: h.path(ctx, oauth.EndSessionPath)}
+	req, err := h.oauthService(ctx).EndSession(ctx, params)
+	if err != nil {
+		var oerr *oauth.Error
+		if !errors.As(err, &oerr) {
+			return err
+		}
+		if oerr.Code == oauth.CodeServerError {
+			log.Errorf("end session endpoint: %v", oerr)
+		}
+		page.Message = "The application sent an invalid sign-out request."
+		if oerr.Description != "" {
+			page.Message += " " + oerr.Description + "."
+		}
+		return renderPage(ctx, "logout.html", oerr.Status, page)
+	}
+
+	var frontchannel []string
+	if sess := h.currentSession(ctx); sess != nil {
+		confirmed := ctx.Method() == fiber.MethodPost && params.Get("confirm") == "yes"
+		if req.SID != sess.ID && !confirmed {
+			page.SignedInAs = sess.Username
+			page.Confirm = &logoutForm{
+				IDTokenHint:           params.Get("id_token_hint"),
+				ClientID:              params.Get("client_id"),
+				PostLogoutRedirectURI: params.Get("post_logout_redirect_uri"),
+				State:                 params.Get("state"),
+			}
+			return renderPage(ctx, "logout.html", fiber.StatusOK, page)
+		}
+		if frontchannel, err = h.endSession(ctx, sess); err != nil {
+			return err
+		}
+	}
+
+	if len(frontchannel) == 0 && req.RedirectURI != "" {
+		ctx.Set(fiber.HeaderCacheControl, "no-store")
+		return ctx.Redirect().Status(fiber.StatusSeeOther).To(req.RedirectURI)
+	}
+	page.SignedOut = true
+	page.FrontchannelURLs = frontchannel
+	page.RedirectURI = req.RedirectURI
+	return renderFramingPage(ctx, "logout.html", fiber.StatusOK, page, origins(frontchannel))
+}
+
+// origins returns the distinct origins of uris.
+func origins(uris []string) []string {
+	var out []string
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if !slices.Contains(out, origin) {
+			out = append(out, origin)
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/mfa"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/user"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+)
+
+// startChallenge asks u, who just entered the right password, for a code
+// when u has an authenticator. It returns the ID of the challenge to post
+// the code with, or "" when the password is enough.
+func (h *Handler) startChallenge(ctx fiber.Ctx, u *user.User) (string, error) {
+	enrolled, err := h.MFA.Enrolled(ctx, tenantID(ctx), u.ID)
+	if err != nil || !enrolled {
+		return "", err
+	}
+	return h.MFA.StartChallenge(ctx, tenantID(ctx), u.ID, u.Username)
+}
+
+// answerChallenge returns the user who answered the challenge with the
+// given ID with the code posted with ctx, and the authentication method of
+// the code.
+func (h *Handler) answerChallenge(ctx fiber.Ctx, id string) (*user.User, string, error) {
+	c, err := h.MFA.AnswerChallenge(ctx, tenantID(ctx), id, ctx.FormValue("code"))
+	if err != nil {
+		return nil, "", err
+	}
+	amr := session.AMROTP
+	if c.Factor == mfa.FactorRecoveryCode {
+		amr = session.AMRRecoveryCode
+	}
+	return &user.User{ID: c.UserID, Username: c.Username}, amr, nil
+}
+
+// challengeOver reports whether err means the user must start over with
+// the password.
+func challengeOver(err error) bool {
+	return errors.Is(err, mfa.ErrChallengeNotFound) || errors.Is(err, mfa.ErrTooManyAttempts) ||
+		errors.Is(err, mfa.ErrNotEnrolled)
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// HandleGetMFA returns the second factors of a user.
+func (h *Handler) HandleGetMFA(ctx fiber.Ctx) error {
+	status, err := h.MFA.Status(ctx, tenantID(ctx), ctx.Params("id"))
+	switch {
+	case errors.Is(err, mfa.ErrUserNotFound):
+		return apperror.NotFoundError(err, "user not found", apperror.StatusMFAError)
+	case err != nil:
+		return apperror.InternalServerError(err, "get mfa error", apperror.StatusMFAError)
+	}
+
+	return ctx.JSON(status)
+}
+
+// HandleEnrollTOTP creates an authenticator for a user and returns its
+// otpauth URI. The user is asked for codes once it is confirmed.
+func (h *Handler) HandleEnrollTOTP(ctx fiber.Ctx) error {
+	enrolment, err := h.MFA.Enroll(ctx, tenantID(ctx), ctx.Params("id"))
+	switch {
+	case errors.Is(err, mfa.ErrUserNotFound):
+		return apperror.NotFoundError(err, "user not found", apperror.StatusMFAError)
+	case errors.Is(err, mfa.ErrAlreadyEnrolled):
+		return apperror.ConflictError(err, "user already has an authenticator", apperror.StatusMFAError)
+	case err != nil:
+		return apperror.InternalServerError(err, "enroll totp error", apperror.StatusMFAError)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.Status(fiber.StatusCreated).JSON(enrolment)
+}
+
+// HandleConfirmTOTP turns on the authenticator of a user with a code from
+// it and returns the user's recovery codes.
+func (h *Handler) HandleConfirmTOTP(ctx fiber.Ctx) error {
+	var req confirmTOTPRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	codes, err := h.MFA.Confirm(ctx, tenantID(ctx), ctx.Params("id"), req.Code)
+	switch {
+	case errors.Is(err, mfa.ErrNoPendingEnrolment):
+		return apperror.NotFoundError(err, "no authenticator to confirm", apperror.StatusMFAError)
+	case errors.Is(err, mfa.ErrAlreadyEnrolled):
+		return apperror.ConflictError(err, "authenticator already confirmed", apperror.StatusMFAError)
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return apperror.BadRequestError(err, "invalid code", apperror.StatusMFAError)
+	case err != nil:
+		return apperror.InternalServerError(err, "confirm totp error", apperror.StatusMFAError)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.JSON(recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// HandleDeleteTOTP removes the authenticator and recovery codes of a user.
+func (h *Handler) HandleDeleteTOTP(ctx fiber.Ctx) error {
+	err := h.MFA.Disable(ctx, tenantID(ctx), ctx.Params("id"))
+	switch {
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return apperror.NotFoundError(err, "authenticator not found", apperror.StatusMFAError)
+	case err != nil:
+		return apperror.InternalServerError(err, "delete totp error", apperror.StatusMFAError)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleRegenerateRecoveryCodes replaces the recovery codes of a user.
+func (h *Handler) HandleRegenerateRecoveryCodes(ctx fiber.Ctx) error {
+	codes, err := h.MFA.RegenerateRecoveryCodes(ctx, tenantID(ctx), ctx.Params("id"))
+	switch {
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return apperror.NotFoundError(err, "authenticator not found", apperror.StatusMFAError)
+	case err != nil:
+		return apperror.InternalServerError(err, "regenerate recovery codes error", apperror.StatusMFAError)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.JSON(recoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/keyauth"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+)
+
+// AdminAuth guards admin routes with a static bearer token.
+func AdminAuth(token string) fiber.Handler {
+	return keyauth.New(keyauth.Config{
+		Validator: func(_ fiber.Ctx, key string) (bool, error) {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(token)) != 1 {
+				return false, keyauth.ErrMissingOrMalformedAPIKey
+			}
+			return true, nil
+		},
+		ErrorHandler: func(_ fiber.Ctx, err error) error {
+			return apperror.UnauthorizedError(err, "invalid admin token", apperror.StatusUnauthorized)
+		},
+	})
+}
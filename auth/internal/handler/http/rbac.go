@@ -0,0 +1,157 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/rbac"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+)
+
+type createRBACObjectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (h *Handler) HandleCreatePermission(ctx fiber.Ctx) error {
+	var req createRBACObjectRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	p, err := h.RBAC.CreatePermission(ctx, tenantID(ctx), req.Name, req.Description)
+	if err != nil {
+		return rbacError(err, "create permission error")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(p)
+}
+
+func (h *Handler) HandleListPermissions(ctx fiber.Ctx) error {
+	perms, err := h.RBAC.ListPermissions(ctx, tenantID(ctx))
+	if err != nil {
+		return rbacError(err, "list permissions error")
+	}
+
+	return ctx.JSON(perms)
+}
+
+func (h *Handler) HandleDeletePermission(ctx fiber.Ctx) error {
+	if err := h.RBAC.DeletePermission(ctx, tenantID(ctx), ctx.Params("name")); err != nil {
+		return rbacError(err, "delete permission error")
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) HandleCreateRole(ctx fiber.Ctx) error {
+	var req createRBACObjectRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	r, err := h.RBAC.CreateRole(ctx, tenantID(ctx), req.Name, req.Description)
+	if err != nil {
+		return rbacError(err, "create role error")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(r)
+}
+
+func (h *Handler) HandleListRoles(ctx fiber.Ctx) error {
+	roles, err := h.RBAC.ListRoles(ctx, tenantID(ctx))
+	if err != nil {
+		return rbacError(err, "list roles error")
+	}
+
+	return ctx.JSON(roles)
+}
+
+// HandleGetRole returns a role with the permissions it grants.
+func (h *Handler) HandleGetRole(ctx fiber.Ctx) error {
+	r, err := h.RBAC.GetRole(ctx, tenantID(ctx), ctx.Params("name"))
+	if err != nil {
+		return rbacError(err, "get role error")
+	}
+
+	return ctx.JSON(r)
+}
+
+func (h *Handler) HandleDeleteRole(ctx fiber.Ctx) error {
+	if err := h.RBAC.DeleteRole(ctx, tenantID(ctx), ctx.Params("name")); err != nil {
+		return rbacError(err, "delete role error")
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleGrantPermission lets the role named in the path grant the permission
+// named after it.
+func (h *Handler) HandleGrantPermission(ctx fiber.Ctx) error {
+	if err := h.RBAC.Grant(ctx, tenantID(ctx), ctx.Params("name"), ctx.Params("permission")); err != nil {
+		return rbacError(err, "grant permission error")
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) HandleRevokePermission(ctx fiber.Ctx) error {
+	if err := h.RBAC.Revoke(ctx, tenantID(ctx), ctx.Params("name"), ctx.Params("permission")); err != nil {
+		return rbacError(err, "revoke permission error")
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+type roleBindingRequest struct {
+	Subject  string `json:"subject"`
+	Role     string `json:"role"`
+	Resource string `json:"resource"`
+}
+
+func (h *Handler) HandleCreateRoleBinding(ctx fiber.Ctx) error {
+	var req roleBindingRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	b, err := h.RBAC.Bind(ctx, tenantID(ctx), req.Subject, req.Role, req.Resource)
+	if err != nil {
+		return rbacError(err, "create role binding error")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(b)
+}
+
+// HandleListRoleBindings lists the bindings of the subject query parameter.
+func (h *Handler) HandleListRoleBindings(ctx fiber.Ctx) error {
+	bindings, err := h.RBAC.ListBindings(ctx, tenantID(ctx), ctx.Query("subject"))
+	if err != nil {
+		return rbacError(err, "list role bindings error")
+	}
+
+	return ctx.JSON(bindings)
+}
+
+// HandleDeleteRoleBinding deletes the binding named by the subject, role and
+// resource query parameters.
+func (h *Handler) HandleDeleteRoleBinding(ctx fiber.Ctx) error {
+	if err := h.RBAC.Unbind(ctx, tenantID(ctx), ctx.Query("subject"), ctx.Query("role"), ctx.Query("resource")); err != nil {
+		return rbacError(err, "delete role binding error")
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func rbacError(err error, msg string) error {
+	switch {
+	case errors.Is(err, rbac.ErrInvalidName), errors.Is(err, rbac.ErrInvalidSubject):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusRBACError)
+	case errors.Is(err, rbac.ErrPermissionExists), errors.Is(err, rbac.ErrRoleExists), errors.Is(err, rbac.ErrBindingExists):
+		return apperror.ConflictError(err, err.Error(), apperror.StatusRBACError)
+	case errors.Is(err, rbac.ErrPermissionNotFound), errors.Is(err, rbac.ErrRoleNotFound), errors.Is(err, rbac.ErrBindingNotFound):
+		return apperror.NotFoundError(err, err.Error(), apperror.StatusRBACError)
+	default:
+		return apperror.InternalServerError(err, msg, apperror.StatusRBACError)
+	}
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+// HandleRegisterClient is the client registration endpoint (RFC 7591
+// section 3).
+func (h *Handler) HandleRegisterClient(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	var md oauth.ClientMetadata
+	if err := json.Unmarshal(ctx.Body(), &md); err != nil {
+		return oauthError(ctx, invalidMetadataBody(err))
+	}
+
+	info, err := h.oauthService(ctx).RegisterClient(ctx, bearerToken(ctx), md)
+	if err != nil {
+		return oauthError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderLocation, info.RegistrationClientURI)
+	return ctx.Status(fiber.StatusCreated).JSON(info)
+}
+
+// HandleReadClient is the client read request of the client configuration
+// endpoint (RFC 7592 section 2.1).
+func (h *Handler) HandleReadClient(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	info, err := h.oauthService(ctx).ReadClient(ctx, ctx.Params("client_id"), bearerToken(ctx))
+	if err != nil {
+		return oauthError(ctx, err)
+	}
+
+	return ctx.JSON(info)
+}
+
+// HandleUpdateClient is the client update request of the client
+// configuration endpoint (RFC 7592 section 2.2).
+func (h *Handler) HandleUpdateClient(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	var req oauth.ClientUpdateRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		return oauthError(ctx, invalidMetadataBody(err))
+	}
+
+	info, err := h.oauthService(ctx).UpdateClient(ctx, ctx.Params("client_id"), bearerToken(ctx), req)
+	if err != nil {
+		return oauthError(ctx, err)
+	}
+
+	return ctx.JSON(info)
+}
+
+// HandleDeleteClient is the client delete request of the client
+// configuration endpoint (RFC 7592 section 2.3).
+func (h *Handler) HandleDeleteClient(ctx fiber.Ctx) error {
+	if err := h.oauthService(ctx).DeleteClient(ctx, ctx.Params("client_id"), bearerToken(ctx)); err != nil {
+		return oauthError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func bearerToken(ctx fiber.Ctx) string {
+	scheme, token, found := strings.Cut(ctx.Get(fiber.HeaderAuthorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func invalidMetadataBody(err error) *oauth.Error {
+	return &oauth.Error{
+		Code:        oauth.CodeInvalidClientMetadata,
+		Description: "request body must be a JSON object",
+		Status:      fiber.StatusBadRequest,
+		Err:         err,
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tenant"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/user"
+)
+
+// tenantID returns the ID of the tenant ctx is for, or "" for the root
+// issuer.
+func tenantID(ctx fiber.Ctx) string {
+	if iss := fiber.Locals[*tenant.Issuer](ctx, issuerKey{}); iss != nil {
+		return iss.Tenant.ID
+	}
+	return ""
+}
+
+// currentSession returns the session of the cookie sent with ctx, or nil
+// when there is none. A session that cannot be read is treated as absent,
+// so the user signs in again.
+func (h *Handler) currentSession(ctx fiber.Ctx) *session.Session {
+	tenant := tenantID(ctx)
+	value := ctx.Cookies(h.Sessions.CookieName(tenant))
+	if value == "" {
+		return nil
+	}
+	s, err := h.Sessions.Lookup(ctx, tenant, value)
+	if err != nil {
+		if !errors.Is(err, session.ErrNotFound) {
+			log.Errorf("session: lookup: %v", err)
+		}
+		return nil
+	}
+	return s
+}
+
+// startSession starts a session for u, who just signed in with the methods
+// amr, replacing the session old, and sets its cookie.
+func (h *Handler) startSession(ctx fiber.Ctx, u *user.User, amr []string, old *session.Session) (*session.Session, error) {
+	acr := session.ACRSingleFactor
+	if len(amr) > 1 {
+		acr = session.ACRMultiFactor
+	}
+	tenant := tenantID(ctx)
+	s, value, err := h.Sessions.Create(ctx, tenant, u.Username, oauth.Authentication{
+		Subject:  u.ID,
+		AuthTime: time.Now(),
+		AMR:      amr,
+		ACR:      acr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if old != nil {
+		if err := h.Sessions.Delete(ctx, old.ID); err != nil {
+			log.Warnf("session: delete replaced session: %v", err)
+		}
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     h.Sessions.CookieName(tenant),
+		Value:    value,
+		Path:     "/",
+		Expires:  s.ExpiresAt,
+		Secure:   h.Sessions.CookieSecure(),
+		HTTPOnly: true,
+		// Lax keeps the cookie on the top-level navigations clients start
+		// sign-ins with, but off cross-site subrequests and form posts.
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return s, nil
+}
+
+// joinSession records that the session sid was used to sign in to clientID,
+// so the client is told when it ends. Failing to record it does not fail
+// the sign-in.
+func (h *Handler) joinSession(ctx fiber.Ctx, sid, clientID string) {
+	if err := h.Sessions.AddClient(ctx, sid, clientID); err != nil {
+		log.Errorf("session: add client %s to session: %v", clientID, err)
+	}
+}
+
+// endSession ends sess, clears its cookie and tells the clients it was used
+// with. It returns the front-channel logout URLs to load.
+func (h *Handler) endSession(ctx fiber.Ctx, sess *session.Session) ([]string, error) {
+	if err := h.Sessions.Delete(ctx, sess.ID); err != nil {
+		return nil, err
+	}
+	ctx.Cookie(&fiber.Cookie{
+		Name:     h.Sessions.CookieName(sess.Tenant),
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   h.Sessions.CookieSecure(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	frontchannel, err := h.oauthService(ctx).SessionEnded(ctx, sess.Subject, sess.ID, sess.Clients)
+	if err != nil {
+		// The user is signed out either way.
+		log.Errorf("session: notify clients of logout: %v", err)
+	}
+	return frontchannel, nil
+}
@@ -0,0 +1,85 @@
+// Runs the WebAuthn ceremonies of the sign-in and passkey pages. Binary
+// fields travel base64url encoded, as in the WebAuthn JSON serialization.
+(() => {
+  "use strict";
+
+  const decode = (s) =>
+    Uint8Array.from(atob(s.replace(/-/g, "+").replace(/_/g, "/")), (c) => c.charCodeAt(0));
+  const encode = (b) =>
+    btoa(String.fromCharCode(...new Uint8Array(b)))
+      .replace(/\+/g, "-")
+      .replace(/\//g, "_")
+      .replace(/=+$/, "");
+
+  const post = async (url, body) => {
+    const res = await fetch(url, {
+      method: "POST",
+      credentials: "same-origin",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(body ?? {}),
+    });
+    const data = await res.json();
+    if (!res.ok) throw new Error((data.error && data.error.message) || res.statusText);
+    return data;
+  };
+
+  const status = (text) => {
+    const el = document.querySelector("[data-webauthn-status]");
+    if (el) el.textContent = text;
+  };
+
+  // Sign-in: the assertion is posted with the authorization form.
+  const login = document.querySelector("[data-webauthn-login]");
+  if (login && window.PublicKeyCredential) {
+    login.hidden = false;
+    login.addEventListener("click", async () => {
+      const form = login.form;
+      try {
+        const opts = await post(login.dataset.webauthnLogin);
+        opts.challenge = decode(opts.challenge);
+        const cred = await navigator.credentials.get({ publicKey: opts });
+        form.elements.webauthn_response.value = JSON.stringify({
+          rawId: encode(cred.rawId),
+          type: cred.type,
+          response: {
+            clientDataJSON: encode(cred.response.clientDataJSON),
+            authenticatorData: encode(cred.response.authenticatorData),
+            signature: encode(cred.response.signature),
+            userHandle: cred.response.userHandle ? encode(cred.response.userHandle) : "",
+          },
+        });
+        for (const el of form.querySelectorAll("[required]")) el.required = false;
+        form.submit();
+      } catch (err) {
+        status("Passkey sign-in failed: " + err.message);
+      }
+    });
+  }
+
+  // Registration for the signed-in user.
+  const register = document.querySelector("[data-webauthn-register]");
+  if (register && window.PublicKeyCredential) {
+    register.hidden = false;
+    register.addEventListener("click", async () => {
+      try {
+        const opts = await post(register.dataset.webauthnOptions);
+        opts.challenge = decode(opts.challenge);
+        opts.user.id = decode(opts.user.id);
+        for (const c of opts.excludeCredentials) c.id = decode(c.id);
+        const cred = await navigator.credentials.create({ publicKey: opts });
+        await post(register.dataset.webauthnRegister, {
+          rawId: encode(cred.rawId),
+          type: cred.type,
+          response: {
+            clientDataJSON: encode(cred.response.clientDataJSON),
+            attestationObject: encode(cred.response.attestationObject),
+            transports: cred.response.getTransports ? cred.response.getTransports() : [],
+          },
+        });
+        status("Passkey added. You can now sign in with it.");
+      } catch (err) {
+        status("Adding the passkey failed: " + err.message);
+      }
+    });
+  }
+})();
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/tenant"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+)
+
+type tenantRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// KeyRotationPeriod is in seconds.
+	KeyRotationPeriod int64 `json:"key_rotation_period"`
+}
+
+// HandleCreateTenant creates a tenant. Its signing keys are created when
+// one of its routes is first used.
+func (h *Handler) HandleCreateTenant(ctx fiber.Ctx) error {
+	var req tenantRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	t, err := h.Tenants.Create(ctx, req.ID, req.Name, req.KeyRotationPeriod)
+	if err != nil {
+		return tenantError(err, "create tenant error")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(t)
+}
+
+func (h *Handler) HandleListTenants(ctx fiber.Ctx) error {
+	tenants, err := h.Tenants.List(ctx)
+	if err != nil {
+		return tenantError(err, "list tenants error")
+	}
+
+	return ctx.JSON(tenants)
+}
+
+func (h *Handler) HandleGetTenant(ctx fiber.Ctx) error {
+	t, err := h.Tenants.Get(ctx, ctx.Params("tenant"))
+	if err != nil {
+		return tenantError(err, "get tenant error")
+	}
+
+	return ctx.JSON(t)
+}
+
+// HandleUpdateTenant replaces the name and key rotation period of a tenant.
+func (h *Handler) HandleUpdateTenant(ctx fiber.Ctx) error {
+	var req tenantRequest
+	if err := ctx.Bind().JSON(&req); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	t, err := h.Tenants.Update(ctx, ctx.Params("tenant"), req.Name, req.KeyRotationPeriod)
+	if err != nil {
+		return tenantError(err, "update tenant error")
+	}
+
+	return ctx.JSON(t)
+}
+
+type registrationTokenResponse struct {
+	InitialAccessToken string `json:"initial_access_token"`
+}
+
+// HandleIssueRegistrationToken replaces the initial access token clients
+// present to register with a tenant. The token is only shown here.
+func (h *Handler) HandleIssueRegistrationToken(ctx fiber.Ctx) error {
+	token, err := h.Tenants.IssueRegistrationToken(ctx, ctx.Params("tenant"))
+	if err != nil {
+		return tenantError(err, "issue registration token error")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(registrationTokenResponse{InitialAccessToken: token})
+}
+
+// HandleDisableRegistration removes the initial access token of a tenant.
+func (h *Handler) HandleDisableRegistration(ctx fiber.Ctx) error {
+	if err := h.Tenants.DisableRegistration(ctx, ctx.Params("tenant")); err != nil {
+		return tenantError(err, "disable registration error")
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func tenantError(err error, msg string) error {
+	switch {
+	case errors.Is(err, tenant.ErrInvalidID), errors.Is(err, tenant.ErrInvalidRotationPeriod):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusTenantError)
+	case errors.Is(err, tenant.ErrExists):
+		return apperror.ConflictError(err, err.Error(), apperror.StatusTenantError)
+	case errors.Is(err, tenant.ErrNotFound):
+		return apperror.NotFoundError(err, err.Error(), apperror.StatusTenantError)
+	default:
+		return apperror.InternalServerError(err, msg, apperror.StatusTenantError)
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+// HandleToken is the OAuth 2.0 token endpoint (RFC 6749 section 3.2).
+func (h *Handler) HandleToken(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	if nonce := h.oauthService(ctx).Nonce(); nonce != "" {
+		ctx.Set("DPoP-Nonce", nonce)
+	}
+
+	req := oauth.TokenRequest{
+		GrantType: ctx.FormValue("grant_type"),
+		Scope:     ctx.FormValue("scope"),
+		Client:    clientCredentials(ctx),
+		DPoP:      ctx.GetReqHeaders()["Dpop"],
+		Resource:  formValues(ctx, "resource"),
+
+		Code:         ctx.FormValue("code"),
+		RedirectURI:  ctx.FormValue("redirect_uri"),
+		CodeVerifier: ctx.FormValue("code_verifier"),
+
+		Assertion:  ctx.FormValue("assertion"),
+		DeviceCode: ctx.FormValue("device_code"),
+
+		SubjectToken:       ctx.FormValue("subject_token"),
+		SubjectTokenType:   ctx.FormValue("subject_token_type"),
+		ActorToken:         ctx.FormValue("actor_token"),
+		ActorTokenType:     ctx.FormValue("actor_token_type"),
+		RequestedTokenType: ctx.FormValue("requested_token_type"),
+		Audience:           formValues(ctx, "audience"),
+	}
+
+	res, err := h.oauthService(ctx).Token(ctx, req)
+	if err != nil {
+		return oauthError(ctx, err)
+	}
+
+	return ctx.JSON(res)
+}
+
+// clientCredentials collects every way a client may have authenticated on
+// the request.
+func clientCredentials(ctx fiber.Ctx) oauth.ClientCredentials {
+	cred := oauth.ClientCredentials{
+		FormID:     ctx.FormValue("client_id"),
+		FormSecret: ctx.FormValue("client_secret"),
+
+		AssertionType: ctx.FormValue("client_assertion_type"),
+		Assertion:     ctx.FormValue("client_assertion"),
+	}
+	if id, secret, ok := basicAuth(ctx.Get(fiber.HeaderAuthorization)); ok {
+		cred.BasicID, cred.BasicSecret, cred.HasBasic = id, secret, true
+	}
+	if state := ctx.RequestCtx().TLSConnectionState(); state != nil {
+		cred.Certificates = state.PeerCertificates
+	}
+	return cred
+}
+
+// oauthError renders err as an RFC 6749 error response.
+func oauthError(ctx fiber.Ctx, err error) error {
+	var oerr *oauth.Error
+	if !errors.As(err, &oerr) {
+		return err
+	}
+
+	switch oerr.Code {
+	case oauth.CodeInvalidClient:
+		ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	case oauth.CodeInvalidToken:
+		ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+	case oauth.CodeUseDPoPNonce, oauth.CodeInvalidDPoPProof:
+		ctx.Set(fiber.HeaderWWWAuthenticate, `DPoP error="`+oerr.Code+`"`)
+	case oauth.CodeServerError:
+		log.Errorf("oauth endpoint: %v", oerr)
+	}
+
+	return ctx.Status(oerr.Status).JSON(oerr)
+}
+
+func basicAuth(header string) (id, secret string, ok bool) {
+	scheme, encoded, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Basic") {
+		return "", "", false
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(raw), ":")
+}
+
+// formValues returns every value of a repeatable form parameter.
+func formValues(ctx fiber.Ctx, name string) []string {
+	var values []string
+	for _, v := range ctx.RequestCtx().PostArgs().PeekMulti(name) {
+		values = append(values, string(v))
+	}
+	return values
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/webauthn"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+)
+
+//go:embed static/webauthn.js
+var webauthnScript []byte
+
+var errNoSession = errors.New("http: no browser session")
+
+type webauthnPage struct {
+	Script        string
+	OptionsAction string
+	Action        string
+	// SignedInAs is the username of the user's session, who registers the
+	// passkey. The user is told to sign in first when it is empty.
+	SignedInAs string
+	Message    string
+}
+
+// HandleWebAuthnScript serves the script that runs the ceremonies in the
+// browser.
+func (h *Handler) HandleWebAuthnScript(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextJavaScriptCharsetUTF8)
+	return ctx.Send(webauthnScript)
+}
+
+// HandleWebAuthnPage lets the user of the browser session register a
+// passkey.
+func (h *Handler) HandleWebAuthnPage(ctx fiber.Ctx) error {
+	page := webauthnPage{
+		Script:        h.path(ctx, webauthn.ScriptPath),
+		OptionsAction: h.path(ctx, webauthn.RegisterOptionsPath),
+		Action:        h.path(ctx, webauthn.RegisterPath),
+	}
+	if sess := h.currentSession(ctx); sess != nil {
+		page.SignedInAs = sess.Username
+	} else {
+		page.Message = "Sign in to an application first, then come back to add a passkey."
+	}
+	return renderPage(ctx, "webauthn.html", fiber.StatusOK, page)
+}
+
+// HandleWebAuthnRegisterOptions starts registering a passkey for the user of
+// the browser session.
+func (h *Handler) HandleWebAuthnRegisterOptions(ctx fiber.Ctx) error {
+	sess := h.currentSession(ctx)
+	if sess == nil {
+		return apperror.UnauthorizedError(errNoSession, "sign in first", apperror.StatusUnauthorized)
+	}
+
+	opts, err := h.WebAuthn.BeginRegistration(ctx, tenantID(ctx), sess.Subject)
+	switch {
+	case errors.Is(err, webauthn.ErrUserNotFound):
+		return apperror.NotFoundError(err, "user not found", apperror.StatusWebAuthnError)
+	case err != nil:
+		return apperror.InternalServerError(err, "begin registration error", apperror.StatusWebAuthnError)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.JSON(opts)
+}
+
+// HandleWebAuthnRegister stores the passkey the browser created with the
+// options of HandleWebAuthnRegisterOptions.
+func (h *Handler) HandleWebAuthnRegister(ctx fiber.Ctx) error {
+	sess := h.currentSession(ctx)
+	if sess == nil {
+		return apperror.UnauthorizedError(errNoSession, "sign in first", apperror.StatusUnauthorized)
+	}
+	var resp webauthn.RegistrationResponse
+	if err := ctx.Bind().JSON(&resp); err != nil {
+		return apperror.BadRequestError(err, "invalid request body", apperror.StatusBadRequest)
+	}
+
+	cred, err := h.WebAuthn.FinishRegistration(ctx, tenantID(ctx), sess.Subject, resp)
+	switch {
+	case errors.Is(err, webauthn.ErrChallengeNotFound), errors.Is(err, webauthn.ErrInvalidResponse),
+		errors.Is(err, webauthn.ErrUnsupportedAlgorithm), errors.Is(err, webauthn.ErrUnsupportedAttestation):
+		return apperror.BadRequestError(err, err.Error(), apperror.StatusWebAuthnError)
+	case errors.Is(err, webauthn.ErrCredentialExists):
+		return apperror.ConflictError(err, "passkey already registered", apperror.StatusWebAuthnError)
+	case errors.Is(err, webauthn.ErrUserNotFound):
+		return apperror.NotFoundError(err, "user not found", apperror.StatusWebAuthnError)
+	case err != nil:
+		return apperror.InternalServerError(err, "finish registration error", apperror.StatusWebAuthnError)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(cred)
+}
+
+// HandleWebAuthnLoginOptions starts a passkey sign-in. The response is
+// posted to the authorization endpoint with the rest of the sign-in form.
+func (h *Handler) HandleWebAuthnLoginOptions(ctx fiber.Ctx) error {
+	opts, err := h.WebAuthn.BeginLogin(ctx, tenantID(ctx))
+	if err != nil {
+		return apperror.InternalServerError(err, "begin login error", apperror.StatusWebAuthnError)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.JSON(opts)
+}
+
+// finishPasskeyLogin returns who signed in with the assertion posted as
+// the JSON value.
+func (h *Handler) finishPasskeyLogin(ctx fiber.Ctx, value string) (*webauthn.Login, error) {
+	var resp webauthn.AssertionResponse
+	if err := json.Unmarshal([]byte(value), &resp); err != nil {
+		return nil, fmt.Errorf("%w: %v", webauthn.ErrInvalidResponse, err)
+	}
+	return h.WebAuthn.FinishLogin(ctx, tenantID(ctx), resp)
+}
+
+// passkeyRejected reports whether err means the passkey did not sign the
+// user in, rather than that it could not be checked.
+func passkeyRejected(err error) bool {
+	for _, target := range []error{
+		webauthn.ErrChallengeNotFound, webauthn.ErrInvalidResponse, webauthn.ErrUnsupportedAlgorithm,
+		webauthn.ErrCredentialNotFound, webauthn.ErrSignCount, webauthn.ErrUserNotFound,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleListWebAuthnCredentials returns the passkeys of a user.
+func (h *Handler) HandleListWebAuthnCredentials(ctx fiber.Ctx) error {
+	creds, err := h.WebAuthn.Credentials(ctx, tenantID(ctx), ctx.Params("id"))
+	if err != nil {
+		return apperror.InternalServerError(err, "list credentials error", apperror.StatusWebAuthnError)
+	}
+
+	return ctx.JSON(creds)
+}
+
+// HandleDeleteWebAuthnCredential removes a passkey of a user. The
+// credential ID is base64url encoded.
+func (h *Handler) HandleDeleteWebAuthnCredential(ctx fiber.Ctx) error {
+	id, err := base64.RawURLEncoding.DecodeString(ctx.Params("credential_id"))
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid credential id", apperror.StatusBadRequest)
+	}
+
+	err = h.WebAuthn.DeleteCredential(ctx, tenantID(ctx), ctx.Params("id"), id)
+	switch {
+	case errors.Is(err, webauthn.ErrCredentialNotFound):
+		return apperror.NotFoundError(err, "credential not found", apperror.StatusWebAuthnError)
+	case err != nil:
+		return apperror.InternalServerError(err, "delete credential error", apperror.StatusWebAuthnError)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
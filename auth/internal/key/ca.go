@@ -0,0 +1,264 @@
+package key
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+)
+
+// caKID is the kid bound into the CA private key envelope.
+const caKID = "ca"
+
+type CAConfig struct {
+	// Enabled makes the manager certify every published signing key and
+	// publish x5c / x5t#S256 in the JWKS.
+	Enabled    bool          `env:"ENABLED"`
+	CommonName string        `env:"COMMON_NAME" envDefault:"auth-service signing CA"`
+	Validity   time.Duration `env:"VALIDITY" envDefault:"87600h"`
+	// LeafValidity is how long key certificates are valid. A key stays
+	// published until two rotations later, so its certificate is renewed
+	// once past half its validity, see Manager.Certify.
+	LeafValidity time.Duration `env:"LEAF_VALIDITY" envDefault:"8760h"`
+}
+
+// CA issues certificates for signing keys.
+type CA struct {
+	Cert         *x509.Certificate
+	priv         crypto.Signer
+	leafValidity time.Duration
+}
+
+// InitCA loads the internal CA from the key store, creating it on first use,
+// certifies the published keys and makes the manager certify keys it
+// generates or imports from now on.
+func (m *Manager) InitCA(ctx context.Context, cfg CAConfig) error {
+	ca, err := m.loadCA(ctx)
+	if errors.Is(err, keystore.ErrNotFound) {
+		err = m.createCA(ctx, cfg)
+		if errors.Is(err, keystore.ErrCAExists) {
+			// Another instance created it first.
+			ca, err = m.loadCA(ctx)
+		} else if err == nil {
+			ca, err = m.loadCA(ctx)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ca.leafValidity = cfg.LeafValidity
+	m.CA = ca
+	_, err = m.Certify(ctx)
+	return err
+}
+
+// Certify issues certificates for the published JWT signing keys that have
+// none, whose certificate is from another CA or is past half its validity,
+// and returns how many keys it certified. It does nothing without a CA.
+func (m *Manager) Certify(ctx context.Context) (int, error) {
+	if m.CA == nil {
+		return 0, nil
+	}
+	keys, err := m.publicJWKs(ctx, keystore.PurposeJWT)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	now := time.Now()
+	for _, jwk := range keys {
+		if !m.CA.needsCertificate(jwk, now) {
+			continue
+		}
+		pub, err := jwk.publicKey()
+		if err != nil {
+			return n, err
+		}
+		if err := m.CA.certify(&jwk, pub); err != nil {
+			return n, err
+		}
+		raw, err := json.Marshal(jwk)
+		if err != nil {
+			return n, err
+		}
+		err = m.Store.SetPublicJWK(ctx, jwk.Kid, raw)
+		if errors.Is(err, keystore.ErrNotFound) {
+			// Retired since it was listed.
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// CACertificate returns the internal CA certificate without unsealing its
+// private key.
+func (m *Manager) CACertificate(ctx context.Context) (*x509.Certificate, error) {
+	stored, err := m.Store.GetCA(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(stored.CertDER)
+}
+
+func (m *Manager) loadCA(ctx context.Context) (*CA, error) {
+	stored, err := m.Store.GetCA(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(stored.CertDER)
+	if err != nil {
+		return nil, err
+	}
+
+	pkcs8, err := m.open(ctx, keystore.Key{
+		KID:             caKID,
+		Alg:             "ES256",
+		PrivCiphertext:  stored.PrivCiphertext,
+		WrappedDEK:      stored.WrappedDEK,
+		KEKRef:          stored.KEKRef,
+		EnvelopeVersion: stored.EnvelopeVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(pkcs8)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	return &CA{Cert: cert, priv: signer}, nil
+}
+
+func (m *Manager) createCA(ctx context.Context, cfg CAConfig) error {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cfg.CommonName, Organization: []string{m.Issuer}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(cfg.Validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, priv.Public(), priv)
+	if err != nil {
+		return err
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	sealed, err := m.sealPKCS8(ctx, caKID, "ES256", pkcs8)
+	if err != nil {
+		return err
+	}
+
+	return m.Store.CreateCA(ctx, keystore.CA{
+		CertDER:         der,
+		PrivCiphertext:  sealed.PrivCiphertext,
+		WrappedDEK:      sealed.WrappedDEK,
+		KEKRef:          sealed.KEKRef,
+		EnvelopeVersion: sealed.EnvelopeVersion,
+	})
+}
+
+// Issue certifies pub as the signing key kid and returns the leaf
+// certificate in DER form.
+func (ca *CA) Issue(kid string, pub crypto.PublicKey) ([]byte, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(ca.leafValidity)
+	if ca.leafValidity <= 0 || notAfter.After(ca.Cert.NotAfter) {
+		notAfter = ca.Cert.NotAfter
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: kid, Organization: ca.Cert.Subject.Organization},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	return x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, pub, ca.priv)
+}
+
+// certify adds the x5c chain and x5t#S256 thumbprint for kid to jwk.
+func (ca *CA) certify(jwk *publicJWK, pub crypto.PublicKey) error {
+	leaf, err := ca.Issue(jwk.Kid, pub)
+	if err != nil {
+		return err
+	}
+
+	thumb := sha256.Sum256(leaf)
+	jwk.X5C = []string{
+		base64.StdEncoding.EncodeToString(leaf),
+		base64.StdEncoding.EncodeToString(ca.Cert.Raw),
+	}
+	jwk.X5TS256 = b64u(thumb[:])
+	return nil
+}
+
+// needsCertificate reports whether jwk has no certificate from ca valid
+// for at least another half of its validity at now.
+func (ca *CA) needsCertificate(jwk publicJWK, now time.Time) bool {
+	if len(jwk.X5C) != 2 {
+		return true
+	}
+	caDER, err := base64.StdEncoding.DecodeString(jwk.X5C[1])
+	if err != nil || !bytes.Equal(caDER, ca.Cert.Raw) {
+		return true
+	}
+	leafDER, err := base64.StdEncoding.DecodeString(jwk.X5C[0])
+	if err != nil {
+		return true
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		return true
+	}
+	renewAt := leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) / 2)
+	return !now.Before(renewAt)
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
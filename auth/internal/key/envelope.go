@@ -0,0 +1,86 @@
+package key
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
+)
+
+// Envelope versions stored in jwk_keys.envelope_version.
+//
+// Version 0 rows hold the raw AES-256-GCM output in priv_ciphertext and
+// priv_nonce with aad="PRIV:"+kid. Version 1 rows hold an envelope.Seal
+// document in priv_ciphertext, with the AAD binding kid, alg and kek_ref.
+// Version 2 rows hold an envelope.Version2 document, which also
+// authenticates its own header.
+const (
+	EnvelopeV0 = 0
+	EnvelopeV1 = 1
+	EnvelopeV2 = 2
+
+	CurrentEnvelopeVersion = EnvelopeV2
+)
+
+// envelopeAAD is the associated data bound to a version 1 or 2 envelope, so a
+// ciphertext cannot be moved to another row, alg or KEK without failing
+// authentication.
+type envelopeAAD struct {
+	Version int    `json:"v"`
+	Suite   string `json:"suite"`
+	KID     string `json:"kid"`
+	Alg     string `json:"alg"`
+	KEKRef  string `json:"kek_ref"`
+}
+
+func sealEnvelope(suite string, dek, plaintext []byte, kid, alg, kekRef string) ([]byte, error) {
+	aad, err := json.Marshal(envelopeAAD{
+		Version: envelope.CurrentVersion,
+		Suite:   suite,
+		KID:     kid,
+		Alg:     alg,
+		KEKRef:  kekRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return envelope.Seal(suite, dek, plaintext, aad)
+}
+
+func openEnvelope(dek, sealed []byte, kid, alg, kekRef string) ([]byte, error) {
+	h, err := envelope.ParseHeader(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	aad, err := json.Marshal(envelopeAAD{
+		Version: h.Version,
+		Suite:   h.Suite,
+		KID:     kid,
+		Alg:     alg,
+		KEKRef:  kekRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return envelope.Open(dek, sealed, aad)
+}
+
+// openPrivateKey decrypts a stored private key of any supported envelope
+// version into its PKCS#8 form.
+func openPrivateKey(version int16, dek, ct, nonce []byte, kid, alg, kekRef string) ([]byte, error) {
+	switch version {
+	case EnvelopeV0:
+		aead, err := envelope.NewAEAD(envelope.SuiteA256GCM, dek)
+		if err != nil {
+			return nil, err
+		}
+		return aead.Open(nil, nonce, ct, []byte("PRIV:"+kid))
+	case EnvelopeV1, EnvelopeV2:
+		return openEnvelope(dek, ct, kid, alg, kekRef)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownEnvelope, version)
+	}
+}
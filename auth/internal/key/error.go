@@ -1,7 +1,26 @@
 package key
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+)
 
 var (
-	ErrNotECDSAKey = errors.New("not an ECDSA key")
+	ErrInvalidKey     = errors.New("invalid key")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	// Deprecated: keys of any supported type are accepted now; match
+	// ErrUnsupportedKey instead.
+	ErrNotECDSAKey   = ErrUnsupportedKey
+	ErrWeakKey       = errors.New("key size too small")
+	ErrAlgMismatch   = errors.New("alg does not match key")
+	ErrKeyMismatch   = errors.New("public key does not match private key")
+	ErrNoPrivateKey  = errors.New("no private key found")
+	ErrUnknownFormat = errors.New("unknown key format")
+	ErrInvalidStatus = errors.New("invalid key status")
+
+	ErrUnsupportedEncryptionKey = errors.New("unsupported encryption key")
+	ErrKIDExists                = keystore.ErrKIDExists
+
+	ErrUnknownEnvelope = errors.New("unknown envelope version")
 )
@@ -0,0 +1,311 @@
+package key
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type ImportFormat string
+
+const (
+	FormatPEM    ImportFormat = "pem"
+	FormatJWK    ImportFormat = "jwk"
+	FormatPKCS12 ImportFormat = "pkcs12"
+)
+
+const minRSABits = 2048
+
+// ImportParams describes an externally generated private key to be stored
+// in jwk_keys. KID and Alg are optional: a JWK's own kid/alg are used when
+// present, otherwise a kid is generated and the alg is derived from the key.
+type ImportParams struct {
+	KID      string
+	Alg      string
+	Status   string
+	Format   ImportFormat
+	Data     []byte
+	Password string
+}
+
+// Import validates an external private key, envelope-encrypts it with the
+// configured KeyWrapper and stores it. Importing a key as ACTIVE demotes the
+// current ACTIVE key exactly like Rotate does.
+func (m *Manager) Import(ctx context.Context, p ImportParams) (kid, alg string, err error) {
+	status := p.Status
+	if status == "" {
+		status = StatusActive
+	}
+	if status != StatusActive && status != StatusRetiring && status != StatusRetired {
+		return "", "", ErrInvalidStatus
+	}
+
+	priv, hint, err := ParsePrivateKey(p.Format, p.Data, p.Password)
+	if err != nil {
+		return "", "", err
+	}
+
+	kid = p.KID
+	if kid == "" {
+		kid = hint.KID
+	}
+	if kid == "" {
+		kid = genKID()
+	}
+
+	alg = p.Alg
+	if alg == "" {
+		alg = hint.Alg
+	}
+	alg, err = algForKey(priv, alg)
+	if err != nil {
+		return "", "", err
+	}
+
+	k, err := m.seal(ctx, kid, alg, priv)
+	if err != nil {
+		return "", "", err
+	}
+
+	if status == StatusActive {
+		err = m.Store.Rotate(ctx, k)
+	} else {
+		k.Status = status
+		err = m.Store.Create(ctx, k)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return kid, alg, nil
+}
+
+// KeyHint carries metadata found alongside a parsed key, such as the kid and
+// alg members of a JWK.
+type KeyHint struct {
+	KID string
+	Alg string
+}
+
+// ParsePrivateKey decodes a private key in PEM (PKCS#8, SEC 1 or PKCS#1), JWK
+// or PKCS#12 form and checks that it is usable for signing.
+func ParsePrivateKey(format ImportFormat, data []byte, password string) (crypto.Signer, KeyHint, error) {
+	var (
+		key  any
+		hint KeyHint
+		err  error
+	)
+
+	switch format {
+	case FormatPEM:
+		key, err = parsePEM(data)
+	case FormatJWK:
+		key, hint, err = parsePrivateJWK(data)
+	case FormatPKCS12:
+		key, _, _, err = pkcs12.DecodeChain(data, password)
+	default:
+		return nil, KeyHint{}, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, KeyHint{}, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		if _, err := ecAlg(k.Curve); err != nil {
+			return nil, KeyHint{}, err
+		}
+		return k, hint, nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, KeyHint{}, ErrWeakKey
+		}
+		if err := k.Validate(); err != nil {
+			return nil, KeyHint{}, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+		}
+		return k, hint, nil
+	default:
+		return nil, KeyHint{}, ErrUnsupportedKey
+	}
+}
+
+func parsePEM(data []byte) (any, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, ErrNoPrivateKey
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, errors.New("encrypted PEM keys are not supported, decrypt with openssl pkcs8 first")
+		}
+	}
+}
+
+type privateJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+}
+
+func parsePrivateJWK(data []byte) (any, KeyHint, error) {
+	var j privateJWK
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, KeyHint{}, err
+	}
+	if j.D == "" {
+		return nil, KeyHint{}, ErrNoPrivateKey
+	}
+	hint := KeyHint{KID: j.Kid, Alg: j.Alg}
+
+	switch j.Kty {
+	case "EC":
+		key, err := ecKeyFromJWK(j)
+		return key, hint, err
+	case "RSA":
+		key, err := rsaKeyFromJWK(j)
+		return key, hint, err
+	default:
+		return nil, KeyHint{}, ErrUnsupportedKey
+	}
+}
+
+func ecKeyFromJWK(j privateJWK) (*ecdsa.PrivateKey, error) {
+	var curve elliptic.Curve
+	switch j.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	d, err := base64.RawURLEncoding.DecodeString(j.D)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := ecdsa.ParseRawPrivateKey(curve, d)
+	if err != nil {
+		return nil, err
+	}
+
+	x, err := decodeBigInt(j.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(j.Y)
+	if err != nil {
+		return nil, err
+	}
+	if priv.X.Cmp(x) != 0 || priv.Y.Cmp(y) != 0 {
+		return nil, ErrKeyMismatch
+	}
+	return priv, nil
+}
+
+func rsaKeyFromJWK(j privateJWK) (*rsa.PrivateKey, error) {
+	if j.P == "" || j.Q == "" {
+		return nil, errors.New("rsa jwk must include the p and q primes")
+	}
+
+	var ints [5]*big.Int
+	for i, v := range []string{j.N, j.E, j.D, j.P, j.Q} {
+		n, err := decodeBigInt(v)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = n
+	}
+	if !ints[1].IsInt64() {
+		return nil, ErrUnsupportedKey
+	}
+
+	priv := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: ints[0], E: int(ints[1].Int64())},
+		D:         ints[2],
+		Primes:    []*big.Int{ints[3], ints[4]},
+	}
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+	priv.Precompute()
+	return priv, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// algForKey returns the JWS alg to sign with, checking a requested alg
+// against the key type or deriving the default one when none is given.
+func algForKey(priv crypto.Signer, requested string) (string, error) {
+	switch k := priv.(type) {
+	case *ecdsa.PrivateKey:
+		alg, err := ecAlg(k.Curve)
+		if err != nil {
+			return "", err
+		}
+		if requested != "" && requested != alg {
+			return "", ErrAlgMismatch
+		}
+		return alg, nil
+	case *rsa.PrivateKey:
+		switch requested {
+		case "":
+			return "RS256", nil
+		case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+			return requested, nil
+		default:
+			return "", ErrAlgMismatch
+		}
+	default:
+		return "", ErrUnsupportedKey
+	}
+}
+
+func ecAlg(curve elliptic.Curve) (string, error) {
+	switch curve {
+	case elliptic.P256():
+		return "ES256", nil
+	case elliptic.P384():
+		return "ES384", nil
+	case elliptic.P521():
+		return "ES512", nil
+	default:
+		return "", ErrUnsupportedKey
+	}
+}
@@ -2,45 +2,196 @@ package key
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"crypto"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
 )
 
+// TokenTypeAccessToken is the typ header of JWT access tokens (RFC 9068
+// section 2.1).
+const TokenTypeAccessToken = "at+jwt"
+
 type Signer struct {
 	KID  string
-	Priv *ecdsa.PrivateKey
+	Alg  string
+	Priv crypto.Signer
 	Iss  string
 	Aud  string
 	TTL  time.Duration
 }
 
+// NewSigner returns a Signer issuing JWTs with the active JWT signing key.
 func NewSigner(ctx context.Context, m *Manager, aud, iss string, ttl time.Duration) (*Signer, error) {
-	kid, priv, err := m.LoadActiveSigner(ctx)
+	kid, alg, priv, err := m.LoadActiveSigner(ctx, keystore.PurposeJWT)
 	if err != nil {
 		return nil, err
 	}
-	return &Signer{KID: kid, Priv: priv, Iss: iss, Aud: aud, TTL: ttl}, nil
+	return &Signer{KID: kid, Alg: alg, Priv: priv, Iss: iss, Aud: aud, TTL: ttl}, nil
+}
+
+// Confirmation is the cnf claim (RFC 7800) binding a token to a key held by
+// the client.
+type Confirmation struct {
+	// JKT is the JWK SHA-256 thumbprint of the client's DPoP key (RFC 9449).
+	JKT string `json:"jkt,omitempty"`
+	// X5TS256 is the SHA-256 thumbprint of the client's mutual-TLS
+	// certificate (RFC 8705).
+	X5TS256 string `json:"x5t#S256,omitempty"`
 }
 
+// Actor is the act claim (RFC 8693 section 4.1) naming the party acting on
+// behalf of the subject. Act holds the prior links of a delegation chain.
+type Actor struct {
+	Sub string `json:"sub"`
+	Act *Actor `json:"act,omitempty"`
+}
+
+// CustomClaims are the claims of an access token. Scope, ClientID, AuthTime
+// and ACR follow RFC 9068 section 2.2; Scopes carries the same scopes as an
+// array for existing consumers.
 type CustomClaims struct {
-	Scopes      []string `json:"scopes"`
-	Permissions []string `json:"permissions,omitempty"`
+	Scopes      []string         `json:"scopes"`
+	Scope       string           `json:"scope,omitempty"`
+	ClientID    string           `json:"client_id,omitempty"`
+	AuthTime    *jwt.NumericDate `json:"auth_time,omitempty"`
+	ACR         string           `json:"acr,omitempty"`
+	AMR         []string         `json:"amr,omitempty"`
+	SID         string           `json:"sid,omitempty"`
+	Permissions []string         `json:"permissions,omitempty"`
+	Cnf         *Confirmation    `json:"cnf,omitempty"`
+	Act         *Actor           `json:"act,omitempty"`
 	jwt.RegisteredClaims
+
+	// Extra are custom claims added next to the others. A name already
+	// used by a claim above is left out.
+	Extra map[string]any `json:"-"`
+}
+
+// MarshalJSON adds Extra to the claims.
+func (c CustomClaims) MarshalJSON() ([]byte, error) {
+	type claims CustomClaims
+	raw, err := json.Marshal(claims(c))
+	if err != nil || len(c.Extra) == 0 {
+		return raw, err
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	for name, v := range c.Extra {
+		if _, ok := m[name]; ok {
+			continue
+		}
+		if m[name], err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(m)
+}
+
+type signOptions struct {
+	cnf      *Confirmation
+	act      *Actor
+	aud      []string
+	clientID string
+	authTime time.Time
+	acr      string
+	amr      []string
+	sid      string
+	perms    []string
+	extra    map[string]any
+}
+
+type SignOption func(*signOptions)
+
+// WithConfirmation binds the token to the key described by cnf.
+func WithConfirmation(cnf *Confirmation) SignOption {
+	return func(o *signOptions) { o.cnf = cnf }
+}
+
+// WithActor records a delegation in the act claim.
+func WithActor(act *Actor) SignOption {
+	return func(o *signOptions) { o.act = act }
+}
+
+// WithAudience replaces the signer's audience.
+func WithAudience(aud ...string) SignOption {
+	return func(o *signOptions) { o.aud = aud }
+}
+
+// WithClientID names the client the token was issued to.
+func WithClientID(clientID string) SignOption {
+	return func(o *signOptions) { o.clientID = clientID }
+}
+
+// WithAuthentication records when and how the subject last authenticated.
+// Tokens without a user authentication, such as client_credentials, leave
+// both claims out.
+func WithAuthentication(authTime time.Time, acr string) SignOption {
+	return func(o *signOptions) {
+		o.authTime = authTime
+		o.acr = acr
+	}
+}
+
+// WithAMR records the authentication methods (RFC 8176) of the subject's
+// last authentication.
+func WithAMR(amr []string) SignOption {
+	return func(o *signOptions) { o.amr = amr }
+}
+
+// WithSessionID sets the sid claim naming the browser session the token
+// was issued in.
+func WithSessionID(sid string) SignOption {
+	return func(o *signOptions) { o.sid = sid }
+}
+
+// WithPermissions sets the permissions claim.
+func WithPermissions(perms []string) SignOption {
+	return func(o *signOptions) { o.perms = perms }
+}
+
+// WithClaims adds custom claims to the token. Names of the standard claims
+// are ignored.
+func WithClaims(extra map[string]any) SignOption {
+	return func(o *signOptions) { o.extra = extra }
 }
 
-func (s *Signer) Sign(sub string, scopes []string) (string, error) {
+func (s *Signer) Sign(sub string, scopes []string, opts ...SignOption) (string, error) {
+	return s.SignClaims(s.Claims(sub, scopes, opts...))
+}
+
+// Claims returns the claims Sign would sign, for callers that need to see
+// or extend them first.
+func (s *Signer) Claims(sub string, scopes []string, opts ...SignOption) CustomClaims {
 	now := time.Now()
 
-	// todo: get permissions from polices service
+	o := signOptions{aud: []string{s.Aud}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	rc := CustomClaims{
-		Scopes: scopes,
+		Scopes:      scopes,
+		Scope:       strings.Join(scopes, " "),
+		ClientID:    o.clientID,
+		ACR:         o.acr,
+		AMR:         o.amr,
+		SID:         o.sid,
+		Permissions: o.perms,
+		Cnf:         o.cnf,
+		Act:         o.act,
+		Extra:       o.extra,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    s.Iss,
-			Audience:  jwt.ClaimStrings{s.Aud},
+			Audience:  jwt.ClaimStrings(o.aud),
 			Subject:   sub,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.TTL)),
@@ -48,8 +199,26 @@ func (s *Signer) Sign(sub string, scopes []string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, rc)
+	if !o.authTime.IsZero() {
+		rc.AuthTime = jwt.NewNumericDate(o.authTime)
+	}
+	return rc
+}
+
+// SignClaims signs rc with the signer's key.
+func (s *Signer) SignClaims(rc CustomClaims) (string, error) {
+	if s.Alg == AlgPASETOV4Public {
+		return s.signPASETO(rc)
+	}
+
+	method := jwt.GetSigningMethod(s.Alg)
+	if method == nil {
+		return "", ErrUnsupportedKey
+	}
+
+	token := jwt.NewWithClaims(method, rc)
 	token.Header["kid"] = s.KID
+	token.Header["typ"] = TokenTypeAccessToken
 
 	return token.SignedString(s.Priv)
 }
@@ -0,0 +1,70 @@
+package key
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// Key management algorithms a resource server may register for encrypted
+// tokens. Content is always encrypted with A256GCM.
+const (
+	EncAlgECDHESA256KW = "ECDH-ES+A256KW"
+	EncAlgRSAOAEP256   = "RSA-OAEP-256"
+)
+
+// EncryptionKey is the public key a resource server registered for
+// encrypted tokens.
+type EncryptionKey struct {
+	KID string
+	Alg string
+	Key crypto.PublicKey
+}
+
+// EncryptionAlgFor returns the key management algorithm for pub, checking
+// it against alg when alg is set.
+func EncryptionAlgFor(pub crypto.PublicKey, alg string) (string, error) {
+	var want string
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return "", ErrUnsupportedEncryptionKey
+		}
+		want = EncAlgECDHESA256KW
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return "", ErrWeakKey
+		}
+		want = EncAlgRSAOAEP256
+	default:
+		return "", ErrUnsupportedEncryptionKey
+	}
+	if alg != "" && alg != want {
+		return "", ErrAlgMismatch
+	}
+	return want, nil
+}
+
+// Encrypt wraps a signed token in a JWE for the holder of ek, producing a
+// nested JWT (RFC 7519 section 5.2).
+func Encrypt(signed string, ek EncryptionKey) (string, error) {
+	enc, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{
+		Algorithm: jose.KeyAlgorithm(ek.Alg),
+		Key:       ek.Key,
+		KeyID:     ek.KID,
+	}, (&jose.EncrypterOptions{}).WithContentType("JWT"))
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := enc.Encrypt([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+	return obj.CompactSerialize()
+}
@@ -0,0 +1,49 @@
+package key
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenTypeLogout is the typ header of logout tokens (OpenID Connect
+// Back-Channel Logout 1.0 section 2.4).
+const TokenTypeLogout = "logout+jwt"
+
+// EventBackchannelLogout is the only member of the events claim of logout
+// tokens.
+const EventBackchannelLogout = "http://schemas.openid.net/event/backchannel-logout"
+
+// LogoutClaims are the claims of a logout token.
+type LogoutClaims struct {
+	SID    string                    `json:"sid,omitempty"`
+	Events map[string]map[string]any `json:"events"`
+	jwt.RegisteredClaims
+}
+
+// SignLogout signs a logout token telling the signer's audience, a client,
+// that the session sid of sub ended. Logout tokens are always JWTs.
+func (s *Signer) SignLogout(sub, sid string) (string, error) {
+	method := jwt.GetSigningMethod(s.Alg)
+	if method == nil || s.Alg == AlgPASETOV4Public {
+		return "", ErrUnsupportedKey
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(method, LogoutClaims{
+		SID:    sid,
+		Events: map[string]map[string]any{EventBackchannelLogout: {}},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.Iss,
+			Audience:  jwt.ClaimStrings{s.Aud},
+			Subject:   sub,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.TTL)),
+			ID:        genJTI(),
+		},
+	})
+	token.Header["kid"] = s.KID
+	token.Header["typ"] = TokenTypeLogout
+
+	return token.SignedString(s.Priv)
+}
@@ -2,238 +2,419 @@ package key
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
-	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
 
-	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
 )
 
-type ecPublicJWK struct {
+type publicJWK struct {
 	Kty string `json:"kty"`
 	Use string `json:"use"`
-	Crv string `json:"crv"`
+	Crv string `json:"crv,omitempty"`
 	Alg string `json:"alg"`
 	Kid string `json:"kid"`
-	X   string `json:"x"`
-	Y   string `json:"y"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+
+	X5C     []string `json:"x5c,omitempty"`
+	X5TS256 string   `json:"x5t#S256,omitempty"`
 }
 
 type jwks struct {
-	Keys []ecPublicJWK `json:"keys"`
+	Keys []publicJWK `json:"keys"`
 }
 
-type KeyWrapper interface {
-	Wrap(ctx context.Context, dek []byte) (wrapped []byte, kekRef string, err error)
-	Unwrap(ctx context.Context, wrapped []byte, kekRef string) (dek []byte, err error)
-}
+const (
+	StatusActive   = keystore.StatusActive
+	StatusRetiring = keystore.StatusRetiring
+	StatusRetired  = keystore.StatusRetired
+)
 
 type Manager struct {
-	DB      *sql.DB
-	queries *db.Queries
-	Wrapper KeyWrapper
+	Store   keystore.Store
+	Wrapper envelope.KeyWrapper
 	Issuer  string
+	// Suite is the cipher suite used to seal newly stored private keys.
+	Suite string
+	// CA certifies the published keys when set, see InitCA.
+	CA *CA
 }
 
-func NewManager(db *sql.DB, q *db.Queries, wrapper KeyWrapper, iss string) *Manager {
+func NewManager(store keystore.Store, wrapper envelope.KeyWrapper, iss string) *Manager {
 	return &Manager{
-		DB:      db,
-		queries: q,
+		Store:   store,
 		Wrapper: wrapper,
 		Issuer:  iss,
+		Suite:   envelope.DefaultSuite,
 	}
 }
 
-func (m *Manager) Init(ctx context.Context) error {
-	n, err := m.queries.CountJWK(ctx)
-	if err != nil {
-		return err
+// ForTenant returns a manager of the keys of tenant, issuing tokens as iss.
+// It shares the wrapper, suite and CA of m.
+func (m *Manager) ForTenant(tenant, iss string) *Manager {
+	return &Manager{
+		Store:   m.Store.Tenant(tenant),
+		Wrapper: m.Wrapper,
+		Issuer:  iss,
+		Suite:   m.Suite,
+		CA:      m.CA,
 	}
+}
 
-	if n == 0 {
-		_, err := m.GenerateAndStore(context.Background())
+// Init creates a JWT signing key and a PASETO signing key when there is
+// none yet, and certifies the published keys when there is a CA.
+func (m *Manager) Init(ctx context.Context) error {
+	for _, purpose := range []string{keystore.PurposeJWT, keystore.PurposePASETO} {
+		keys, err := m.Store.ListPublic(ctx, purpose)
 		if err != nil {
 			return err
 		}
+
+		if len(keys) == 0 {
+			if _, err := m.generateAndStore(ctx, purpose); err != nil {
+				return err
+			}
+		}
 	}
 
-	return nil
+	_, err := m.Certify(ctx)
+	return err
 }
 
 func (m *Manager) GenerateAndStore(ctx context.Context) (string, error) {
-	kid := genKID()
+	return m.generateAndStore(ctx, keystore.PurposeJWT)
+}
 
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+func (m *Manager) generateAndStore(ctx context.Context, purpose string) (string, error) {
+	k, err := m.generate(ctx, purpose)
 	if err != nil {
 		return "", err
 	}
-	pub := priv.Public().(*ecdsa.PublicKey)
+	k.Status = StatusActive
 
-	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
-	if err != nil {
+	if err := m.Store.Create(ctx, k); err != nil {
 		return "", err
 	}
 
-	dek := make([]byte, 32)
-	if _, err := rand.Read(dek); err != nil {
-		return "", err
+	return k.KID, nil
+}
+
+// generate creates and seals a new key for purpose: ES256 for JWTs, Ed25519
+// for PASETO v4.public, identified by its PASERK ID.
+func (m *Manager) generate(ctx context.Context, purpose string) (keystore.Key, error) {
+	if purpose == keystore.PurposePASETO {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return keystore.Key{}, err
+		}
+		return m.seal(ctx, PASERKID(pub), AlgPASETOV4Public, priv)
 	}
 
-	privNonce, privCT, err := aesGCMEncrypt(dek, pkcs8, []byte("PRIV:"+kid))
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
-		return "", err
+		return keystore.Key{}, err
 	}
+	return m.seal(ctx, genKID(), "ES256", priv)
+}
 
-	wrapped, kekRef, err := m.Wrapper.Wrap(ctx, dek)
+// LoadActiveSigner returns the private key to sign with for purpose.
+func (m *Manager) LoadActiveSigner(ctx context.Context, purpose string) (kid, alg string, priv crypto.Signer, err error) {
+	k, err := m.Store.GetActive(ctx, purpose)
 	if err != nil {
-		return "", err
+		return "", "", nil, err
 	}
 
-	pubJWK, err := buildECPublicJWK(kid, pub)
+	pkcs8, err := m.open(ctx, k)
 	if err != nil {
-		return "", err
+		return "", "", nil, err
 	}
-	pubJWKJSON, _ := json.Marshal(pubJWK)
-
-	m.queries.CreateJWK(ctx, db.CreateJWKParams{
-		KID:            kid,
-		ALG:            "ES256",
-		PublicJWK:      pubJWKJSON,
-		PrivCiphertext: privCT,
-		PrivNonce:      privNonce,
-		WrappedDEK:     wrapped,
-		KEKRef: sql.NullString{
-			String: kekRef,
-			Valid:  kekRef != "",
-		},
-	})
+	key, err := x509.ParsePKCS8PrivateKey(pkcs8)
+	if err != nil {
+		return "", "", nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", "", nil, ErrUnsupportedKey
+	}
+	return k.KID, k.Alg, signer, nil
+}
 
-	return kid, err
+func (m *Manager) Rotate(ctx context.Context) (string, error) {
+	return m.rotate(ctx, keystore.PurposeJWT)
 }
 
-func (m *Manager) LoadActiveSigner(ctx context.Context) (kid string, priv *ecdsa.PrivateKey, err error) {
-	r, err := m.queries.GetJWK(ctx)
-	if err != nil {
-		return "", nil, err
+// RotatePASETO rotates the PASETO signing keys the same way Rotate does the
+// JWT ones.
+func (m *Manager) RotatePASETO(ctx context.Context) (string, error) {
+	return m.rotate(ctx, keystore.PurposePASETO)
+}
+
+// RotateDue rotates the JWT and PASETO signing keys whose current key is at
+// least period old, or both when period is zero, and returns the new kids.
+// It renews the certificates of the published keys too, see Certify.
+func (m *Manager) RotateDue(ctx context.Context, period time.Duration) ([]string, error) {
+	var kids []string
+	for _, purpose := range []string{keystore.PurposeJWT, keystore.PurposePASETO} {
+		if period > 0 {
+			k, err := m.Store.GetActive(ctx, purpose)
+			if err != nil && !errors.Is(err, keystore.ErrNotFound) {
+				return kids, err
+			}
+			if err == nil && time.Since(k.CreatedAt) < period {
+				continue
+			}
+		}
+
+		kid, err := m.rotate(ctx, purpose)
+		if err != nil {
+			return kids, err
+		}
+		kids = append(kids, kid)
 	}
+	_, err := m.Certify(ctx)
+	return kids, err
+}
 
-	dek, err := m.Wrapper.Unwrap(ctx, r.WrappedDEK, r.KEKRef.String)
+func (m *Manager) rotate(ctx context.Context, purpose string) (string, error) {
+	k, err := m.generate(ctx, purpose)
 	if err != nil {
-		return "", nil, err
+		return "", err
+	}
+
+	if err := m.Store.Rotate(ctx, k); err != nil {
+		return "", err
 	}
+	return k.KID, nil
+}
+
+// Retire stops publishing and signing with kid, e.g. after a compromise.
+func (m *Manager) Retire(ctx context.Context, kid string) error {
+	return m.Store.Retire(ctx, kid)
+}
 
-	pkcs8, err := aesGCMDecrypt(dek, r.PrivNonce, r.PrivCiphertext, []byte("PRIV:"+r.KID))
+func (m *Manager) JWKS(ctx context.Context) (jwks, error) {
+	keys, err := m.publicJWKs(ctx, keystore.PurposeJWT)
 	if err != nil {
-		return "", nil, err
+		return jwks{}, err
 	}
-	key, err := x509.ParsePKCS8PrivateKey(pkcs8)
+
+	return jwks{Keys: keys}, nil
+}
+
+func (m *Manager) publicJWKs(ctx context.Context, purpose string) ([]publicJWK, error) {
+	rawPub, err := m.Store.ListPublic(ctx, purpose)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
-	if !ok {
-		return "", nil, ErrNotECDSAKey
+
+	keys := make([]publicJWK, len(rawPub))
+	for i, p := range rawPub {
+		if err := json.Unmarshal(p.PublicJWK, &keys[i]); err != nil {
+			return nil, err
+		}
 	}
-	return r.KID, ecdsaKey, nil
+	return keys, nil
 }
 
-func (m *Manager) Rotate(ctx context.Context) (string, error) {
-	newKID := genKID()
-
-	tx, err := m.DB.BeginTx(ctx, nil)
+// seal encrypts priv under a fresh DEK, with the DEK wrapped by the
+// manager's KeyWrapper, and returns it ready to be stored.
+func (m *Manager) seal(ctx context.Context, kid, alg string, priv crypto.Signer) (keystore.Key, error) {
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
 	if err != nil {
-		return "", err
+		return keystore.Key{}, err
 	}
-	defer func() { _ = tx.Rollback() }()
 
-	qtx := m.queries.WithTx(tx)
-	err = qtx.UpdateJWKToRetired(ctx)
-	err = qtx.UpdateJWKToRetiring(ctx)
+	k, err := m.sealPKCS8(ctx, kid, alg, pkcs8)
+	if err != nil {
+		return keystore.Key{}, err
+	}
+	k.Purpose = keystore.PurposeJWT
+	if alg == AlgPASETOV4Public {
+		k.Purpose = keystore.PurposePASETO
+	}
 
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pubJWK, err := buildPublicJWK(kid, alg, priv.Public())
 	if err != nil {
-		return "", err
+		return keystore.Key{}, err
 	}
-	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
+	// PASETO keys are published as PASERKs, which have no place for a
+	// certificate chain.
+	if m.CA != nil && k.Purpose == keystore.PurposeJWT {
+		if err := m.CA.certify(&pubJWK, priv.Public()); err != nil {
+			return keystore.Key{}, err
+		}
+	}
+	k.PublicJWK, err = json.Marshal(pubJWK)
 	if err != nil {
-		return "", err
+		return keystore.Key{}, err
 	}
 
-	dek := make([]byte, 32)
-	_, _ = rand.Read(dek)
-	privNonce, privCT, err := aesGCMEncrypt(dek, pkcs8, []byte("PRIV:"+newKID))
+	return k, nil
+}
+
+func (m *Manager) sealPKCS8(ctx context.Context, kid, alg string, pkcs8 []byte) (keystore.Key, error) {
+	dek, err := envelope.NewDEK()
 	if err != nil {
-		return "", err
+		return keystore.Key{}, err
 	}
 
 	wrapped, kekRef, err := m.Wrapper.Wrap(ctx, dek)
 	if err != nil {
-		return "", err
+		return keystore.Key{}, err
 	}
 
-	pubJWK, _ := buildECPublicJWK(newKID, &priv.PublicKey)
-	pubJWKJSON, _ := json.Marshal(pubJWK)
-
-	err = qtx.CreateJWK(ctx, db.CreateJWKParams{
-		KID:            newKID,
-		ALG:            "ES256",
-		PublicJWK:      pubJWKJSON,
-		PrivCiphertext: privCT,
-		PrivNonce:      privNonce,
-		WrappedDEK:     wrapped,
-		KEKRef: sql.NullString{
-			String: kekRef,
-			Valid:  kekRef != "",
-		},
-	})
+	privCT, err := sealEnvelope(m.Suite, dek, pkcs8, kid, alg, kekRef)
 	if err != nil {
-		return "", err
+		return keystore.Key{}, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return "", err
-	}
-	return newKID, nil
+	return keystore.Key{
+		KID:             kid,
+		Alg:             alg,
+		PrivCiphertext:  privCT,
+		WrappedDEK:      wrapped,
+		KEKRef:          kekRef,
+		EnvelopeVersion: CurrentEnvelopeVersion,
+	}, nil
 }
 
-func (m *Manager) JWKS(ctx context.Context) (jwks, error) {
-	rawPub, err := m.queries.GetPubJWK(ctx)
+// open returns the PKCS#8 private key of a stored key.
+func (m *Manager) open(ctx context.Context, k keystore.Key) ([]byte, error) {
+	dek, err := m.Wrapper.Unwrap(ctx, k.WrappedDEK, k.KEKRef)
 	if err != nil {
-		return jwks{}, err
+		return nil, err
 	}
+	return openPrivateKey(k.EnvelopeVersion, dek, k.PrivCiphertext, k.PrivNonce, k.KID, k.Alg, k.KEKRef)
+}
 
-	keys := make([]ecPublicJWK, len(rawPub))
-	for i, p := range rawPub {
-		if err := json.Unmarshal(p.PublicJWK, &keys[i]); err != nil {
-			return jwks{}, err
+// UpgradeEnvelopes re-seals every private key stored with an older envelope
+// version under a fresh DEK and the current envelope version. Rows it cannot
+// decrypt abort the whole upgrade.
+func (m *Manager) UpgradeEnvelopes(ctx context.Context) (int, error) {
+	return m.Store.Reseal(ctx, CurrentEnvelopeVersion, func(k keystore.Key) (keystore.Key, error) {
+		pkcs8, err := m.open(ctx, k)
+		if err != nil {
+			return keystore.Key{}, err
 		}
+		return m.sealPKCS8(ctx, k.KID, k.Alg, pkcs8)
+	})
+}
+
+func buildPublicJWK(kid, alg string, pub crypto.PublicKey) (publicJWK, error) {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		return buildECPublicJWK(kid, alg, pub)
+	case *rsa.PublicKey:
+		return buildRSAPublicJWK(kid, alg, pub), nil
+	case ed25519.PublicKey:
+		return publicJWK{
+			Kty: "OKP",
+			Use: "sig",
+			Crv: "Ed25519",
+			Alg: alg,
+			Kid: kid,
+			X:   b64u(pub),
+		}, nil
+	default:
+		return publicJWK{}, ErrUnsupportedKey
 	}
+}
 
-	return jwks{Keys: keys}, nil
+// publicKey returns the public key jwk describes.
+func (j publicJWK) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		point := slices.Concat([]byte{4}, leftPad(x.Bytes(), size), leftPad(y.Bytes(), size))
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, ErrUnsupportedKey
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKey
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
 }
 
-func buildECPublicJWK(kid string, pub *ecdsa.PublicKey) (ecPublicJWK, error) {
-	x := pub.X.Bytes()
-	y := pub.Y.Bytes()
-	xb := leftPad(x, 32)
-	yb := leftPad(y, 32)
-	return ecPublicJWK{
+func buildECPublicJWK(kid, alg string, pub *ecdsa.PublicKey) (publicJWK, error) {
+	params := pub.Curve.Params()
+	size := (params.BitSize + 7) / 8
+	xb := leftPad(pub.X.Bytes(), size)
+	yb := leftPad(pub.Y.Bytes(), size)
+	return publicJWK{
 		Kty: "EC",
 		Use: "sig",
-		Crv: "P-256",
-		Alg: "ES256",
+		Crv: params.Name,
+		Alg: alg,
 		Kid: kid,
 		X:   b64u(xb),
 		Y:   b64u(yb),
 	}, nil
 }
 
+func buildRSAPublicJWK(kid, alg string, pub *rsa.PublicKey) publicJWK {
+	return publicJWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: alg,
+		Kid: kid,
+		N:   b64u(pub.N.Bytes()),
+		E:   b64u(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
 func leftPad(b []byte, n int) []byte {
 	if len(b) >= n {
 		return b
@@ -245,35 +426,6 @@ func leftPad(b []byte, n int) []byte {
 
 func b64u(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
 
-func aesGCMEncrypt(key, plaintext, aad []byte) (nonce, ct []byte, err error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, nil, err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, nil, err
-	}
-	nonce = make([]byte, gcm.NonceSize())
-	if _, err = rand.Read(nonce); err != nil {
-		return nil, nil, err
-	}
-	ct = gcm.Seal(nil, nonce, plaintext, aad)
-	return nonce, ct, nil
-}
-
-func aesGCMDecrypt(key, nonce, ct, aad []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-	return gcm.Open(nil, nonce, ct, aad)
-}
-
 func genKID() string {
 	b := make([]byte, 32)
 	_, _ = rand.Read(b)
@@ -0,0 +1,144 @@
+package key
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Access token formats. They double as the purpose of the keys signing them.
+const (
+	FormatJWT    = keystore.PurposeJWT
+	FormatPASETO = keystore.PurposePASETO
+)
+
+// AlgPASETOV4Public is the alg stored for PASETO signing keys. It names a
+// PASETO version and purpose rather than a JWS alg, so no JWT verifier will
+// accept a signature made with such a key.
+const AlgPASETOV4Public = "v4.public"
+
+const pasetoV4PublicHeader = "v4.public."
+
+type paserk struct {
+	PID    string `json:"pid"`
+	PASERK string `json:"paserk"`
+}
+
+type paserks struct {
+	Keys []paserk `json:"keys"`
+}
+
+// PASERKPublic returns the k4.public PASERK of pub.
+func PASERKPublic(pub ed25519.PublicKey) string {
+	return "k4.public." + b64u(pub)
+}
+
+// PASERKID returns the k4.pid PASERK identifying pub. It is both the kid of
+// the key in jwk_keys and the kid in the footer of the tokens it signs.
+func PASERKID(pub ed25519.PublicKey) string {
+	const h = "k4.pid."
+	d, _ := blake2b.New(33, nil)
+	d.Write([]byte(h))
+	d.Write([]byte(PASERKPublic(pub)))
+	return h + b64u(d.Sum(nil))
+}
+
+// NewPASETOSigner returns a Signer issuing PASETO v4.public tokens with the
+// active PASETO key.
+func NewPASETOSigner(ctx context.Context, m *Manager, aud, iss string, ttl time.Duration) (*Signer, error) {
+	kid, alg, priv, err := m.LoadActiveSigner(ctx, keystore.PurposePASETO)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{KID: kid, Alg: alg, Priv: priv, Iss: iss, Aud: aud, TTL: ttl}, nil
+}
+
+// PASERKs returns the public PASETO keys in use, for the PASERK endpoint.
+func (m *Manager) PASERKs(ctx context.Context) (paserks, error) {
+	keys, err := m.publicJWKs(ctx, keystore.PurposePASETO)
+	if err != nil {
+		return paserks{}, err
+	}
+
+	set := paserks{Keys: make([]paserk, 0, len(keys))}
+	for _, k := range keys {
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return paserks{}, ErrInvalidKey
+		}
+		set.Keys = append(set.Keys, paserk{PID: k.Kid, PASERK: PASERKPublic(x)})
+	}
+	return set, nil
+}
+
+// signPASETO encodes claims as a PASETO v4.public token with the kid in the
+// footer. The registered time claims become RFC 3339 strings and a single
+// audience a plain string, as PASETO defines them.
+func (s *Signer) signPASETO(claims CustomClaims) (string, error) {
+	priv, ok := s.Priv.(ed25519.PrivateKey)
+	if !ok {
+		return "", ErrUnsupportedKey
+	}
+
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return "", err
+	}
+	for name, t := range map[string]*time.Time{
+		"exp": timeOf(claims.ExpiresAt),
+		"iat": timeOf(claims.IssuedAt),
+		"nbf": timeOf(claims.NotBefore),
+	} {
+		if t != nil {
+			payload[name] = t.UTC().Format(time.RFC3339)
+		}
+	}
+	if len(claims.Audience) == 1 {
+		payload["aud"] = claims.Audience[0]
+	}
+
+	m, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	f, err := json.Marshal(map[string]string{"kid": s.KID})
+	if err != nil {
+		return "", err
+	}
+
+	sig := ed25519.Sign(priv, pae([]byte(pasetoV4PublicHeader), m, f, nil))
+	return pasetoV4PublicHeader + b64u(append(m, sig...)) + "." + b64u(f), nil
+}
+
+func timeOf(d *jwt.NumericDate) *time.Time {
+	if d == nil {
+		return nil
+	}
+	return &d.Time
+}
+
+// pae is the pre-authentication encoding of PASETO.
+func pae(pieces ...[]byte) []byte {
+	out := le64(len(pieces))
+	for _, p := range pieces {
+		out = append(out, le64(len(p))...)
+		out = append(out, p...)
+	}
+	return out
+}
+
+func le64(n int) []byte {
+	return binary.LittleEndian.AppendUint64(nil, uint64(n)&math.MaxInt64)
+}
@@ -0,0 +1,35 @@
+// Package backend opens the keystore.Store selected by configuration.
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/memory"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/postgres"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// Open returns the store for cfg.Driver. pg is the Postgres connection used
+// for the postgres driver.
+func Open(ctx context.Context, cfg keystore.Config, pg *sql.DB, q *db.Queries) (keystore.Store, error) {
+	switch cfg.Driver {
+	case "", "postgres":
+		return postgres.New(pg, q), nil
+	case "sqlite":
+		sqlDB, err := sql.Open("sqlite", cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		return sqlite.New(ctx, sqlDB)
+	case "memory":
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown key store driver %q", cfg.Driver)
+	}
+}
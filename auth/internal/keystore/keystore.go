@@ -0,0 +1,116 @@
+// Package keystore defines where jwk_keys rows live. key.Manager only deals
+// with already sealed keys, so a Store never sees plaintext private keys.
+//
+// Keys belong to a tenant. A Store only reads and changes the keys of its
+// own tenant, RootTenant for the store returned by a backend and the one
+// named for the views returned by Tenant.
+package keystore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	StatusActive   = "ACTIVE"
+	StatusRetiring = "RETIRING"
+	StatusRetired  = "RETIRED"
+)
+
+// Purposes keep the JWT signing keys and the PASETO v4.public keys apart, so
+// each rotates on its own and a key is never used for both token formats.
+const (
+	PurposeJWT    = "jwt"
+	PurposePASETO = "paseto"
+)
+
+// RootTenant owns the keys of the root issuer.
+const RootTenant = ""
+
+var (
+	ErrNotFound  = errors.New("keystore: key not found")
+	ErrKIDExists = errors.New("keystore: kid already exists")
+	ErrCAExists  = errors.New("keystore: ca already exists")
+)
+
+// Key is a stored signing key. PrivCiphertext and PrivNonce hold the private
+// key sealed according to EnvelopeVersion, under the DEK in WrappedDEK.
+// Tenant is set by the store: keys are always stored for the tenant of the
+// Store they are handed to.
+type Key struct {
+	KID             string
+	Alg             string
+	PublicJWK       json.RawMessage
+	Status          string
+	Purpose         string
+	Tenant          string
+	PrivCiphertext  []byte
+	PrivNonce       []byte
+	WrappedDEK      []byte
+	KEKRef          string
+	EnvelopeVersion int16
+	CreatedAt       time.Time
+	RotatedAt       time.Time
+}
+
+// CA is the internal certificate authority that certifies signing keys. Its
+// private key is sealed the same way as a Key's.
+type CA struct {
+	CertDER         []byte
+	PrivCiphertext  []byte
+	WrappedDEK      []byte
+	KEKRef          string
+	EnvelopeVersion int16
+	CreatedAt       time.Time
+}
+
+type PublicKey struct {
+	KID       string
+	PublicJWK json.RawMessage
+}
+
+type Store interface {
+	// Tenant returns a view of the same store holding the keys of tenant.
+	// KIDs are unique across tenants.
+	Tenant(tenant string) Store
+
+	// Create stores k with k.Status without touching other keys.
+	Create(ctx context.Context, k Key) error
+	// Rotate retires the RETIRING key, demotes the ACTIVE key to RETIRING
+	// and stores k as ACTIVE, atomically. Only keys with k.Purpose change.
+	Rotate(ctx context.Context, k Key) error
+	// GetActive returns the ACTIVE key of purpose, or the newest RETIRING
+	// key when there is no ACTIVE one.
+	GetActive(ctx context.Context, purpose string) (Key, error)
+	// ListPublic returns the public JWKs of ACTIVE and RETIRING keys of
+	// purpose, newest first.
+	ListPublic(ctx context.Context, purpose string) ([]PublicKey, error)
+	// Retire marks kid RETIRED so it is neither used nor published.
+	Retire(ctx context.Context, kid string) error
+	// SetPublicJWK replaces the public JWK of the ACTIVE or RETIRING key
+	// kid, e.g. to publish a renewed certificate, or returns ErrNotFound.
+	SetPublicJWK(ctx context.Context, kid string, publicJWK json.RawMessage) error
+	// Reseal replaces every key whose EnvelopeVersion is below version with
+	// the key returned by fn, atomically, and returns how many were replaced.
+	// It covers the keys of every tenant, since they share the KEK.
+	Reseal(ctx context.Context, version int16, fn func(Key) (Key, error)) (int, error)
+
+	// GetCA returns the internal CA, or ErrNotFound before CreateCA. The CA
+	// is shared by all tenants.
+	GetCA(ctx context.Context) (CA, error)
+	// CreateCA stores the internal CA. There is at most one, so a second
+	// call returns ErrCAExists.
+	CreateCA(ctx context.Context, ca CA) error
+}
+
+type Config struct {
+	// Driver is one of postgres, sqlite or memory.
+	Driver     string `env:"DRIVER" envDefault:"postgres"`
+	SQLitePath string `env:"SQLITE_PATH" envDefault:"keys.db"`
+	// RotationPeriod is the age at which cmd/rotate replaces the root
+	// issuer's signing keys; zero replaces them on every run. Tenants have
+	// their own period.
+	RotationPeriod time.Duration `env:"ROTATION_PERIOD" envDefault:"0s"`
+}
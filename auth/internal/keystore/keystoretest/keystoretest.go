@@ -0,0 +1,452 @@
+// Package keystoretest is a conformance suite for keystore.Store
+// implementations. Backends call Run from their own tests:
+//
+//	func TestStore(t *testing.T) {
+//		keystoretest.Run(t, func(t *testing.T) keystore.Store { return memory.New() })
+//	}
+package keystoretest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+)
+
+// Run exercises a fresh, empty store returned by newStore in every subtest.
+func Run(t *testing.T, newStore func(t *testing.T) keystore.Store) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s keystore.Store)
+	}{
+		{"GetActiveEmpty", testGetActiveEmpty},
+		{"CreateAndGetActive", testCreateAndGetActive},
+		{"CreateDuplicateKID", testCreateDuplicateKID},
+		{"CreateKeepsOtherKeys", testCreateKeepsOtherKeys},
+		{"RotateChain", testRotateChain},
+		{"GetActiveFallsBackToRetiring", testGetActiveFallsBackToRetiring},
+		{"ListPublicOrder", testListPublicOrder},
+		{"PurposesRotateSeparately", testPurposesRotateSeparately},
+		{"TenantsAreIsolated", testTenantsAreIsolated},
+		{"Retire", testRetire},
+		{"RetireUnknown", testRetireUnknown},
+		{"SetPublicJWK", testSetPublicJWK},
+		{"Reseal", testReseal},
+		{"ResealFailureIsAtomic", testResealFailureIsAtomic},
+		{"CA", testCA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newKey(kid, status string) keystore.Key {
+	jwk, _ := json.Marshal(map[string]string{"kty": "EC", "kid": kid})
+	return keystore.Key{
+		KID:             kid,
+		Alg:             "ES256",
+		PublicJWK:       jwk,
+		Status:          status,
+		Purpose:         keystore.PurposeJWT,
+		PrivCiphertext:  []byte("ct:" + kid),
+		PrivNonce:       []byte("nonce:" + kid),
+		WrappedDEK:      []byte("dek:" + kid),
+		KEKRef:          "test://kek",
+		EnvelopeVersion: 0,
+	}
+}
+
+func mustCreate(t *testing.T, s keystore.Store, k keystore.Key) {
+	t.Helper()
+	if err := s.Create(context.Background(), k); err != nil {
+		t.Fatalf("Create(%s): %v", k.KID, err)
+	}
+}
+
+func mustRotate(t *testing.T, s keystore.Store, kid string) {
+	t.Helper()
+	if err := s.Rotate(context.Background(), newKey(kid, keystore.StatusActive)); err != nil {
+		t.Fatalf("Rotate(%s): %v", kid, err)
+	}
+}
+
+func activeKID(t *testing.T, s keystore.Store) string {
+	t.Helper()
+	k, err := s.GetActive(context.Background(), keystore.PurposeJWT)
+	if err != nil {
+		t.Fatalf("GetActive: %v", err)
+	}
+	return k.KID
+}
+
+func publicKIDs(t *testing.T, s keystore.Store) []string {
+	t.Helper()
+	keys, err := s.ListPublic(context.Background(), keystore.PurposeJWT)
+	if err != nil {
+		t.Fatalf("ListPublic: %v", err)
+	}
+	kids := make([]string, len(keys))
+	for i, k := range keys {
+		kids[i] = k.KID
+	}
+	return kids
+}
+
+func expectKIDs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got kids %v, want %v", got, want)
+	}
+}
+
+func testGetActiveEmpty(t *testing.T, s keystore.Store) {
+	if _, err := s.GetActive(context.Background(), keystore.PurposeJWT); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("GetActive on empty store: got %v, want ErrNotFound", err)
+	}
+	expectKIDs(t, publicKIDs(t, s))
+}
+
+func testCreateAndGetActive(t *testing.T, s keystore.Store) {
+	want := newKey("a", keystore.StatusActive)
+	mustCreate(t, s, want)
+
+	got, err := s.GetActive(context.Background(), keystore.PurposeJWT)
+	if err != nil {
+		t.Fatalf("GetActive: %v", err)
+	}
+	if got.KID != want.KID || got.Alg != want.Alg || got.Status != want.Status ||
+		string(got.PrivCiphertext) != string(want.PrivCiphertext) ||
+		string(got.PrivNonce) != string(want.PrivNonce) ||
+		string(got.WrappedDEK) != string(want.WrappedDEK) ||
+		got.KEKRef != want.KEKRef ||
+		got.EnvelopeVersion != want.EnvelopeVersion {
+		t.Fatalf("GetActive returned %+v, want %+v", got, want)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Fatal("CreatedAt not set")
+	}
+
+	var jwk map[string]string
+	if err := json.Unmarshal(got.PublicJWK, &jwk); err != nil || jwk["kid"] != "a" {
+		t.Fatalf("PublicJWK round trip: %s (%v)", got.PublicJWK, err)
+	}
+}
+
+func testCreateDuplicateKID(t *testing.T, s keystore.Store) {
+	mustCreate(t, s, newKey("a", keystore.StatusActive))
+
+	err := s.Create(context.Background(), newKey("a", keystore.StatusRetiring))
+	if !errors.Is(err, keystore.ErrKIDExists) {
+		t.Fatalf("Create duplicate: got %v, want ErrKIDExists", err)
+	}
+	err = s.Rotate(context.Background(), newKey("a", keystore.StatusActive))
+	if !errors.Is(err, keystore.ErrKIDExists) {
+		t.Fatalf("Rotate duplicate: got %v, want ErrKIDExists", err)
+	}
+	expectKIDs(t, publicKIDs(t, s), "a")
+}
+
+func testCreateKeepsOtherKeys(t *testing.T, s keystore.Store) {
+	mustCreate(t, s, newKey("a", keystore.StatusActive))
+	mustCreate(t, s, newKey("b", keystore.StatusRetiring))
+	mustCreate(t, s, newKey("c", keystore.StatusRetired))
+
+	if kid := activeKID(t, s); kid != "a" {
+		t.Fatalf("active kid %q, want a", kid)
+	}
+	expectKIDs(t, publicKIDs(t, s), "b", "a")
+}
+
+func testRotateChain(t *testing.T, s keystore.Store) {
+	mustRotate(t, s, "a")
+	mustRotate(t, s, "b")
+
+	if kid := activeKID(t, s); kid != "b" {
+		t.Fatalf("active kid %q after first rotation, want b", kid)
+	}
+	expectKIDs(t, publicKIDs(t, s), "b", "a")
+
+	mustRotate(t, s, "c")
+
+	if kid := activeKID(t, s); kid != "c" {
+		t.Fatalf("active kid %q after second rotation, want c", kid)
+	}
+	// a is RETIRED now and no longer published.
+	expectKIDs(t, publicKIDs(t, s), "c", "b")
+}
+
+func testGetActiveFallsBackToRetiring(t *testing.T, s keystore.Store) {
+	mustCreate(t, s, newKey("a", keystore.StatusRetiring))
+
+	k, err := s.GetActive(context.Background(), keystore.PurposeJWT)
+	if err != nil {
+		t.Fatalf("GetActive: %v", err)
+	}
+	if k.KID != "a" || k.Status != keystore.StatusRetiring {
+		t.Fatalf("GetActive returned %s/%s, want a/RETIRING", k.KID, k.Status)
+	}
+}
+
+func testListPublicOrder(t *testing.T, s keystore.Store) {
+	mustCreate(t, s, newKey("a", keystore.StatusRetiring))
+	mustCreate(t, s, newKey("b", keystore.StatusRetiring))
+	mustCreate(t, s, newKey("c", keystore.StatusActive))
+
+	expectKIDs(t, publicKIDs(t, s), "c", "b", "a")
+}
+
+func testPurposesRotateSeparately(t *testing.T, s keystore.Store) {
+	ctx := context.Background()
+	mustRotate(t, s, "a")
+	mustRotate(t, s, "b")
+
+	p := newKey("p1", keystore.StatusActive)
+	p.Purpose = keystore.PurposePASETO
+	if err := s.Rotate(ctx, p); err != nil {
+		t.Fatalf("Rotate(p1): %v", err)
+	}
+	p = newKey("p2", keystore.StatusActive)
+	p.Purpose = keystore.PurposePASETO
+	if err := s.Rotate(ctx, p); err != nil {
+		t.Fatalf("Rotate(p2): %v", err)
+	}
+
+	// Rotating the PASETO keys leaves the JWT keys where they were.
+	if kid := activeKID(t, s); kid != "b" {
+		t.Fatalf("active jwt kid %q, want b", kid)
+	}
+	expectKIDs(t, publicKIDs(t, s), "b", "a")
+
+	k, err := s.GetActive(ctx, keystore.PurposePASETO)
+	if err != nil {
+		t.Fatalf("GetActive(paseto): %v", err)
+	}
+	if k.KID != "p2" || k.Purpose != keystore.PurposePASETO {
+		t.Fatalf("GetActive(paseto) returned %s/%s, want p2/paseto", k.KID, k.Purpose)
+	}
+	keys, err := s.ListPublic(ctx, keystore.PurposePASETO)
+	if err != nil {
+		t.Fatalf("ListPublic(paseto): %v", err)
+	}
+	if len(keys) != 2 || keys[0].KID != "p2" || keys[1].KID != "p1" {
+		t.Fatalf("ListPublic(paseto) returned %v, want p2 and p1", keys)
+	}
+}
+
+func testTenantsAreIsolated(t *testing.T, s keystore.Store) {
+	ctx := context.Background()
+	acme := s.Tenant("acme")
+	mustRotate(t, s, "a")
+	mustRotate(t, s, "b")
+	mustRotate(t, acme, "t1")
+	mustRotate(t, acme, "t2")
+	mustRotate(t, acme, "t3")
+
+	// Rotating the tenant's keys leaves the root keys where they were.
+	if kid := activeKID(t, s); kid != "b" {
+		t.Fatalf("active root kid %q, want b", kid)
+	}
+	expectKIDs(t, publicKIDs(t, s), "b", "a")
+	if kid := activeKID(t, acme); kid != "t3" {
+		t.Fatalf("active acme kid %q, want t3", kid)
+	}
+	expectKIDs(t, publicKIDs(t, acme), "t3", "t2")
+
+	k, err := acme.GetActive(ctx, keystore.PurposeJWT)
+	if err != nil {
+		t.Fatalf("GetActive(acme): %v", err)
+	}
+	if k.Tenant != "acme" {
+		t.Fatalf("GetActive(acme) returned a key of tenant %q", k.Tenant)
+	}
+
+	if _, err := s.Tenant("other").GetActive(ctx, keystore.PurposeJWT); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("GetActive of a tenant without keys: got %v, want ErrNotFound", err)
+	}
+	if err := acme.Retire(ctx, "b"); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("Retire of another tenant's kid: got %v, want ErrNotFound", err)
+	}
+	if err := acme.Create(ctx, newKey("a", keystore.StatusActive)); !errors.Is(err, keystore.ErrKIDExists) {
+		t.Fatalf("Create of another tenant's kid: got %v, want ErrKIDExists", err)
+	}
+	expectKIDs(t, publicKIDs(t, s), "b", "a")
+}
+
+func testRetire(t *testing.T, s keystore.Store) {
+	mustRotate(t, s, "a")
+	mustRotate(t, s, "b")
+
+	if err := s.Retire(context.Background(), "b"); err != nil {
+		t.Fatalf("Retire: %v", err)
+	}
+
+	if kid := activeKID(t, s); kid != "a" {
+		t.Fatalf("active kid %q after retiring b, want the RETIRING key a", kid)
+	}
+	expectKIDs(t, publicKIDs(t, s), "a")
+}
+
+func testRetireUnknown(t *testing.T, s keystore.Store) {
+	if err := s.Retire(context.Background(), "missing"); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("Retire unknown kid: got %v, want ErrNotFound", err)
+	}
+}
+
+func testSetPublicJWK(t *testing.T, s keystore.Store) {
+	ctx := context.Background()
+	mustRotate(t, s, "a")
+	mustRotate(t, s, "b")
+	mustRotate(t, s, "c")
+
+	jwk := json.RawMessage(`{"kty":"EC","kid":"b","x5c":["leaf"]}`)
+	if err := s.SetPublicJWK(ctx, "b", jwk); err != nil {
+		t.Fatalf("SetPublicJWK: %v", err)
+	}
+	keys, err := s.ListPublic(ctx, keystore.PurposeJWT)
+	if err != nil {
+		t.Fatalf("ListPublic: %v", err)
+	}
+	for _, k := range keys {
+		if k.KID != "b" {
+			continue
+		}
+		// Compare decoded, since Postgres stores the JWK as JSONB.
+		var got struct {
+			X5C []string `json:"x5c"`
+		}
+		if err := json.Unmarshal(k.PublicJWK, &got); err != nil || len(got.X5C) != 1 || got.X5C[0] != "leaf" {
+			t.Fatalf("public JWK of b is %s, want %s", k.PublicJWK, jwk)
+		}
+	}
+
+	// Retired keys are not published, so they are not updated either.
+	if err := s.SetPublicJWK(ctx, "a", jwk); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("SetPublicJWK of a RETIRED key: got %v, want ErrNotFound", err)
+	}
+	if err := s.Tenant("acme").SetPublicJWK(ctx, "c", jwk); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("SetPublicJWK of another tenant's key: got %v, want ErrNotFound", err)
+	}
+}
+
+func testReseal(t *testing.T, s keystore.Store) {
+	mustCreate(t, s, newKey("a", keystore.StatusRetiring))
+	current := newKey("b", keystore.StatusActive)
+	current.EnvelopeVersion = 1
+	mustCreate(t, s, current)
+
+	var seen []string
+	n, err := s.Reseal(context.Background(), 1, func(k keystore.Key) (keystore.Key, error) {
+		seen = append(seen, k.KID)
+		if string(k.PrivNonce) != "nonce:"+k.KID {
+			t.Errorf("Reseal passed nonce %q for %s", k.PrivNonce, k.KID)
+		}
+		k.PrivCiphertext = []byte("resealed:" + k.KID)
+		k.WrappedDEK = []byte("new-dek:" + k.KID)
+		k.KEKRef = "test://kek2"
+		k.EnvelopeVersion = 1
+		return k, nil
+	})
+	if err != nil {
+		t.Fatalf("Reseal: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("Reseal updated %d keys, want 1", n)
+	}
+	expectKIDs(t, seen, "a")
+
+	if err := s.Retire(context.Background(), "b"); err != nil {
+		t.Fatalf("Retire: %v", err)
+	}
+	k, err := s.GetActive(context.Background(), keystore.PurposeJWT)
+	if err != nil {
+		t.Fatalf("GetActive: %v", err)
+	}
+	if k.KID != "a" || string(k.PrivCiphertext) != "resealed:a" || len(k.PrivNonce) != 0 ||
+		string(k.WrappedDEK) != "new-dek:a" || k.KEKRef != "test://kek2" || k.EnvelopeVersion != 1 {
+		t.Fatalf("resealed key not stored: %+v", k)
+	}
+
+	n, err = s.Reseal(context.Background(), 1, func(k keystore.Key) (keystore.Key, error) {
+		t.Errorf("Reseal called fn for up to date key %s", k.KID)
+		return k, nil
+	})
+	if err != nil || n != 0 {
+		t.Fatalf("second Reseal: n=%d err=%v, want 0 and nil", n, err)
+	}
+}
+
+func testResealFailureIsAtomic(t *testing.T, s keystore.Store) {
+	mustCreate(t, s, newKey("a", keystore.StatusRetiring))
+	mustCreate(t, s, newKey("b", keystore.StatusActive))
+
+	boom := errors.New("boom")
+	var calls int
+	_, err := s.Reseal(context.Background(), 1, func(k keystore.Key) (keystore.Key, error) {
+		calls++
+		if calls == 2 {
+			return keystore.Key{}, boom
+		}
+		k.PrivCiphertext = []byte("resealed:" + k.KID)
+		k.EnvelopeVersion = 1
+		return k, nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Reseal: got %v, want fn error", err)
+	}
+
+	n, err := s.Reseal(context.Background(), 1, func(k keystore.Key) (keystore.Key, error) {
+		k.EnvelopeVersion = 1
+		return k, nil
+	})
+	if err != nil {
+		t.Fatalf("Reseal: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("failed Reseal left partial updates: %d keys still outdated, want 2", n)
+	}
+}
+
+func testCA(t *testing.T, s keystore.Store) {
+	ctx := context.Background()
+	if _, err := s.GetCA(ctx); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("GetCA before CreateCA: got %v, want ErrNotFound", err)
+	}
+
+	want := keystore.CA{
+		CertDER:         []byte("cert"),
+		PrivCiphertext:  []byte("ct:ca"),
+		WrappedDEK:      []byte("dek:ca"),
+		KEKRef:          "test://kek",
+		EnvelopeVersion: 1,
+	}
+	if err := s.CreateCA(ctx, want); err != nil {
+		t.Fatalf("CreateCA: %v", err)
+	}
+
+	got, err := s.GetCA(ctx)
+	if err != nil {
+		t.Fatalf("GetCA: %v", err)
+	}
+	if string(got.CertDER) != "cert" || string(got.PrivCiphertext) != "ct:ca" || string(got.WrappedDEK) != "dek:ca" ||
+		got.KEKRef != want.KEKRef || got.EnvelopeVersion != want.EnvelopeVersion || got.CreatedAt.IsZero() {
+		t.Fatalf("GetCA returned %+v, want %+v", got, want)
+	}
+
+	other := want
+	other.CertDER = []byte("other")
+	if err := s.CreateCA(ctx, other); !errors.Is(err, keystore.ErrCAExists) {
+		t.Fatalf("second CreateCA: got %v, want ErrCAExists", err)
+	}
+	if got, _ := s.GetCA(ctx); string(got.CertDER) != "cert" {
+		t.Fatal("second CreateCA replaced the CA")
+	}
+	expectKIDs(t, publicKIDs(t, s))
+}
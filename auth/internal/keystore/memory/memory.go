@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+)
+
+// Store keeps keys in process memory. Keys are lost on restart, so it is
+// meant for tests and throwaway local instances.
+type Store struct {
+	*state
+	tenant string
+}
+
+// state is shared by a store and its tenant views.
+type state struct {
+	mu   sync.RWMutex
+	keys []keystore.Key // oldest first
+	ca   *keystore.CA
+	now  func() time.Time
+}
+
+var _ keystore.Store = (*Store)(nil)
+
+func New() *Store {
+	return &Store{state: &state{now: time.Now}}
+}
+
+func (s *Store) Tenant(tenant string) keystore.Store {
+	return &Store{state: s.state, tenant: tenant}
+}
+
+func (s *Store) Create(ctx context.Context, k keystore.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.indexOf(k.KID) >= 0 {
+		return keystore.ErrKIDExists
+	}
+	s.append(k)
+	return nil
+}
+
+func (s *Store) Rotate(ctx context.Context, k keystore.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.indexOf(k.KID) >= 0 {
+		return keystore.ErrKIDExists
+	}
+
+	now := s.now()
+	for i := range s.keys {
+		if s.keys[i].Tenant != s.tenant || s.keys[i].Purpose != k.Purpose {
+			continue
+		}
+		switch s.keys[i].Status {
+		case keystore.StatusRetiring:
+			s.keys[i].Status = keystore.StatusRetired
+		case keystore.StatusActive:
+			s.keys[i].Status = keystore.StatusRetiring
+			s.keys[i].RotatedAt = now
+		}
+	}
+
+	k.Status = keystore.StatusActive
+	s.append(k)
+	return nil
+}
+
+func (s *Store) GetActive(ctx context.Context, purpose string) (keystore.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, status := range []string{keystore.StatusActive, keystore.StatusRetiring} {
+		for i := len(s.keys) - 1; i >= 0; i-- {
+			if s.keys[i].Tenant == s.tenant && s.keys[i].Purpose == purpose && s.keys[i].Status == status {
+				return clone(s.keys[i]), nil
+			}
+		}
+	}
+	return keystore.Key{}, keystore.ErrNotFound
+}
+
+func (s *Store) ListPublic(ctx context.Context, purpose string) ([]keystore.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []keystore.PublicKey
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		k := s.keys[i]
+		if k.Tenant != s.tenant || k.Purpose != purpose {
+			continue
+		}
+		if k.Status == keystore.StatusActive || k.Status == keystore.StatusRetiring {
+			keys = append(keys, keystore.PublicKey{KID: k.KID, PublicJWK: slices.Clone(k.PublicJWK)})
+		}
+	}
+	return keys, nil
+}
+
+func (s *Store) Retire(ctx context.Context, kid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(kid)
+	if i < 0 || s.keys[i].Tenant != s.tenant {
+		return keystore.ErrNotFound
+	}
+	s.keys[i].Status = keystore.StatusRetired
+	return nil
+}
+
+func (s *Store) SetPublicJWK(ctx context.Context, kid string, publicJWK json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(kid)
+	if i < 0 || s.keys[i].Tenant != s.tenant || s.keys[i].Status == keystore.StatusRetired {
+		return keystore.ErrNotFound
+	}
+	s.keys[i].PublicJWK = slices.Clone(publicJWK)
+	return nil
+}
+
+func (s *Store) Reseal(ctx context.Context, version int16, fn func(keystore.Key) (keystore.Key, error)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Work on a copy so a failing fn leaves the store untouched.
+	keys := make([]keystore.Key, len(s.keys))
+	copy(keys, s.keys)
+
+	var n int
+	for i, k := range keys {
+		if k.EnvelopeVersion >= version {
+			continue
+		}
+		sealed, err := fn(clone(k))
+		if err != nil {
+			return 0, err
+		}
+		keys[i].PrivCiphertext = sealed.PrivCiphertext
+		keys[i].PrivNonce = nil
+		keys[i].WrappedDEK = sealed.WrappedDEK
+		keys[i].KEKRef = sealed.KEKRef
+		keys[i].EnvelopeVersion = sealed.EnvelopeVersion
+		n++
+	}
+
+	s.keys = keys
+	return n, nil
+}
+
+func (s *Store) GetCA(ctx context.Context) (keystore.CA, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.ca == nil {
+		return keystore.CA{}, keystore.ErrNotFound
+	}
+	ca := *s.ca
+	ca.CertDER = slices.Clone(ca.CertDER)
+	ca.PrivCiphertext = slices.Clone(ca.PrivCiphertext)
+	ca.WrappedDEK = slices.Clone(ca.WrappedDEK)
+	return ca, nil
+}
+
+func (s *Store) CreateCA(ctx context.Context, ca keystore.CA) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ca != nil {
+		return keystore.ErrCAExists
+	}
+	ca.CertDER = slices.Clone(ca.CertDER)
+	ca.PrivCiphertext = slices.Clone(ca.PrivCiphertext)
+	ca.WrappedDEK = slices.Clone(ca.WrappedDEK)
+	ca.CreatedAt = s.now()
+	s.ca = &ca
+	return nil
+}
+
+// append stores a copy of k for s.tenant. s.mu must be held.
+func (s *Store) append(k keystore.Key) {
+	k = clone(k)
+	k.Tenant = s.tenant
+	k.CreatedAt = s.now()
+	k.RotatedAt = time.Time{}
+	s.keys = append(s.keys, k)
+}
+
+func (s *state) indexOf(kid string) int {
+	return slices.IndexFunc(s.keys, func(k keystore.Key) bool { return k.KID == kid })
+}
+
+func clone(k keystore.Key) keystore.Key {
+	k.PublicJWK = slices.Clone(k.PublicJWK)
+	k.PrivCiphertext = slices.Clone(k.PrivCiphertext)
+	k.PrivNonce = slices.Clone(k.PrivNonce)
+	k.WrappedDEK = slices.Clone(k.WrappedDEK)
+	return k
+}
@@ -0,0 +1,13 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/keystoretest"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/memory"
+)
+
+func TestStore(t *testing.T) {
+	keystoretest.Run(t, func(t *testing.T) keystore.Store { return memory.New() })
+}
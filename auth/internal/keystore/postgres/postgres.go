@@ -0,0 +1,271 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+)
+
+// Store keeps keys in the jwk_keys table through the sqlc queries.
+type Store struct {
+	db      *sql.DB
+	queries *db.Queries
+	tenant  string
+}
+
+var _ keystore.Store = (*Store)(nil)
+
+func New(sqlDB *sql.DB, q *db.Queries) *Store {
+	return &Store{
+		db:      sqlDB,
+		queries: q,
+	}
+}
+
+func (s *Store) Tenant(tenant string) keystore.Store {
+	return &Store{
+		db:      s.db,
+		queries: s.queries,
+		tenant:  tenant,
+	}
+}
+
+func (s *Store) Create(ctx context.Context, k keystore.Key) error {
+	return s.withTx(ctx, func(q *db.Queries) error {
+		if err := checkKID(ctx, q, k.KID); err != nil {
+			return err
+		}
+		return q.ImportJWK(ctx, db.ImportJWKParams{
+			KID:             k.KID,
+			ALG:             k.Alg,
+			PublicJWK:       k.PublicJWK,
+			Status:          k.Status,
+			Purpose:         k.Purpose,
+			PrivCiphertext:  k.PrivCiphertext,
+			PrivNonce:       k.PrivNonce,
+			WrappedDEK:      k.WrappedDEK,
+			KEKRef:          nullString(k.KEKRef),
+			EnvelopeVersion: k.EnvelopeVersion,
+			Tenant:          s.tenant,
+		})
+	})
+}
+
+func (s *Store) Rotate(ctx context.Context, k keystore.Key) error {
+	return s.withTx(ctx, func(q *db.Queries) error {
+		if err := checkKID(ctx, q, k.KID); err != nil {
+			return err
+		}
+		err := q.UpdateJWKToRetired(ctx, db.UpdateJWKToRetiredParams{
+			Purpose: k.Purpose,
+			Tenant:  s.tenant,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.UpdateJWKToRetiring(ctx, db.UpdateJWKToRetiringParams{
+			Purpose: k.Purpose,
+			Tenant:  s.tenant,
+		})
+		if err != nil {
+			return err
+		}
+		return q.CreateJWK(ctx, db.CreateJWKParams{
+			KID:             k.KID,
+			ALG:             k.Alg,
+			PublicJWK:       k.PublicJWK,
+			Purpose:         k.Purpose,
+			PrivCiphertext:  k.PrivCiphertext,
+			PrivNonce:       k.PrivNonce,
+			WrappedDEK:      k.WrappedDEK,
+			KEKRef:          nullString(k.KEKRef),
+			EnvelopeVersion: k.EnvelopeVersion,
+			Tenant:          s.tenant,
+		})
+	})
+}
+
+func (s *Store) GetActive(ctx context.Context, purpose string) (keystore.Key, error) {
+	r, err := s.queries.GetJWK(ctx, db.GetJWKParams{
+		Purpose: purpose,
+		Tenant:  s.tenant,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return keystore.Key{}, keystore.ErrNotFound
+	}
+	if err != nil {
+		return keystore.Key{}, err
+	}
+
+	return keystore.Key{
+		KID:             r.KID,
+		Alg:             r.ALG,
+		PublicJWK:       r.PublicJWK,
+		Status:          r.Status,
+		Purpose:         r.Purpose,
+		Tenant:          r.Tenant,
+		PrivCiphertext:  r.PrivCiphertext,
+		PrivNonce:       r.PrivNonce,
+		WrappedDEK:      r.WrappedDEK,
+		KEKRef:          r.KEKRef.String,
+		EnvelopeVersion: r.EnvelopeVersion,
+		CreatedAt:       r.CreatedAt,
+		RotatedAt:       r.RotatedAt.Time,
+	}, nil
+}
+
+func (s *Store) ListPublic(ctx context.Context, purpose string) ([]keystore.PublicKey, error) {
+	rows, err := s.queries.GetPubJWK(ctx, db.GetPubJWKParams{
+		Purpose: purpose,
+		Tenant:  s.tenant,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]keystore.PublicKey, len(rows))
+	for i, r := range rows {
+		keys[i] = keystore.PublicKey{KID: r.KID, PublicJWK: r.PublicJWK}
+	}
+	return keys, nil
+}
+
+func (s *Store) Retire(ctx context.Context, kid string) error {
+	n, err := s.queries.RetireJWK(ctx, db.RetireJWKParams{
+		KID:    kid,
+		Tenant: s.tenant,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return keystore.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) SetPublicJWK(ctx context.Context, kid string, publicJWK json.RawMessage) error {
+	n, err := s.queries.UpdateJWKPublic(ctx, db.UpdateJWKPublicParams{
+		KID:       kid,
+		Tenant:    s.tenant,
+		PublicJWK: publicJWK,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return keystore.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) Reseal(ctx context.Context, version int16, fn func(keystore.Key) (keystore.Key, error)) (int, error) {
+	var n int
+	err := s.withTx(ctx, func(q *db.Queries) error {
+		rows, err := q.ListOutdatedJWK(ctx, version)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rows {
+			k, err := fn(keystore.Key{
+				KID:             r.KID,
+				Alg:             r.ALG,
+				PrivCiphertext:  r.PrivCiphertext,
+				PrivNonce:       r.PrivNonce,
+				WrappedDEK:      r.WrappedDEK,
+				KEKRef:          r.KEKRef.String,
+				EnvelopeVersion: r.EnvelopeVersion,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = q.UpdateJWKEnvelope(ctx, db.UpdateJWKEnvelopeParams{
+				KID:             r.KID,
+				PrivCiphertext:  k.PrivCiphertext,
+				WrappedDEK:      k.WrappedDEK,
+				KEKRef:          nullString(k.KEKRef),
+				EnvelopeVersion: k.EnvelopeVersion,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		n = len(rows)
+		return nil
+	})
+	return n, err
+}
+
+func (s *Store) GetCA(ctx context.Context) (keystore.CA, error) {
+	r, err := s.queries.GetCA(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return keystore.CA{}, keystore.ErrNotFound
+	}
+	if err != nil {
+		return keystore.CA{}, err
+	}
+
+	return keystore.CA{
+		CertDER:         r.CertDER,
+		PrivCiphertext:  r.PrivCiphertext,
+		WrappedDEK:      r.WrappedDEK,
+		KEKRef:          r.KEKRef.String,
+		EnvelopeVersion: r.EnvelopeVersion,
+		CreatedAt:       r.CreatedAt,
+	}, nil
+}
+
+func (s *Store) CreateCA(ctx context.Context, ca keystore.CA) error {
+	n, err := s.queries.CreateCA(ctx, db.CreateCAParams{
+		CertDER:         ca.CertDER,
+		PrivCiphertext:  ca.PrivCiphertext,
+		WrappedDEK:      ca.WrappedDEK,
+		KEKRef:          nullString(ca.KEKRef),
+		EnvelopeVersion: ca.EnvelopeVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return keystore.ErrCAExists
+	}
+	return nil
+}
+
+func (s *Store) withTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func checkKID(ctx context.Context, q *db.Queries, kid string) error {
+	exists, err := q.ExistsJWK(ctx, kid)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return keystore.ErrKIDExists
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{
+		String: s,
+		Valid:  s != "",
+	}
+}
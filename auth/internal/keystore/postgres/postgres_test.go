@@ -0,0 +1,35 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/keystoretest"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/postgres"
+)
+
+// TestStore runs against the migrated database KEYSTORE_TEST_POSTGRES_DSN
+// names, whose jwk_keys and jwk_ca tables it empties before every subtest.
+func TestStore(t *testing.T) {
+	dsn := os.Getenv("KEYSTORE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("KEYSTORE_TEST_POSTGRES_DSN not set")
+	}
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	keystoretest.Run(t, func(t *testing.T) keystore.Store {
+		if _, err := sqlDB.ExecContext(context.Background(), `TRUNCATE jwk_keys, jwk_ca`); err != nil {
+			t.Fatal(err)
+		}
+		return postgres.New(sqlDB, db.New(sqlDB))
+	})
+}
@@ -0,0 +1,347 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS jwk_keys (
+  kid TEXT PRIMARY KEY,
+  alg TEXT NOT NULL,
+  public_jwk TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('ACTIVE', 'RETIRING', 'RETIRED')),
+  purpose TEXT NOT NULL DEFAULT 'jwt' CHECK (purpose IN ('jwt', 'paseto')),
+  tenant TEXT NOT NULL DEFAULT '',
+  priv_ciphertext BLOB NOT NULL,
+  priv_nonce BLOB,
+  wrapped_dek BLOB NOT NULL,
+  kek_ref TEXT,
+  envelope_version INTEGER NOT NULL DEFAULT 0,
+  created_at INTEGER NOT NULL, -- unix nanoseconds
+  rotated_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS jwk_ca (
+  id INTEGER PRIMARY KEY CHECK (id = 1), -- at most one CA
+  cert_der BLOB NOT NULL,
+  priv_ciphertext BLOB NOT NULL,
+  wrapped_dek BLOB NOT NULL,
+  kek_ref TEXT,
+  envelope_version INTEGER NOT NULL,
+  created_at INTEGER NOT NULL
+)`
+
+// upgrades add the columns missing from jwk_keys tables created by earlier
+// versions, in order.
+var upgrades = []struct{ column, stmt string }{
+	{"purpose", `ALTER TABLE jwk_keys ADD COLUMN purpose TEXT NOT NULL DEFAULT 'jwt' CHECK (purpose IN ('jwt', 'paseto'))`},
+	{"tenant", `ALTER TABLE jwk_keys ADD COLUMN tenant TEXT NOT NULL DEFAULT ''`},
+}
+
+const keyColumns = `kid, alg, public_jwk, status, purpose, tenant, priv_ciphertext, priv_nonce, wrapped_dek, kek_ref, envelope_version, created_at, rotated_at`
+
+// Store keeps keys in an SQLite database with the same layout as the
+// Postgres jwk_keys table. The caller registers the driver, e.g. by
+// importing modernc.org/sqlite.
+type Store struct {
+	db     *sql.DB
+	now    func() time.Time
+	tenant string
+}
+
+var _ keystore.Store = (*Store)(nil)
+
+// New creates the jwk_keys table if needed. SQLite allows a single writer,
+// so db should be limited to one open connection.
+func New(ctx context.Context, db *sql.DB) (*Store, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, err
+	}
+	for _, u := range upgrades {
+		var exists bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pragma_table_info('jwk_keys') WHERE name = ?)`, u.column).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			if _, err := db.ExecContext(ctx, u.stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Store{db: db, now: time.Now}, nil
+}
+
+func (s *Store) Tenant(tenant string) keystore.Store {
+	return &Store{db: s.db, now: s.now, tenant: tenant}
+}
+
+func (s *Store) Create(ctx context.Context, k keystore.Key) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		return s.insert(ctx, tx, k)
+	})
+}
+
+func (s *Store) Rotate(ctx context.Context, k keystore.Key) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE jwk_keys SET status = 'RETIRED' WHERE status = 'RETIRING' AND purpose = ? AND tenant = ?`, k.Purpose, s.tenant); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE jwk_keys SET status = 'RETIRING', rotated_at = ? WHERE status = 'ACTIVE' AND purpose = ? AND tenant = ?`, s.now().UnixNano(), k.Purpose, s.tenant); err != nil {
+			return err
+		}
+		k.Status = keystore.StatusActive
+		return s.insert(ctx, tx, k)
+	})
+}
+
+func (s *Store) GetActive(ctx context.Context, purpose string) (keystore.Key, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+keyColumns+` FROM jwk_keys
+WHERE status IN ('ACTIVE', 'RETIRING') AND purpose = ? AND tenant = ?
+ORDER BY CASE status WHEN 'ACTIVE' THEN 0 ELSE 1 END, created_at DESC, rowid DESC
+LIMIT 1`, purpose, s.tenant)
+
+	k, err := scanKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return keystore.Key{}, keystore.ErrNotFound
+	}
+	return k, err
+}
+
+func (s *Store) ListPublic(ctx context.Context, purpose string) ([]keystore.PublicKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kid, public_jwk FROM jwk_keys
+WHERE status IN ('ACTIVE', 'RETIRING') AND purpose = ? AND tenant = ?
+ORDER BY created_at DESC, rowid DESC`, purpose, s.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []keystore.PublicKey
+	for rows.Next() {
+		var (
+			k   keystore.PublicKey
+			jwk string
+		)
+		if err := rows.Scan(&k.KID, &jwk); err != nil {
+			return nil, err
+		}
+		k.PublicJWK = []byte(jwk)
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *Store) Retire(ctx context.Context, kid string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE jwk_keys SET status = 'RETIRED' WHERE kid = ? AND tenant = ?`, kid, s.tenant)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return keystore.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) SetPublicJWK(ctx context.Context, kid string, publicJWK json.RawMessage) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE jwk_keys SET public_jwk = ? WHERE kid = ? AND tenant = ? AND status IN ('ACTIVE', 'RETIRING')`, string(publicJWK), kid, s.tenant)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return keystore.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) Reseal(ctx context.Context, version int16, fn func(keystore.Key) (keystore.Key, error)) (int, error) {
+	var n int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT `+keyColumns+` FROM jwk_keys WHERE envelope_version < ? ORDER BY created_at, rowid`, version)
+		if err != nil {
+			return err
+		}
+
+		var outdated []keystore.Key
+		for rows.Next() {
+			k, err := scanKey(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			outdated = append(outdated, k)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, k := range outdated {
+			sealed, err := fn(k)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `UPDATE jwk_keys
+SET priv_ciphertext = ?, priv_nonce = NULL, wrapped_dek = ?, kek_ref = ?, envelope_version = ?
+WHERE kid = ?`, sealed.PrivCiphertext, sealed.WrappedDEK, nullString(sealed.KEKRef), sealed.EnvelopeVersion, k.KID)
+			if err != nil {
+				return err
+			}
+		}
+
+		n = len(outdated)
+		return nil
+	})
+	return n, err
+}
+
+func (s *Store) GetCA(ctx context.Context) (keystore.CA, error) {
+	var (
+		ca        keystore.CA
+		kekRef    sql.NullString
+		createdAt int64
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT cert_der, priv_ciphertext, wrapped_dek, kek_ref, envelope_version, created_at FROM jwk_ca WHERE id = 1`).Scan(
+		&ca.CertDER,
+		&ca.PrivCiphertext,
+		&ca.WrappedDEK,
+		&kekRef,
+		&ca.EnvelopeVersion,
+		&createdAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return keystore.CA{}, keystore.ErrNotFound
+	}
+	if err != nil {
+		return keystore.CA{}, err
+	}
+
+	ca.KEKRef = kekRef.String
+	ca.CreatedAt = time.Unix(0, createdAt)
+	return ca, nil
+}
+
+func (s *Store) CreateCA(ctx context.Context, ca keystore.CA) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO jwk_ca (id, cert_der, priv_ciphertext, wrapped_dek, kek_ref, envelope_version, created_at)
+VALUES (1, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO NOTHING`,
+		ca.CertDER,
+		ca.PrivCiphertext,
+		ca.WrappedDEK,
+		nullString(ca.KEKRef),
+		ca.EnvelopeVersion,
+		s.now().UnixNano(),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return keystore.ErrCAExists
+	}
+	return nil
+}
+
+func (s *Store) insert(ctx context.Context, tx *sql.Tx, k keystore.Key) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM jwk_keys WHERE kid = ?)`, k.KID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return keystore.ErrKIDExists
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO jwk_keys (`+keyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)`,
+		k.KID,
+		k.Alg,
+		string(k.PublicJWK),
+		k.Status,
+		k.Purpose,
+		s.tenant,
+		k.PrivCiphertext,
+		k.PrivNonce,
+		k.WrappedDEK,
+		nullString(k.KEKRef),
+		k.EnvelopeVersion,
+		s.now().UnixNano(),
+	)
+	return err
+}
+
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (keystore.Key, error) {
+	var (
+		k         keystore.Key
+		jwk       string
+		kekRef    sql.NullString
+		createdAt int64
+		rotatedAt sql.NullInt64
+	)
+	err := row.Scan(
+		&k.KID,
+		&k.Alg,
+		&jwk,
+		&k.Status,
+		&k.Purpose,
+		&k.Tenant,
+		&k.PrivCiphertext,
+		&k.PrivNonce,
+		&k.WrappedDEK,
+		&kekRef,
+		&k.EnvelopeVersion,
+		&createdAt,
+		&rotatedAt,
+	)
+	if err != nil {
+		return keystore.Key{}, err
+	}
+
+	k.PublicJWK = []byte(jwk)
+	k.KEKRef = kekRef.String
+	k.CreatedAt = time.Unix(0, createdAt)
+	if rotatedAt.Valid {
+		k.RotatedAt = time.Unix(0, rotatedAt.Int64)
+	}
+	return k, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{
+		String: s,
+		Valid:  s != "",
+	}
+}
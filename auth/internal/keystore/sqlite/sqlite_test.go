@@ -0,0 +1,31 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/keystoretest"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/keystore/sqlite"
+	_ "modernc.org/sqlite"
+)
+
+func TestStore(t *testing.T) {
+	keystoretest.Run(t, func(t *testing.T) keystore.Store {
+		// Every connection to :memory: opens its own database, so one
+		// connection is one fresh store.
+		sqlDB, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+		t.Cleanup(func() { sqlDB.Close() })
+
+		s, err := sqlite.New(context.Background(), sqlDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,134 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+var (
+	ErrChallengeNotFound = errors.New("mfa: sign-in challenge not found or expired")
+	// ErrTooManyAttempts ends a challenge after MaxAttempts wrong codes, or
+	// MaxFailures of the user's.
+	ErrTooManyAttempts = errors.New("mfa: too many invalid codes")
+)
+
+// Challenge is a sign-in waiting for the code of a user who entered the
+// right password.
+type Challenge struct {
+	Tenant   string `json:"tenant,omitempty"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	// Factor is what the challenge was answered with, FactorTOTP or
+	// FactorRecoveryCode, once it is.
+	Factor string `json:"-"`
+}
+
+// StartChallenge records that the user with the given ID and username signed
+// in at tenant with a password, and returns the ID of the challenge to answer
+// with a code.
+func (s *Service) StartChallenge(ctx context.Context, tenant, userID, username string) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+
+	raw, err := json.Marshal(Challenge{Tenant: tenant, UserID: userID, Username: username})
+	if err != nil {
+		return "", err
+	}
+	if err := s.kv.Put(ctx, challengeKey(id), raw, s.cfg.ChallengeTTL); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// AnswerChallenge verifies code for the challenge with the given ID at
+// tenant and returns the challenge, which is then over. Wrong codes return
+// ErrInvalidCode until MaxAttempts for the challenge or MaxFailures for the
+// user, which end the challenge with ErrTooManyAttempts.
+func (s *Service) AnswerChallenge(ctx context.Context, tenant, id, code string) (*Challenge, error) {
+	raw, err := s.kv.Get(ctx, challengeKey(id))
+	if errors.Is(err, oauth.ErrKeyNotFound) {
+		return nil, ErrChallengeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Challenge
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	if c.Tenant != tenant {
+		return nil, ErrChallengeNotFound
+	}
+
+	// Attempts are counted before the code is checked, so concurrent
+	// answers cannot try more codes than the limits allow. The user's are
+	// counted until a right code.
+	attempts, err := s.kv.Incr(ctx, attemptsKey(id), s.cfg.ChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	var failures int64
+	if s.cfg.MaxFailures > 0 {
+		if failures, err = s.kv.Incr(ctx, failuresKey(c.Tenant, c.UserID), s.cfg.FailureWindow); err != nil {
+			return nil, err
+		}
+	}
+	if attempts > int64(s.cfg.MaxAttempts) || failures > int64(s.cfg.MaxFailures) {
+		return nil, s.endChallenge(ctx, id)
+	}
+
+	c.Factor, err = s.Verify(ctx, c.Tenant, c.UserID, code)
+	if errors.Is(err, ErrInvalidCode) {
+		if attempts == int64(s.cfg.MaxAttempts) || (s.cfg.MaxFailures > 0 && failures == int64(s.cfg.MaxFailures)) {
+			return nil, s.endChallenge(ctx, id)
+		}
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Take hands the challenge to one of concurrent answers only.
+	if _, err := s.kv.Take(ctx, challengeKey(id)); errors.Is(err, oauth.ErrKeyNotFound) {
+		return nil, ErrChallengeNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if s.cfg.MaxFailures > 0 {
+		if _, err := s.kv.Take(ctx, failuresKey(c.Tenant, c.UserID)); err != nil && !errors.Is(err, oauth.ErrKeyNotFound) {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// endChallenge ends the challenge with the given ID after too many wrong
+// codes.
+func (s *Service) endChallenge(ctx context.Context, id string) error {
+	if _, err := s.kv.Take(ctx, challengeKey(id)); err != nil && !errors.Is(err, oauth.ErrKeyNotFound) {
+		return err
+	}
+	return ErrTooManyAttempts
+}
+
+func challengeKey(id string) string {
+	return "mfa:" + id
+}
+
+func attemptsKey(id string) string {
+	return "mfa:" + id + ":attempts"
+}
+
+// failuresKey names the count of codes the user with the given ID entered
+// at tenant since the last right one.
+func failuresKey(tenant, userID string) string {
+	return "mfa:failures:" + tenant + ":" + userID
+}
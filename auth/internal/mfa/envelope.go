@@ -0,0 +1,85 @@
+package mfa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
+)
+
+// purposeTOTP tells TOTP secrets apart from other sealed values in the AAD.
+const purposeTOTP = "totp"
+
+// secretAAD is the associated data bound to a sealed TOTP secret, as
+// envelopeAAD is to private keys in jwk_keys, so a secret cannot be moved to
+// another user or KEK without failing authentication.
+type secretAAD struct {
+	Version int    `json:"v"`
+	Suite   string `json:"suite"`
+	Purpose string `json:"purpose"`
+	UserID  string `json:"user_id"`
+	KEKRef  string `json:"kek_ref"`
+}
+
+type sealedSecret struct {
+	ciphertext []byte
+	wrappedDEK []byte
+	kekRef     string
+}
+
+// sealSecret encrypts the TOTP secret of userID under a fresh DEK, with the
+// DEK wrapped by the service's KeyWrapper.
+func (s *Service) sealSecret(ctx context.Context, userID string, secret []byte) (sealedSecret, error) {
+	dek, err := envelope.NewDEK()
+	if err != nil {
+		return sealedSecret{}, err
+	}
+	wrapped, kekRef, err := s.wrapper.Wrap(ctx, dek)
+	if err != nil {
+		return sealedSecret{}, err
+	}
+
+	aad, err := json.Marshal(secretAAD{
+		Version: envelope.CurrentVersion,
+		Suite:   s.suite,
+		Purpose: purposeTOTP,
+		UserID:  userID,
+		KEKRef:  kekRef,
+	})
+	if err != nil {
+		return sealedSecret{}, err
+	}
+	ct, err := envelope.Seal(s.suite, dek, secret, aad)
+	if err != nil {
+		return sealedSecret{}, err
+	}
+	return sealedSecret{ciphertext: ct, wrappedDEK: wrapped, kekRef: kekRef}, nil
+}
+
+// openSecret decrypts the TOTP secret of userID.
+func (s *Service) openSecret(ctx context.Context, version int16, userID string, sealed sealedSecret) ([]byte, error) {
+	if version != envelope.Version1 && version != envelope.Version2 {
+		return nil, fmt.Errorf("mfa: unknown envelope version %d", version)
+	}
+	dek, err := s.wrapper.Unwrap(ctx, sealed.wrappedDEK, sealed.kekRef)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := envelope.ParseHeader(sealed.ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := json.Marshal(secretAAD{
+		Version: h.Version,
+		Suite:   h.Suite,
+		Purpose: purposeTOTP,
+		UserID:  userID,
+		KEKRef:  sealed.kekRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Open(dek, sealed.ciphertext, aad)
+}
@@ -0,0 +1,361 @@
+// Package mfa adds a second factor to password sign-ins: TOTP authenticators
+// (RFC 6238) with their secrets sealed like signing keys, and single-use
+// recovery codes for users who lost theirs.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/envelope"
+)
+
+// recoveryCodeSize is the number of random bytes in a recovery code, enough
+// that the unsalted SHA-256 stored of it cannot be reversed.
+const recoveryCodeSize = 10
+
+// Second factors a code may be.
+const (
+	FactorTOTP         = "totp"
+	FactorRecoveryCode = "recovery_code"
+)
+
+var (
+	ErrUserNotFound       = errors.New("mfa: user not found")
+	ErrAlreadyEnrolled    = errors.New("mfa: user already has a confirmed authenticator")
+	ErrNotEnrolled        = errors.New("mfa: user has no confirmed authenticator")
+	ErrNoPendingEnrolment = errors.New("mfa: no authenticator is waiting for confirmation")
+	ErrInvalidCode        = errors.New("mfa: invalid or already used code")
+)
+
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+type Config struct {
+	// Issuer names the service next to the username in authenticator apps.
+	Issuer string `env:"ISSUER" envDefault:"authd"`
+	// Skew is how many time steps before and after the current one codes
+	// are still accepted from, for clocks that drift.
+	Skew int `env:"SKEW" envDefault:"1"`
+	// RecoveryCodes is how many recovery codes a user is given.
+	RecoveryCodes int `env:"RECOVERY_CODES" envDefault:"10"`
+	// ChallengeTTL is how long a user has to enter a code after the
+	// password.
+	ChallengeTTL time.Duration `env:"CHALLENGE_TTL" envDefault:"5m"`
+	// MaxAttempts is how many wrong codes end a challenge, after which the
+	// user starts over with the password.
+	MaxAttempts int `env:"MAX_ATTEMPTS" envDefault:"5"`
+	// MaxFailures is how many codes a user may enter across challenges
+	// without a right one, within FailureWindow of the first. Later
+	// challenges end without their codes being checked until the window
+	// is over. Zero disables the limit.
+	MaxFailures   int           `env:"MAX_FAILURES" envDefault:"20"`
+	FailureWindow time.Duration `env:"FAILURE_WINDOW" envDefault:"15m"`
+}
+
+// Enrolment is a new authenticator, to be confirmed with a code from it.
+type Enrolment struct {
+	// Secret is the base32 shared secret, for apps that cannot read URI.
+	Secret string `json:"secret"`
+	// URI is the otpauth URI to show as a QR code.
+	URI string `json:"otpauth_uri"`
+}
+
+// Status tells what second factors a user has.
+type Status struct {
+	TOTP          bool  `json:"totp"`
+	RecoveryCodes int64 `json:"recovery_codes"`
+}
+
+type Service struct {
+	cfg     Config
+	q       db.Querier
+	wrapper envelope.KeyWrapper
+	suite   string
+	kv      oauth.KVStore
+	now     func() time.Time
+}
+
+// NewService returns a service sealing TOTP secrets with wrapper and keeping
+// sign-in challenges in kv.
+func NewService(cfg Config, q db.Querier, wrapper envelope.KeyWrapper, kv oauth.KVStore) *Service {
+	return &Service{
+		cfg:     cfg,
+		q:       q,
+		wrapper: wrapper,
+		suite:   envelope.DefaultSuite,
+		kv:      kv,
+		now:     time.Now,
+	}
+}
+
+// Enroll creates a TOTP secret for the user of tenant with the given ID,
+// replacing any not yet confirmed. The user is not asked for codes until
+// Confirm. Like every method taking a tenant, it only finds users of that
+// tenant.
+func (s *Service) Enroll(ctx context.Context, tenant, userID string) (*Enrolment, error) {
+	u, err := s.q.GetUser(ctx, db.GetUserParams{ID: userID, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	sealed, err := s.sealSecret(ctx, u.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.q.CreateTOTP(ctx, db.CreateTOTPParams{
+		UserID:           u.ID,
+		SecretCiphertext: sealed.ciphertext,
+		WrappedDEK:       sealed.wrappedDEK,
+		KEKRef:           sealed.kekRef,
+		EnvelopeVersion:  envelope.CurrentVersion,
+		Tenant:           tenant,
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	return &Enrolment{
+		Secret: secretEncoding.EncodeToString(secret),
+		URI:    keyURI(s.cfg.Issuer, u.Username, secret),
+	}, nil
+}
+
+// Confirm turns on the authenticator of the user with the given ID once
+// code shows it was set up, and returns the user's new recovery codes.
+func (s *Service) Confirm(ctx context.Context, tenant, userID, code string) ([]string, error) {
+	row, err := s.q.GetTOTP(ctx, db.GetTOTPParams{UserID: userID, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoPendingEnrolment
+	}
+	if err != nil {
+		return nil, err
+	}
+	if row.ConfirmedAt.Valid {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	secret, err := s.openSecret(ctx, row.EnvelopeVersion, userID, sealedSecret{
+		ciphertext: row.SecretCiphertext,
+		wrappedDEK: row.WrappedDEK,
+		kekRef:     row.KEKRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchStep(secret, normalizeCode(code), s.now(), s.cfg.Skew, 0)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	n, err := s.q.ConfirmTOTP(ctx, db.ConfirmTOTPParams{UserID: userID, LastStep: step, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	return s.replaceRecoveryCodes(ctx, tenant, userID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user with the
+// given ID, used or not.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, tenant, userID string) ([]string, error) {
+	enrolled, err := s.Enrolled(ctx, tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enrolled {
+		return nil, ErrNotEnrolled
+	}
+	return s.replaceRecoveryCodes(ctx, tenant, userID)
+}
+
+// Disable removes the authenticator and recovery codes of the user with the
+// given ID, who then signs in with a password alone.
+func (s *Service) Disable(ctx context.Context, tenant, userID string) error {
+	n, err := s.q.DeleteTOTP(ctx, db.DeleteTOTPParams{UserID: userID, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	if err := s.q.DeleteRecoveryCodes(ctx, db.DeleteRecoveryCodesParams{UserID: userID, Tenant: tenant}); err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotEnrolled
+	}
+	return nil
+}
+
+// Enrolled reports whether the user with the given ID has a confirmed
+// authenticator and so must enter a code to sign in.
+func (s *Service) Enrolled(ctx context.Context, tenant, userID string) (bool, error) {
+	row, err := s.q.GetTOTP(ctx, db.GetTOTPParams{UserID: userID, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return row.ConfirmedAt.Valid, nil
+}
+
+// Status returns the second factors of the user with the given ID.
+func (s *Service) Status(ctx context.Context, tenant, userID string) (*Status, error) {
+	if _, err := s.q.GetUser(ctx, db.GetUserParams{ID: userID, Tenant: tenant}); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	enrolled, err := s.Enrolled(ctx, tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.q.CountRecoveryCodes(ctx, db.CountRecoveryCodesParams{UserID: userID, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	return &Status{TOTP: enrolled, RecoveryCodes: n}, nil
+}
+
+// Verify checks code, a TOTP code or a recovery code, for the user with the
+// given ID and uses it up: a TOTP code, and any earlier one, is not accepted
+// again, and a recovery code not at all. It returns which factor the code
+// was, FactorTOTP or FactorRecoveryCode.
+func (s *Service) Verify(ctx context.Context, tenant, userID, code string) (string, error) {
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		if err := s.useRecoveryCode(ctx, tenant, userID, code); err != nil {
+			return "", err
+		}
+		return FactorRecoveryCode, nil
+	}
+	if err := s.useTOTPCode(ctx, tenant, userID, code); err != nil {
+		return "", err
+	}
+	return FactorTOTP, nil
+}
+
+func (s *Service) useTOTPCode(ctx context.Context, tenant, userID, code string) error {
+	row, err := s.q.GetTOTP(ctx, db.GetTOTPParams{UserID: userID, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !row.ConfirmedAt.Valid) {
+		return ErrNotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+	secret, err := s.openSecret(ctx, row.EnvelopeVersion, userID, sealedSecret{
+		ciphertext: row.SecretCiphertext,
+		wrappedDEK: row.WrappedDEK,
+		kekRef:     row.KEKRef,
+	})
+	if err != nil {
+		return err
+	}
+	step, ok := matchStep(secret, code, s.now(), s.cfg.Skew, row.LastStep)
+	if !ok {
+		return ErrInvalidCode
+	}
+	// Only one of concurrent sign-ins with the same code moves last_step.
+	n, err := s.q.UseTOTPStep(ctx, db.UseTOTPStepParams{UserID: userID, LastStep: step, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *Service) useRecoveryCode(ctx context.Context, tenant, userID, code string) error {
+	if code == "" {
+		return ErrInvalidCode
+	}
+	n, err := s.q.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hashRecoveryCode(code),
+		Tenant:   tenant,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, tenant, userID string) ([]string, error) {
+	codes := make([]string, s.cfg.RecoveryCodes)
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := recoveryEncoding.EncodeToString(b)
+		codes[i] = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	if err := s.q.ReplaceRecoveryCodes(ctx, db.ReplaceRecoveryCodesParams{
+		UserID:     userID,
+		Tenant:     tenant,
+		CodeHashes: hashes,
+	}); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeCode drops the spaces and dashes users type codes with, and
+// lowercases recovery codes.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// hashRecoveryCode returns the base64url SHA-256 of a normalized recovery
+// code.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
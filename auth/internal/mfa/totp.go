@@ -0,0 +1,81 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). They are the defaults of authenticator apps,
+// some of which ignore any others.
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	totpAlg    = "SHA1"
+	// secretSize is the size of a shared secret, that of an HMAC-SHA1
+	// output (RFC 4226 section 4).
+	secretSize = 20
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpStep returns the time step t falls in.
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// totpCode returns the code for step (RFC 4226 section 5.3).
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1_000_000)
+}
+
+// matchStep returns the earliest step within skew steps of now, and after
+// last, that code is for.
+func matchStep(secret []byte, code string, now time.Time, skew int, last int64) (int64, bool) {
+	current := totpStep(now)
+	for step := current - int64(skew); step <= current+int64(skew); step++ {
+		if step <= last {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// keyURI returns the otpauth URI authenticator apps read secret from,
+// usually as a QR code, labelled with issuer and the account name
+// (https://github.com/google/google-authenticator/wiki/Key-Uri-Format).
+func keyURI(issuer, account string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", secretEncoding.EncodeToString(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", totpAlg)
+	q.Set("digits", strconv.Itoa(totpDigits))
+	q.Set("period", strconv.Itoa(totpPeriod))
+	// Some apps show a + in the issuer literally.
+	query := strings.ReplaceAll(q.Encode(), "+", "%20")
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query,
+	}
+	return u.String()
+}
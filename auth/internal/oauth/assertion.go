@@ -0,0 +1,72 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+const (
+	ClientAssertionTypeJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// maxAssertionLifetime bounds exp so replay cache entries stay short.
+	maxAssertionLifetime = time.Hour
+	assertionLeeway      = 30 * time.Second
+)
+
+var (
+	errAssertionAudience = errors.New("assertion audience does not name this server")
+	errAssertionLifetime = errors.New("assertion expires too far in the future")
+	errAssertionJTI      = errors.New("assertion has no jti")
+	errAssertionReplay   = errors.New("assertion was already used")
+	errAssertionSubject  = errors.New("assertion sub does not match")
+)
+
+// verifyAssertion validates a JWT assertion (RFC 7523 section 3) signed by a
+// key from keys and issued by iss, and records its jti so it cannot be used
+// twice.
+func (s *Service) verifyAssertion(ctx context.Context, assertion string, keys verify.KeySource, iss string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	if err := verify.ParseSigned(ctx, assertion, keys, claims,
+		jwt.WithIssuer(iss),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(assertionLeeway),
+		jwt.WithTimeFunc(s.now),
+	); err != nil {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(claims.Audience, s.isOwnAudience) {
+		return nil, errAssertionAudience
+	}
+	ttl := claims.ExpiresAt.Sub(s.now())
+	if ttl > maxAssertionLifetime {
+		return nil, errAssertionLifetime
+	}
+	if claims.ID == "" {
+		return nil, errAssertionJTI
+	}
+
+	fresh, err := s.replay.Remember(ctx, "assertion:"+iss+":"+claims.ID, ttl+assertionLeeway)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, errAssertionReplay
+	}
+
+	return claims, nil
+}
+
+// isOwnAudience reports whether aud identifies this authorization server: its
+// token endpoint, base URL or issuer.
+func (s *Service) isOwnAudience(aud string) bool {
+	return aud == s.TokenEndpoint() ||
+		aud == strings.TrimRight(s.cfg.BaseURL, "/") ||
+		aud == s.mgr.Issuer
+}
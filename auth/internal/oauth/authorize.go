@@ -0,0 +1,442 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	GrantAuthorizationCode = "authorization_code"
+
+	AuthorizePath = "/oauth/authorize"
+
+	CodeChallengeS256 = "S256"
+
+	minCodeChallengeLength = 43
+	maxCodeChallengeLength = 128
+)
+
+// Prompt values (OpenID Connect Core 1.0 section 3.1.2.1).
+const (
+	PromptNone          = "none"
+	PromptLogin         = "login"
+	PromptConsent       = "consent"
+	PromptSelectAccount = "select_account"
+)
+
+var promptValues = []string{PromptNone, PromptLogin, PromptConsent, PromptSelectAccount}
+
+var ErrAuthorizationNotFound = errors.New("oauth: authorization request not found or expired")
+
+// AuthorizationRequest is a validated authorization request (RFC 6749
+// section 4.1.1) with its PKCE challenge (RFC 7636).
+type AuthorizationRequest struct {
+	ClientID      string   `json:"client_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	State         string   `json:"state,omitempty"`
+	Nonce         string   `json:"nonce,omitempty"`
+	CodeChallenge string   `json:"code_challenge"`
+	Resources     []string `json:"resources,omitempty"`
+	Prompt        []string `json:"prompt,omitempty"`
+	// MaxAge is the age in seconds past which the user must sign in again.
+	MaxAge *int64 `json:"max_age,omitempty"`
+}
+
+// acceptsSession reports whether the user's existing sign-in a may serve
+// the request, which prompt=login and max_age can rule out.
+func (r *AuthorizationRequest) acceptsSession(a *Authentication, now time.Time) bool {
+	if a == nil || slices.Contains(r.Prompt, PromptLogin) {
+		return false
+	}
+	return r.MaxAge == nil || now.Sub(a.AuthTime) <= time.Duration(*r.MaxAge)*time.Second
+}
+
+// Authentication is how and when the user behind a grant signed in.
+type Authentication struct {
+	Subject  string    `json:"sub"`
+	AuthTime time.Time `json:"auth_time"`
+	// AMR are the authentication methods used (RFC 8176).
+	AMR []string `json:"amr,omitempty"`
+	ACR string   `json:"acr,omitempty"`
+	// SID identifies the browser session the user signed in with.
+	SID string `json:"sid,omitempty"`
+}
+
+// authorizationCode is what an issued code stands for.
+type authorizationCode struct {
+	AuthorizationRequest
+	Authentication
+}
+
+// PendingAuthorization is a validated authorization request waiting for the
+// user to sign in and decide.
+type PendingAuthorization struct {
+	ID         string
+	Request    AuthorizationRequest
+	ClientName string
+	// Session is the user's existing sign-in when the request accepts it,
+	// so the user only has to decide.
+	Session *Authentication
+	// Redirect is set instead of ID when the request was completed without
+	// the user (prompt=none), and is where to send the user agent.
+	Redirect string
+}
+
+// RedirectError is an authorization error reported to the client by
+// redirecting the user agent back to it (RFC 6749 section 4.1.2.1). Errors
+// found before the redirect URI is validated are plain *Error values and
+// must be shown to the user instead.
+type RedirectError struct {
+	Err         *Error
+	RedirectURI string
+	State       string
+}
+
+func (e *RedirectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
+
+// Location returns the redirect URL carrying the error.
+func (e *RedirectError) Location(iss string) string {
+	params := url.Values{"error": {e.Err.Code}}
+	if e.Err.Description != "" {
+		params.Set("error_description", e.Err.Description)
+	}
+	return redirectLocation(e.RedirectURI, e.State, iss, params)
+}
+
+// Authorize validates an authorization request from the user agent, which may
+// refer to a pushed request (request_uri) or carry a request object
+// (request), and starts a pending sign-in for it. session is the user's
+// existing sign-in, if any; prompt=none requests are completed with it right
+// away and fail with login_required without it.
+func (s *Service) Authorize(ctx context.Context, params url.Values, session *Authentication) (*PendingAuthorization, error) {
+	clientID := params.Get("client_id")
+	if clientID == "" {
+		return nil, invalidRequest("client_id is required")
+	}
+	client, _, err := s.loadClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var req *AuthorizationRequest
+	if requestURI := params.Get("request_uri"); requestURI != "" {
+		if params.Has("request") {
+			return nil, invalidRequest("request and request_uri are mutually exclusive")
+		}
+		if req, err = s.takePushedRequest(ctx, requestURI, client.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		if client.RequirePAR {
+			return nil, invalidRequest("this client must use pushed authorization requests")
+		}
+		if request := params.Get("request"); request != "" {
+			if params, err = s.requestObjectParams(ctx, client, request); err != nil {
+				return nil, err
+			}
+		}
+		if req, err = s.validateAuthorizationRequest(client, params); err != nil {
+			return nil, err
+		}
+	}
+
+	if !req.acceptsSession(session, s.now()) {
+		session = nil
+	}
+	if slices.Contains(req.Prompt, PromptNone) {
+		if session == nil {
+			return nil, &RedirectError{
+				Err:         newError(http.StatusBadRequest, CodeLoginRequired, "the user is not signed in", nil),
+				RedirectURI: req.RedirectURI,
+				State:       req.State,
+			}
+		}
+		location, err := s.issueCode(ctx, req, *session)
+		if err != nil {
+			return nil, serverError(err)
+		}
+		return &PendingAuthorization{Request: *req, ClientName: client.Name, Session: session, Redirect: location}, nil
+	}
+
+	id, err := randomToken(24)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	if err := s.kv.Put(ctx, pendingKey(s.tenant, id), raw, s.cfg.Authorize.LoginTTL); err != nil {
+		return nil, serverError(err)
+	}
+
+	return &PendingAuthorization{ID: id, Request: *req, ClientName: client.Name, Session: session}, nil
+}
+
+// PendingAuthorization returns a pending sign-in started by Authorize, or
+// ErrAuthorizationNotFound. session is the user's existing sign-in, as for
+// Authorize.
+func (s *Service) PendingAuthorization(ctx context.Context, id string, session *Authentication) (*PendingAuthorization, error) {
+	raw, err := s.kv.Get(ctx, pendingKey(s.tenant, id))
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrAuthorizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var req AuthorizationRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, err
+	}
+
+	client, _, err := s.loadClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if !req.acceptsSession(session, s.now()) {
+		session = nil
+	}
+	return &PendingAuthorization{ID: id, Request: req, ClientName: client.Name, Session: session}, nil
+}
+
+// CompleteAuthorization ends a pending sign-in with the decision of the user
+// who signed in as described by authn and returns where to redirect the user
+// agent.
+func (s *Service) CompleteAuthorization(ctx context.Context, id string, authn Authentication, approve bool) (string, error) {
+	raw, err := s.kv.Take(ctx, pendingKey(s.tenant, id))
+	if errors.Is(err, ErrKeyNotFound) {
+		return "", ErrAuthorizationNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	var req AuthorizationRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return "", err
+	}
+
+	if !approve {
+		denied := &RedirectError{
+			Err:         newError(http.StatusBadRequest, CodeAccessDenied, "the user denied the request", nil),
+			RedirectURI: req.RedirectURI,
+			State:       req.State,
+		}
+		return denied.Location(s.mgr.Issuer), nil
+	}
+	return s.issueCode(ctx, &req, authn)
+}
+
+// issueCode issues a code for req to the user authn describes and returns
+// the redirect carrying it.
+func (s *Service) issueCode(ctx context.Context, req *AuthorizationRequest, authn Authentication) (string, error) {
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(authorizationCode{
+		AuthorizationRequest: *req,
+		Authentication:       authn,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.kv.Put(ctx, codeKey(s.tenant, code), raw, s.cfg.Authorize.CodeTTL); err != nil {
+		return "", err
+	}
+
+	return redirectLocation(req.RedirectURI, req.State, s.mgr.Issuer, url.Values{"code": {code}}), nil
+}
+
+func (s *Service) validateAuthorizationRequest(client *Client, params url.Values) (*AuthorizationRequest, error) {
+	if params.Get("client_id") != client.ID {
+		return nil, invalidRequest("client_id does not match")
+	}
+	redirectURI, err := resolveRedirectURI(client, params.Get("redirect_uri"))
+	if err != nil {
+		return nil, err
+	}
+
+	state := params.Get("state")
+	fail := func(e *Error) error {
+		return &RedirectError{Err: e, RedirectURI: redirectURI, State: state}
+	}
+
+	if params.Get("response_type") != "code" {
+		return nil, fail(newError(http.StatusBadRequest, CodeUnsupportedResponseType, "response_type must be code", nil))
+	}
+	if !client.AllowsGrant(GrantAuthorizationCode) {
+		return nil, fail(newError(http.StatusBadRequest, CodeUnauthorizedClient, "grant type not allowed for this client", nil))
+	}
+	scopes, err := grantScopes(client, params.Get("scope"))
+	if err != nil {
+		var oerr *Error
+		errors.As(err, &oerr)
+		return nil, fail(oerr)
+	}
+
+	resources := params["resource"]
+	if err := checkResources(client, resources); err != nil {
+		var oerr *Error
+		errors.As(err, &oerr)
+		return nil, fail(oerr)
+	}
+
+	var prompt []string
+	if p := params.Get("prompt"); p != "" {
+		prompt = strings.Fields(p)
+		for _, v := range prompt {
+			if !slices.Contains(promptValues, v) {
+				return nil, fail(invalidRequest("unsupported prompt value " + v))
+			}
+		}
+		if slices.Contains(prompt, PromptNone) && len(prompt) > 1 {
+			return nil, fail(invalidRequest("prompt=none cannot be combined with other values"))
+		}
+	}
+	var maxAge *int64
+	if v := params.Get("max_age"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fail(invalidRequest("max_age must be a non-negative number of seconds"))
+		}
+		maxAge = &n
+	}
+
+	challenge := params.Get("code_challenge")
+	if len(challenge) < minCodeChallengeLength || len(challenge) > maxCodeChallengeLength {
+		return nil, fail(invalidRequest("a PKCE code_challenge is required"))
+	}
+	if params.Get("code_challenge_method") != CodeChallengeS256 {
+		return nil, fail(invalidRequest("code_challenge_method must be S256"))
+	}
+
+	return &AuthorizationRequest{
+		ClientID:      client.ID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		State:         state,
+		Nonce:         params.Get("nonce"),
+		CodeChallenge: challenge,
+		Resources:     resources,
+		Prompt:        prompt,
+		MaxAge:        maxAge,
+	}, nil
+}
+
+// resolveRedirectURI returns the registered redirect URI the request asked
+// for, or the only registered one when it asked for none. URIs are compared
+// as exact strings.
+func resolveRedirectURI(client *Client, uri string) (string, error) {
+	if uri == "" {
+		if len(client.RedirectURIs) == 1 {
+			return client.RedirectURIs[0], nil
+		}
+		return "", invalidRequest("redirect_uri is required")
+	}
+	if !slices.Contains(client.RedirectURIs, uri) {
+		return "", invalidRequest("redirect_uri is not registered for this client")
+	}
+	return uri, nil
+}
+
+// authorizationCodeGrant redeems a code issued by CompleteAuthorization.
+func (s *Service) authorizationCodeGrant(ctx context.Context, client *Client, req TokenRequest) (*grant, error) {
+	if req.Code == "" {
+		return nil, invalidRequest("code is required")
+	}
+
+	raw, err := s.kv.Take(ctx, codeKey(s.tenant, req.Code))
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, invalidGrant(errors.New("unknown or expired code"))
+	}
+	if err != nil {
+		return nil, serverError(err)
+	}
+	var code authorizationCode
+	if err := json.Unmarshal(raw, &code); err != nil {
+		return nil, serverError(err)
+	}
+
+	if code.ClientID != client.ID {
+		return nil, invalidGrant(errors.New("code was issued to another client"))
+	}
+	if req.RedirectURI != "" && req.RedirectURI != code.RedirectURI {
+		return nil, invalidGrant(errors.New("redirect_uri does not match"))
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, invalidGrant(errors.New("code_verifier does not match"))
+	}
+
+	return &grant{
+		sub:      code.Subject,
+		scopes:   code.Scopes,
+		aud:      code.Resources,
+		authTime: code.AuthTime,
+		amr:      code.AMR,
+		acr:      code.ACR,
+		sid:      code.SID,
+	}, nil
+}
+
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if verifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// redirectLocation adds params, state and iss (RFC 9207) to redirectURI.
+func redirectLocation(redirectURI, state, iss string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	q.Set("iss", iss)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func pendingKey(tenant, id string) string {
+	return tenantKey(tenant, "authz:"+id)
+}
+
+func codeKey(tenant, code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return tenantKey(tenant, "code:"+base64.RawURLEncoding.EncodeToString(sum[:]))
+}
+
+// tenantKey scopes key in the KV store to tenant, so requests, codes and
+// request URIs issued by one issuer are unknown to the others. Keys of the
+// root issuer keep their unscoped form.
+func tenantKey(tenant, key string) string {
+	if tenant == "" {
+		return key
+	}
+	return "t:" + tenant + ":" + key
+}
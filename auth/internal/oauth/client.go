@@ -0,0 +1,252 @@
+package oauth
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Token endpoint authentication methods (RFC 7591 section 2, RFC 7523
+// section 2.2, RFC 8705 section 2).
+const (
+	AuthMethodSecretBasic       = "client_secret_basic"
+	AuthMethodSecretPost        = "client_secret_post"
+	AuthMethodPrivateKeyJWT     = "private_key_jwt"
+	AuthMethodTLSClientAuth     = "tls_client_auth"
+	AuthMethodSelfSignedTLSAuth = "self_signed_tls_client_auth"
+	AuthMethodNone              = "none"
+)
+
+var (
+	errUnknownClient = errors.New("unknown client")
+	errWrongMethod   = errors.New("client used a different authentication method")
+	errWrongSecret   = errors.New("client secret mismatch")
+)
+
+// ClientCredentials is how a client identified itself on a request.
+type ClientCredentials struct {
+	// Basic holds the user-info of an Authorization: Basic header, which RFC
+	// 6749 section 2.3.1 requires to be form-urlencoded.
+	BasicID     string
+	BasicSecret string
+	HasBasic    bool
+
+	// Form holds client_id and client_secret body parameters.
+	FormID     string
+	FormSecret string
+
+	// AssertionType and Assertion are the client_assertion_type and
+	// client_assertion body parameters of private_key_jwt.
+	AssertionType string
+	Assertion     string
+
+	// Certificates is the client certificate chain of a mutual-TLS
+	// connection, leaf first.
+	Certificates []*x509.Certificate
+}
+
+type Client struct {
+	ID                    string
+	Name                  string
+	GrantTypes            []string
+	Scopes                []string
+	AuthMethod            string
+	DPoPBoundAccessTokens bool
+	TLS                   TLSClientAuth
+	// JWKS holds the keys of a private_key_jwt client and the self-signed
+	// certificates of a self_signed_tls_client_auth client (in x5c).
+	JWKS verify.JWKS
+	// JWKSURI is fetched for keys not found in JWKS.
+	JWKSURI string
+	// CertificateBoundAccessTokens binds every token to the client
+	// certificate (RFC 8705 section 3).
+	CertificateBoundAccessTokens bool
+	RedirectURIs                 []string
+	// RequirePAR rejects authorization requests that were not pushed
+	// (RFC 9126 section 6).
+	RequirePAR bool
+	// Resources are the resource indicators the client may request tokens
+	// for (RFC 8707). Tokens requested without one are issued for the
+	// default audience.
+	Resources []string
+	// AccessTokenFormat is jwt or paseto. Settings of the audience take
+	// precedence, see SetAudience.
+	AccessTokenFormat string
+	// Tokens are the token lifetimes and claims an administrator set for
+	// the client; registration cannot change them.
+	Tokens TokenSettings
+	// TokenHookFailure overrides the service's token hook failure policy
+	// when set.
+	TokenHookFailure string
+	// PostLogoutRedirectURIs are where the user agent may be sent after
+	// RP-initiated logout.
+	PostLogoutRedirectURIs []string
+	// BackchannelLogoutURI receives logout tokens, and FrontchannelLogoutURI
+	// is loaded in an iframe, when a session the client was used in ends.
+	BackchannelLogoutURI  string
+	FrontchannelLogoutURI string
+}
+
+// TLSClientAuth is the registered certificate identity of a tls_client_auth
+// client. Exactly one field is expected to be set.
+type TLSClientAuth struct {
+	SubjectDN string
+	SANDNS    string
+	SANURI    string
+	SANIP     string
+	SANEmail  string
+}
+
+func (c *Client) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+func (s *Service) authenticateClient(ctx context.Context, cred ClientCredentials) (*Client, error) {
+	id, secret, method := cred.FormID, cred.FormSecret, AuthMethodNone
+	switch {
+	case cred.HasBasic:
+		if cred.FormSecret != "" {
+			return nil, invalidRequest("multiple client authentication methods")
+		}
+		var err error
+		if id, err = url.QueryUnescape(cred.BasicID); err != nil {
+			return nil, invalidClient(err)
+		}
+		if secret, err = url.QueryUnescape(cred.BasicSecret); err != nil {
+			return nil, invalidClient(err)
+		}
+		method = AuthMethodSecretBasic
+	case cred.FormSecret != "":
+		method = AuthMethodSecretPost
+	}
+	if cred.Assertion != "" {
+		if method != AuthMethodNone {
+			return nil, invalidRequest("multiple client authentication methods")
+		}
+		if cred.AssertionType != ClientAssertionTypeJWT {
+			return nil, invalidRequest("unsupported client_assertion_type")
+		}
+		method = AuthMethodPrivateKeyJWT
+		if id == "" {
+			// client_id is optional with an assertion, whose iss names the
+			// client (RFC 7523 section 3).
+			var claims jwt.RegisteredClaims
+			if _, _, err := jwt.NewParser().ParseUnverified(cred.Assertion, &claims); err != nil {
+				return nil, invalidClient(err)
+			}
+			id = claims.Issuer
+		}
+	}
+	if id == "" {
+		return nil, invalidClient(errUnknownClient)
+	}
+
+	client, secretHash, err := s.loadClient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch client.AuthMethod {
+	case AuthMethodSecretBasic, AuthMethodSecretPost:
+		if method != client.AuthMethod || secretHash == "" {
+			return nil, invalidClient(errWrongMethod)
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(secret)); err != nil {
+			return nil, invalidClient(errWrongSecret)
+		}
+	case AuthMethodPrivateKeyJWT:
+		if method != client.AuthMethod {
+			return nil, invalidClient(errWrongMethod)
+		}
+		claims, err := s.verifyAssertion(ctx, cred.Assertion, s.clientKeys(client.JWKS, client.JWKSURI), client.ID)
+		if err != nil {
+			return nil, invalidClient(err)
+		}
+		if claims.Subject != client.ID {
+			return nil, invalidClient(errAssertionSubject)
+		}
+	case AuthMethodTLSClientAuth, AuthMethodSelfSignedTLSAuth:
+		if method != AuthMethodNone {
+			return nil, invalidClient(errWrongMethod)
+		}
+		if err := s.authenticateTLSClient(client, cred.Certificates); err != nil {
+			return nil, invalidClient(err)
+		}
+	case AuthMethodNone:
+		if method != AuthMethodNone {
+			return nil, invalidClient(errWrongMethod)
+		}
+	default:
+		return nil, invalidClient(errWrongMethod)
+	}
+
+	return client, nil
+}
+
+// loadClient returns the registered client and its secret hash, if any.
+func (s *Service) loadClient(ctx context.Context, id string) (*Client, string, error) {
+	row, err := s.q.GetClient(ctx, db.GetClientParams{
+		ClientID: id,
+		Tenant:   s.tenant,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", invalidClient(errUnknownClient)
+	}
+	if err != nil {
+		return nil, "", serverError(err)
+	}
+
+	client, err := clientFromRow(row)
+	if err != nil {
+		return nil, "", serverError(err)
+	}
+	return client, row.ClientSecretHash.String, nil
+}
+
+func clientFromRow(row db.OauthClient) (*Client, error) {
+	c := &Client{
+		ID:                    row.ClientID,
+		Name:                  row.Name,
+		GrantTypes:            row.GrantTypes,
+		Scopes:                row.Scopes,
+		AuthMethod:            row.TokenEndpointAuthMethod,
+		DPoPBoundAccessTokens: row.DPoPBoundAccessTokens,
+		TLS: TLSClientAuth{
+			SubjectDN: row.TLSClientAuthSubjectDN.String,
+			SANDNS:    row.TLSClientAuthSANDNS.String,
+			SANURI:    row.TLSClientAuthSANURI.String,
+			SANIP:     row.TLSClientAuthSANIP.String,
+			SANEmail:  row.TLSClientAuthSANEmail.String,
+		},
+		CertificateBoundAccessTokens: row.TLSClientCertificateBoundAccessTokens,
+		JWKSURI:                      row.JWKSURI.String,
+		RedirectURIs:                 row.RedirectURIs,
+		RequirePAR:                   row.RequirePushedAuthorizationRequests,
+		Resources:                    row.Resources,
+		AccessTokenFormat:            row.AccessTokenFormat,
+		PostLogoutRedirectURIs:       row.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:         row.BackchannelLogoutURI,
+		FrontchannelLogoutURI:        row.FrontchannelLogoutURI,
+	}
+	if len(row.JWKS) > 0 {
+		if err := json.Unmarshal(row.JWKS, &c.JWKS); err != nil {
+			return nil, err
+		}
+	}
+	tokens, err := tokenSettingsFromRow(row.AccessTokenTTL, row.RefreshTokenTTL, row.IDTokenTTL, row.ClaimsTemplate)
+	if err != nil {
+		return nil, err
+	}
+	c.Tokens = *tokens
+	c.TokenHookFailure = row.TokenHookFailure
+	return c, nil
+}
@@ -0,0 +1,73 @@
+package oauth
+
+import "time"
+
+type Config struct {
+	// BaseURL is the public URL of this service. DPoP proofs must name the
+	// token endpoint under it in htu.
+	BaseURL string `env:"BASE_URL" envDefault:"http://localhost:8080"`
+	// Issuer is the iss of tokens of the root issuer; tenants issue as
+	// BaseURL/t/{tenant}. RFC 8414 clients expect it to be BaseURL.
+	Issuer         string        `env:"ISSUER" envDefault:"auth-service"`
+	Audience       string        `env:"AUDIENCE" envDefault:"api"`
+	AccessTokenTTL time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	// RefreshTokenTTL and IDTokenTTL are the defaults for clients and
+	// audiences that do not set their own. They are reserved: the service
+	// issues no refresh or ID tokens yet, so neither is applied.
+	RefreshTokenTTL time.Duration      `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	IDTokenTTL      time.Duration      `env:"ID_TOKEN_TTL" envDefault:"1h"`
+	DPoP            DPoPConfig         `envPrefix:"DPOP_"`
+	MTLS            MTLSConfig         `envPrefix:"MTLS_"`
+	Device          DeviceConfig       `envPrefix:"DEVICE_"`
+	Authorize       AuthorizeConfig    `envPrefix:"AUTHORIZE_"`
+	Registration    RegistrationConfig `envPrefix:"REGISTRATION_"`
+	Logout          LogoutConfig       `envPrefix:"LOGOUT_"`
+}
+
+type DPoPConfig struct {
+	// NonceSecret is the hex encoded HMAC key for server nonces. It must be
+	// shared by every instance; a random key is used when empty.
+	NonceSecret   string        `env:"NONCE_SECRET"`
+	RequireNonce  bool          `env:"REQUIRE_NONCE" envDefault:"true"`
+	NonceLifetime time.Duration `env:"NONCE_LIFETIME" envDefault:"5m"`
+	ProofMaxAge   time.Duration `env:"PROOF_MAX_AGE" envDefault:"5m"`
+}
+
+type MTLSConfig struct {
+	// ClientCAFile holds the PEM CAs trusted for tls_client_auth clients.
+	// The server must request client certificates (SERVER_TLS_CLIENT_AUTH)
+	// for mutual-TLS client authentication to work.
+	ClientCAFile string `env:"CLIENT_CA_FILE"`
+}
+
+type DeviceConfig struct {
+	CodeTTL      time.Duration `env:"CODE_TTL" envDefault:"10m"`
+	PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"5s"`
+}
+
+type AuthorizeConfig struct {
+	CodeTTL time.Duration `env:"CODE_TTL" envDefault:"1m"`
+	// LoginTTL bounds how long the user may take to sign in.
+	LoginTTL      time.Duration `env:"LOGIN_TTL" envDefault:"10m"`
+	RequestURITTL time.Duration `env:"REQUEST_URI_TTL" envDefault:"90s"`
+}
+
+type RegistrationConfig struct {
+	// InitialAccessToken must be presented as a bearer token to register a
+	// client with the root issuer. Registration there is disabled when it
+	// is empty. Tenants have their own, issued through the admin API.
+	InitialAccessToken string `env:"INITIAL_ACCESS_TOKEN"`
+	// AllowedScopes bounds the scopes a client may register.
+	AllowedScopes []string `env:"ALLOWED_SCOPES" envSeparator:","`
+}
+
+type LogoutConfig struct {
+	// Timeout bounds each back-channel logout request.
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"5s"`
+	// TokenTTL is the lifetime of logout tokens, and so how long failed
+	// back-channel deliveries are retried.
+	TokenTTL time.Duration `env:"TOKEN_TTL" envDefault:"1h"`
+	// RetryInterval is how often due retries are looked for and how long
+	// the first retry waits; later ones wait twice as long each.
+	RetryInterval time.Duration `env:"RETRY_INTERVAL" envDefault:"30s"`
+}
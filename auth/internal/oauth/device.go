@@ -0,0 +1,260 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+)
+
+const (
+	GrantDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+	DeviceAuthorizationPath = "/oauth/device_authorization"
+	DeviceVerificationPath  = "/device"
+
+	// userCodeAlphabet has no vowels, so codes cannot spell words, and no
+	// characters that are easily confused (RFC 8628 section 6.1).
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+
+	// slowDownStep is added to the polling interval on every slow_down
+	// (RFC 8628 section 3.5).
+	slowDownStep = 5
+
+	userCodeAttempts = 5
+)
+
+type DeviceAuthorizationRequest struct {
+	Client ClientCredentials
+	Scope  string
+}
+
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorization starts a device authorization grant (RFC 8628 section
+// 3.1).
+func (s *Service) DeviceAuthorization(ctx context.Context, req DeviceAuthorizationRequest) (*DeviceAuthorizationResponse, error) {
+	client, err := s.authenticateClient(ctx, req.Client)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(GrantDeviceCode) {
+		return nil, newError(http.StatusBadRequest, CodeUnauthorizedClient, "grant type not allowed for this client", nil)
+	}
+	scopes, err := grantScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := randomToken(32)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	interval := int(s.cfg.Device.PollInterval.Seconds())
+	d := &DeviceAuthorization{
+		Tenant:         s.tenant,
+		DeviceCodeHash: hashDeviceCode(deviceCode),
+		ClientID:       client.ID,
+		Scopes:         scopes,
+		Status:         DevicePending,
+		Interval:       interval,
+		ExpiresAt:      s.now().Add(s.cfg.Device.CodeTTL),
+	}
+
+	for attempt := 0; ; attempt++ {
+		if d.UserCode, err = newUserCode(); err != nil {
+			return nil, serverError(err)
+		}
+		err = s.devices.Create(ctx, d)
+		if !errors.Is(err, ErrUserCodeExists) || attempt == userCodeAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return nil, serverError(err)
+	}
+
+	verificationURI := strings.TrimRight(s.cfg.BaseURL, "/") + DeviceVerificationPath
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                d.UserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(d.UserCode),
+		ExpiresIn:               int64(s.cfg.Device.CodeTTL.Seconds()),
+		Interval:                interval,
+	}, nil
+}
+
+// DeviceInfo describes a pending device authorization to the approving
+// user.
+type DeviceInfo struct {
+	UserCode   string
+	ClientID   string
+	ClientName string
+	Scopes     []string
+}
+
+// LookupDevice returns the pending device authorization for userCode, or
+// ErrDeviceNotFound.
+func (s *Service) LookupDevice(ctx context.Context, userCode string) (*DeviceInfo, error) {
+	d, err := s.devices.GetByUserCode(ctx, s.tenant, NormalizeUserCode(userCode))
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != DevicePending {
+		return nil, ErrDeviceNotFound
+	}
+
+	row, err := s.q.GetClient(ctx, db.GetClientParams{
+		ClientID: d.ClientID,
+		Tenant:   s.tenant,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// The client was deleted after the device started the flow.
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceInfo{
+		UserCode:   d.UserCode,
+		ClientID:   d.ClientID,
+		ClientName: row.Name,
+		Scopes:     d.Scopes,
+	}, nil
+}
+
+// DecideDevice records the user's decision on a pending device
+// authorization. sub is the approving user.
+func (s *Service) DecideDevice(ctx context.Context, userCode, sub string, approve bool) error {
+	d, err := s.devices.GetByUserCode(ctx, s.tenant, NormalizeUserCode(userCode))
+	if err != nil {
+		return err
+	}
+	if d.Status != DevicePending {
+		return ErrDeviceNotFound
+	}
+
+	d.Status = DeviceDenied
+	if approve {
+		d.Status = DeviceApproved
+		d.Subject = sub
+		d.AuthTime = s.now()
+	}
+	// Only a pending authorization is decided, so of two racing decisions
+	// the second fails.
+	return s.devices.Update(ctx, d, DevicePending)
+}
+
+// deviceCode handles polling for a device authorization (RFC 8628 section
+// 3.4).
+func (s *Service) deviceCode(ctx context.Context, client *Client, req TokenRequest) (*grant, error) {
+	if req.DeviceCode == "" {
+		return nil, invalidRequest("device_code is required")
+	}
+
+	d, err := s.devices.Get(ctx, s.tenant, hashDeviceCode(req.DeviceCode))
+	if errors.Is(err, ErrDeviceNotFound) {
+		return nil, newError(http.StatusBadRequest, CodeExpiredToken, "device_code has expired", nil)
+	}
+	if err != nil {
+		return nil, serverError(err)
+	}
+	if d.ClientID != client.ID {
+		return nil, invalidGrant(errors.New("device_code was issued to another client"))
+	}
+
+	switch d.Status {
+	case DevicePending:
+		now := s.now()
+		code, description := CodeAuthorizationPending, "the user has not yet approved the request"
+		if now.Sub(d.LastPoll) < time.Duration(d.Interval)*time.Second {
+			d.Interval += slowDownStep
+			code, description = CodeSlowDown, "polling too frequently"
+		}
+		d.LastPoll = now
+		// A decision made since d was read wins over the poll, which then
+		// changes nothing; the next poll sees it.
+		if err := s.devices.Update(ctx, d, DevicePending); err != nil && !errors.Is(err, ErrDeviceNotFound) {
+			return nil, serverError(err)
+		}
+		return nil, newError(http.StatusBadRequest, code, description, nil)
+	case DeviceApproved:
+		redeemed, err := s.devices.Delete(ctx, d)
+		if err != nil {
+			return nil, serverError(err)
+		}
+		if !redeemed {
+			return nil, invalidGrant(errors.New("device_code already redeemed"))
+		}
+		return &grant{sub: d.Subject, scopes: d.Scopes, authTime: d.AuthTime}, nil
+	default:
+		if _, err := s.devices.Delete(ctx, d); err != nil {
+			return nil, serverError(err)
+		}
+		return nil, newError(http.StatusBadRequest, CodeAccessDenied, "the user denied the request", nil)
+	}
+}
+
+func hashDeviceCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// newUserCode returns a code such as "BDFG-HJKL".
+func newUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, userCodeLength+1)
+	for i, c := range b {
+		if i == userCodeLength/2 {
+			code = append(code, '-')
+		}
+		// 256 is not a multiple of 20; the bias is acceptable for codes
+		// that live minutes and are rate limited by polling.
+		code = append(code, userCodeAlphabet[int(c)%len(userCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+// NormalizeUserCode upper-cases a user-typed code and drops separators, so
+// "bdfg hjkl" matches "BDFG-HJKL".
+func NormalizeUserCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			b.WriteRune(r)
+		}
+	}
+	s := b.String()
+	if len(s) != userCodeLength {
+		return s
+	}
+	return s[:userCodeLength/2] + "-" + s[userCodeLength/2:]
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,249 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Device authorization states.
+const (
+	DevicePending  = "pending"
+	DeviceApproved = "approved"
+	DeviceDenied   = "denied"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("oauth: device authorization not found")
+	ErrUserCodeExists = errors.New("oauth: user code already in use")
+)
+
+// DeviceAuthorization is a pending device authorization grant (RFC 8628).
+// Only the SHA-256 hash of the device code is stored. Device and user codes
+// are unique per tenant; "" is the root issuer.
+type DeviceAuthorization struct {
+	Tenant         string    `json:"tenant,omitempty"`
+	DeviceCodeHash string    `json:"device_code_hash"`
+	UserCode       string    `json:"user_code"`
+	ClientID       string    `json:"client_id"`
+	Scopes         []string  `json:"scopes"`
+	Status         string    `json:"status"`
+	Subject        string    `json:"sub,omitempty"`
+	AuthTime       time.Time `json:"auth_time,omitzero"`
+	Interval       int       `json:"interval"`
+	LastPoll       time.Time `json:"last_poll"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// DeviceStore keeps device authorizations until they expire or are redeemed.
+type DeviceStore interface {
+	// Create stores d until d.ExpiresAt, failing with ErrUserCodeExists
+	// when its user code is taken in d.Tenant.
+	Create(ctx context.Context, d *DeviceAuthorization) error
+	Get(ctx context.Context, tenant, deviceCodeHash string) (*DeviceAuthorization, error)
+	GetByUserCode(ctx context.Context, tenant, userCode string) (*DeviceAuthorization, error)
+	// Update overwrites d without extending its expiry, provided the
+	// stored authorization is still in state from. Otherwise, or when it
+	// is gone, it fails with ErrDeviceNotFound, so a stale copy never
+	// undoes a decision.
+	Update(ctx context.Context, d *DeviceAuthorization, from string) error
+	// Delete removes d and reports whether this call removed it, so a
+	// device code is redeemed at most once.
+	Delete(ctx context.Context, d *DeviceAuthorization) (bool, error)
+}
+
+// MemoryDeviceStore is a DeviceStore for a single process. Its maps are
+// keyed by deviceKey.
+type MemoryDeviceStore struct {
+	mu     sync.Mutex
+	byHash map[string]DeviceAuthorization
+	byUser map[string]string
+}
+
+func NewMemoryDeviceStore() *MemoryDeviceStore {
+	return &MemoryDeviceStore{
+		byHash: make(map[string]DeviceAuthorization),
+		byUser: make(map[string]string),
+	}
+}
+
+func (m *MemoryDeviceStore) Create(ctx context.Context, d *DeviceAuthorization) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	user := deviceKey(d.Tenant, "user", d.UserCode)
+	if _, ok := m.byUser[user]; ok {
+		return ErrUserCodeExists
+	}
+	m.byHash[deviceKey(d.Tenant, "code", d.DeviceCodeHash)] = *d
+	m.byUser[user] = d.DeviceCodeHash
+	return nil
+}
+
+func (m *MemoryDeviceStore) Get(ctx context.Context, tenant, deviceCodeHash string) (*DeviceAuthorization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	d, ok := m.byHash[deviceKey(tenant, "code", deviceCodeHash)]
+	if !ok {
+		return nil, ErrDeviceNotFound
+	}
+	return &d, nil
+}
+
+func (m *MemoryDeviceStore) GetByUserCode(ctx context.Context, tenant, userCode string) (*DeviceAuthorization, error) {
+	m.mu.Lock()
+	hash, ok := m.byUser[deviceKey(tenant, "user", userCode)]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrDeviceNotFound
+	}
+	return m.Get(ctx, tenant, hash)
+}
+
+func (m *MemoryDeviceStore) Update(ctx context.Context, d *DeviceAuthorization, from string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code := deviceKey(d.Tenant, "code", d.DeviceCodeHash)
+	if stored, ok := m.byHash[code]; !ok || stored.Status != from {
+		return ErrDeviceNotFound
+	}
+	m.byHash[code] = *d
+	return nil
+}
+
+func (m *MemoryDeviceStore) Delete(ctx context.Context, d *DeviceAuthorization) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code := deviceKey(d.Tenant, "code", d.DeviceCodeHash)
+	if _, ok := m.byHash[code]; !ok {
+		return false, nil
+	}
+	delete(m.byHash, code)
+	delete(m.byUser, deviceKey(d.Tenant, "user", d.UserCode))
+	return true, nil
+}
+
+// expire drops expired entries. m.mu must be held.
+func (m *MemoryDeviceStore) expire(now time.Time) {
+	for code, d := range m.byHash {
+		if now.After(d.ExpiresAt) {
+			delete(m.byHash, code)
+			delete(m.byUser, deviceKey(d.Tenant, "user", d.UserCode))
+		}
+	}
+}
+
+// deviceKey names the kind ("code" or "user") entry for value in tenant.
+func deviceKey(tenant, kind, value string) string {
+	return tenantKey(tenant, kind+":"+value)
+}
+
+// RedisDeviceStore is a DeviceStore shared by every instance. Entries expire
+// through Redis TTLs.
+type RedisDeviceStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+var _ DeviceStore = (*RedisDeviceStore)(nil)
+
+func NewRedisDeviceStore(rdb *redis.Client) *RedisDeviceStore {
+	return &RedisDeviceStore{rdb: rdb, prefix: "auth:device:"}
+}
+
+func (r *RedisDeviceStore) codeKey(tenant, hash string) string {
+	return r.prefix + deviceKey(tenant, "code", hash)
+}
+
+func (r *RedisDeviceStore) userKey(tenant, userCode string) string {
+	return r.prefix + deviceKey(tenant, "user", userCode)
+}
+
+func (r *RedisDeviceStore) Create(ctx context.Context, d *DeviceAuthorization) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(d.ExpiresAt)
+
+	ok, err := r.rdb.SetNX(ctx, r.userKey(d.Tenant, d.UserCode), d.DeviceCodeHash, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserCodeExists
+	}
+	return r.rdb.Set(ctx, r.codeKey(d.Tenant, d.DeviceCodeHash), raw, ttl).Err()
+}
+
+func (r *RedisDeviceStore) Get(ctx context.Context, tenant, deviceCodeHash string) (*DeviceAuthorization, error) {
+	raw, err := r.rdb.Get(ctx, r.codeKey(tenant, deviceCodeHash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d DeviceAuthorization
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *RedisDeviceStore) GetByUserCode(ctx context.Context, tenant, userCode string) (*DeviceAuthorization, error) {
+	hash, err := r.rdb.Get(ctx, r.userKey(tenant, userCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, tenant, hash)
+}
+
+// updateScript sets KEYS[1] to ARGV[1], keeping its TTL, if it holds an
+// authorization with status ARGV[2]. It returns 1 when it did.
+var updateScript = redis.NewScript(`
+local raw = redis.call("GET", KEYS[1])
+if not raw or cjson.decode(raw).status ~= ARGV[2] then
+  return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "KEEPTTL")
+return 1
+`)
+
+func (r *RedisDeviceStore) Update(ctx context.Context, d *DeviceAuthorization, from string) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	n, err := updateScript.Run(ctx, r.rdb, []string{r.codeKey(d.Tenant, d.DeviceCodeHash)}, raw, from).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (r *RedisDeviceStore) Delete(ctx context.Context, d *DeviceAuthorization) (bool, error) {
+	n, err := r.rdb.Del(ctx, r.codeKey(d.Tenant, d.DeviceCodeHash)).Result()
+	if err != nil {
+		return false, err
+	}
+	if err := r.rdb.Del(ctx, r.userKey(d.Tenant, d.UserCode)).Err(); err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,76 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error codes from RFC 6749 sections 4.1.2.1 and 5.2, RFC 6750 section 3.1,
+// RFC 7591 section 3.2.2, RFC 8628 section 3.5, RFC 8693 section 2.2.2, RFC
+// 9101 section 6.3, RFC 9126 section 2.3 and RFC 9449 section 12.2.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidClient        = "invalid_client"
+	CodeInvalidGrant         = "invalid_grant"
+	CodeUnauthorizedClient   = "unauthorized_client"
+	CodeUnsupportedGrantType = "unsupported_grant_type"
+	CodeInvalidScope         = "invalid_scope"
+	CodeInvalidTarget        = "invalid_target"
+	CodeInvalidRequestObject = "invalid_request_object"
+	CodeInvalidRequestURI    = "invalid_request_uri"
+	CodeInvalidToken         = "invalid_token"
+
+	CodeInvalidRedirectURI    = "invalid_redirect_uri"
+	CodeInvalidClientMetadata = "invalid_client_metadata"
+
+	CodeUnsupportedResponseType = "unsupported_response_type"
+	CodeAuthorizationPending    = "authorization_pending"
+	CodeSlowDown                = "slow_down"
+	CodeAccessDenied            = "access_denied"
+	CodeLoginRequired           = "login_required"
+	CodeExpiredToken            = "expired_token"
+	CodeInvalidDPoPProof        = "invalid_dpop_proof"
+	CodeUseDPoPNonce            = "use_dpop_nonce"
+	CodeServerError             = "server_error"
+)
+
+// Error is an OAuth error response. Token endpoint errors are rendered as
+// RFC 6749 JSON rather than apperror responses so that standard clients
+// understand them.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+	Err         error  `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Description, e.Err)
+	}
+	return e.Code + ": " + e.Description
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(status int, code, description string, err error) *Error {
+	return &Error{Code: code, Description: description, Status: status, Err: err}
+}
+
+func invalidRequest(description string) *Error {
+	return newError(http.StatusBadRequest, CodeInvalidRequest, description, nil)
+}
+
+func invalidClient(err error) *Error {
+	return newError(http.StatusUnauthorized, CodeInvalidClient, "client authentication failed", err)
+}
+
+func invalidGrant(err error) *Error {
+	return newError(http.StatusBadRequest, CodeInvalidGrant, "invalid grant", err)
+}
+
+func serverError(err error) *Error {
+	return newError(http.StatusInternalServerError, CodeServerError, "internal error", err)
+}
@@ -0,0 +1,154 @@
+package oauth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+const GrantTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Token type identifiers (RFC 8693 section 3).
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// tokenExchange issues a token for the subject of an access token this
+// service issued, to be used by the client (or the actor token's subject) on
+// the subject's behalf. The new token can only narrow the subject token: its
+// scopes and audiences are subsets of the subject token's and it does not
+// outlive it. The subject token must be meant for the client, through its
+// audience or may_act claim, and a sender-constrained one must be exchanged
+// with the same proof of possession, see requireBinding.
+func (s *Service) tokenExchange(ctx context.Context, client *Client, req TokenRequest) (*grant, error) {
+	if req.SubjectToken == "" || req.SubjectTokenType == "" {
+		return nil, invalidRequest("subject_token and subject_token_type are required")
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeAccessToken {
+		return nil, invalidRequest("only access tokens can be requested")
+	}
+	if req.ActorToken == "" && req.ActorTokenType != "" {
+		return nil, invalidRequest("actor_token_type given without actor_token")
+	}
+
+	subject, err := s.verifyExchangeToken(ctx, req.SubjectToken, req.SubjectTokenType)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid subject_token", err)
+	}
+
+	act := &key.Actor{Sub: client.ID, Act: actorChain(subject.Act)}
+	if req.ActorToken != "" {
+		if req.ActorTokenType == "" {
+			return nil, invalidRequest("actor_token_type is required with actor_token")
+		}
+		actor, err := s.verifyExchangeToken(ctx, req.ActorToken, req.ActorTokenType)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid actor_token", err)
+		}
+		act.Sub = actor.Subject
+	}
+	if !slices.Contains(subject.Audience, client.ID) && !mayAct(subject, client.ID, act.Sub) {
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "subject_token is not meant for this client", nil)
+	}
+
+	scopes, err := exchangeScopes(client, subject.Scopes, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	aud := []string(subject.Audience)
+	if len(req.Audience) > 0 {
+		for _, a := range req.Audience {
+			if !slices.Contains(subject.Audience, a) {
+				return nil, newError(http.StatusBadRequest, CodeInvalidTarget, "audience "+a+" is not covered by the subject_token", nil)
+			}
+		}
+		aud = req.Audience
+	}
+
+	g := &grant{
+		sub:             subject.Subject,
+		scopes:          scopes,
+		aud:             aud,
+		act:             act,
+		ttl:             time.Until(subject.ExpiresAt.Time),
+		issuedTokenType: TokenTypeAccessToken,
+		amr:             subject.AMR,
+		acr:             subject.ACR,
+		sid:             subject.SID,
+		subjectCnf:      subject.Cnf,
+	}
+	// Tokens of grants without a user, e.g. client_credentials, have no
+	// auth_time.
+	if subject.AuthTime != nil {
+		g.authTime = subject.AuthTime.Time
+	}
+	return g, nil
+}
+
+// mayAct reports whether the may_act claim (RFC 8693 section 4.4) of
+// subject names the client or the actor.
+func mayAct(subject *verify.Claims, clientID, actor string) bool {
+	claim, _ := subject.Extra["may_act"].(map[string]any)
+	sub, _ := claim["sub"].(string)
+	return sub != "" && (sub == clientID || sub == actor)
+}
+
+// requireBinding checks that a token request exchanging a subject token
+// bound to a DPoP key or client certificate proves possession of it, and
+// binds the new token to the same certificate; cnf already holds the DPoP
+// key of the request.
+func requireBinding(cnf *key.Confirmation, bound *verify.Confirmation, chain []*x509.Certificate) error {
+	if bound.JKT != "" && cnf.JKT != bound.JKT {
+		return newError(http.StatusBadRequest, CodeInvalidRequest, "subject_token is DPoP-bound to another key", nil)
+	}
+	if bound.X5TS256 != "" {
+		if len(chain) == 0 || verify.CertificateThumbprint(chain[0]) != bound.X5TS256 {
+			return newError(http.StatusBadRequest, CodeInvalidRequest, "subject_token is bound to another client certificate", nil)
+		}
+		cnf.X5TS256 = bound.X5TS256
+	}
+	return nil
+}
+
+func (s *Service) verifyExchangeToken(ctx context.Context, token, tokenType string) (*verify.Claims, error) {
+	if tokenType != TokenTypeAccessToken && tokenType != TokenTypeJWT {
+		return nil, verify.ErrInvalidToken
+	}
+	return s.tokens.Verify(ctx, token)
+}
+
+// exchangeScopes returns the requested scopes, or every scope of the subject
+// token the client may use when none were requested.
+func exchangeScopes(client *Client, subjectScopes []string, scope string) ([]string, error) {
+	allowed := make([]string, 0, len(subjectScopes))
+	for _, sc := range subjectScopes {
+		if slices.Contains(client.Scopes, sc) {
+			allowed = append(allowed, sc)
+		}
+	}
+
+	requested := splitScope(scope)
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+	for _, sc := range requested {
+		if !slices.Contains(allowed, sc) {
+			return nil, newError(http.StatusBadRequest, CodeInvalidScope, "scope "+sc+" exceeds the subject_token", nil)
+		}
+	}
+	return requested, nil
+}
+
+func actorChain(a *verify.Actor) *key.Actor {
+	if a == nil {
+		return nil
+	}
+	return &key.Actor{Sub: a.Sub, Act: actorChain(a.Act)}
+}
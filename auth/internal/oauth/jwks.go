@@ -0,0 +1,52 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+const jwksFetchTimeout = 5 * time.Second
+
+var (
+	errJWKSRedirect = errors.New("jwks_uri redirects are not followed")
+	errJWKSAddress  = errors.New("jwks_uri must not be on a loopback, private or link-local address")
+)
+
+// keySet is a verify.KeySource over registered keys, falling back to a
+// remote JWKS for kids it does not know.
+type keySet struct {
+	inline verify.StaticKeys
+	remote *verify.RemoteJWKS
+}
+
+func (k keySet) Key(ctx context.Context, kid string) (verify.JWK, error) {
+	jwk, err := k.inline.Key(ctx, kid)
+	if errors.Is(err, verify.ErrUnknownKID) && k.remote != nil {
+		return k.remote.Key(ctx, kid)
+	}
+	return jwk, err
+}
+
+// clientKeys returns the keys registered inline in jwks or published at
+// jwksURI. Remote sets are cached per URL across requests, and fetched
+// only from public addresses since clients register jwks_uri themselves.
+func (s *Service) clientKeys(jwks verify.JWKS, jwksURI string) verify.KeySource {
+	ks := keySet{inline: verify.StaticKeys(jwks)}
+	if jwksURI == "" {
+		return ks
+	}
+
+	s.jwksMu.Lock()
+	defer s.jwksMu.Unlock()
+
+	remote, ok := s.remoteJWKS[jwksURI]
+	if !ok {
+		remote = verify.NewRemoteJWKS(jwksURI, s.jwksClient, 0)
+		s.remoteJWKS[jwksURI] = remote
+	}
+	ks.remote = remote
+	return ks
+}
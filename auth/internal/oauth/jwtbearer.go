@@ -0,0 +1,72 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+const GrantJWTBearer = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+var errUntrustedIssuer = errors.New("assertion issuer is not trusted")
+
+// jwtBearer issues a token for the subject of an assertion from a trusted
+// federated issuer (RFC 7523 section 2.1).
+func (s *Service) jwtBearer(ctx context.Context, client *Client, req TokenRequest) (*grant, error) {
+	if req.Assertion == "" {
+		return nil, invalidRequest("assertion is required")
+	}
+
+	var unverified jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(req.Assertion, &unverified); err != nil {
+		return nil, invalidGrant(err)
+	}
+
+	issuer, err := s.q.GetTrustedIssuer(ctx, db.GetTrustedIssuerParams{
+		Issuer: unverified.Issuer,
+		Tenant: s.tenant,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, invalidGrant(errUntrustedIssuer)
+	}
+	if err != nil {
+		return nil, serverError(err)
+	}
+	var jwks verify.JWKS
+	if err := json.Unmarshal(issuer.JWKS, &jwks); err != nil {
+		return nil, serverError(err)
+	}
+
+	claims, err := s.verifyAssertion(ctx, req.Assertion, s.clientKeys(jwks, issuer.JWKSURI.String), issuer.Issuer)
+	if err != nil {
+		return nil, invalidGrant(err)
+	}
+	if claims.Subject == "" {
+		return nil, invalidGrant(errAssertionSubject)
+	}
+
+	allowed := make([]string, 0, len(client.Scopes))
+	for _, sc := range client.Scopes {
+		if slices.Contains(issuer.Scopes, sc) {
+			allowed = append(allowed, sc)
+		}
+	}
+	scopes := allowed
+	if requested := splitScope(req.Scope); len(requested) > 0 {
+		for _, sc := range requested {
+			if !slices.Contains(allowed, sc) {
+				return nil, newError(http.StatusBadRequest, CodeInvalidScope, "scope "+sc+" is not allowed for this assertion", nil)
+			}
+		}
+		scopes = requested
+	}
+
+	return &grant{sub: claims.Subject, scopes: scopes}, nil
+}
@@ -0,0 +1,144 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrKeyNotFound = errors.New("oauth: key not found")
+
+// KVStore holds short-lived values such as pushed authorization requests,
+// pending sign-ins and authorization codes.
+type KVStore interface {
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Take returns and deletes the value, so it is handed out at most once.
+	Take(ctx context.Context, key string) ([]byte, error)
+	// Incr adds one to the counter at key and returns the new count. A
+	// counter starts at zero and expires ttl after its first increment.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// MemoryKVStore is a KVStore for a single process.
+type MemoryKVStore struct {
+	mu      sync.Mutex
+	entries map[string]kvEntry
+}
+
+type kvEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func NewMemoryKVStore() *MemoryKVStore {
+	return &MemoryKVStore{entries: make(map[string]kvEntry)}
+}
+
+func (m *MemoryKVStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = kvEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemoryKVStore) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, ErrKeyNotFound
+	}
+	return e.value, nil
+}
+
+func (m *MemoryKVStore) Take(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	delete(m.entries, key)
+	if !ok || time.Now().After(e.expires) {
+		return nil, ErrKeyNotFound
+	}
+	return e.value, nil
+}
+
+func (m *MemoryKVStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	e, ok := m.entries[key]
+	var n int64
+	if ok && !now.After(e.expires) {
+		var err error
+		if n, err = strconv.ParseInt(string(e.value), 10, 64); err != nil {
+			return 0, err
+		}
+	} else {
+		e.expires = now.Add(ttl)
+	}
+	n++
+	e.value = strconv.AppendInt(nil, n, 10)
+	m.entries[key] = e
+	return n, nil
+}
+
+// RedisKVStore is a KVStore shared by every instance.
+type RedisKVStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+var _ KVStore = (*RedisKVStore)(nil)
+
+func NewRedisKVStore(rdb *redis.Client) *RedisKVStore {
+	return &RedisKVStore{rdb: rdb, prefix: "auth:kv:"}
+}
+
+func (r *RedisKVStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.rdb.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+func (r *RedisKVStore) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := r.rdb.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrKeyNotFound
+	}
+	return v, err
+}
+
+func (r *RedisKVStore) Take(ctx context.Context, key string) ([]byte, error) {
+	v, err := r.rdb.GetDel(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrKeyNotFound
+	}
+	return v, err
+}
+
+// incrScript increments KEYS[1] and sets its TTL to ARGV[1] milliseconds
+// when it was created, so a counter never outlives its TTL.
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (r *RedisKVStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(ctx, r.rdb, []string{r.prefix + key}, ttl.Milliseconds()).Int64()
+}
@@ -0,0 +1,355 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+const EndSessionPath = "/oauth/logout"
+
+const (
+	// logoutBatchSize is how many due back-channel deliveries are claimed
+	// at once.
+	logoutBatchSize = 50
+	// maxLogoutErrorSize bounds the error kept with a failed delivery.
+	maxLogoutErrorSize = 500
+)
+
+var (
+	errLogoutRedirect = errors.New("back-channel logout redirects are not followed")
+	errLogoutAddress  = errors.New("back-channel logout endpoints must not be on loopback, private or link-local addresses")
+)
+
+// EndSessionRequest is a validated RP-initiated logout request (OpenID
+// Connect RP-Initiated Logout 1.0 section 2).
+type EndSessionRequest struct {
+	// ClientID is the client asking, from client_id or id_token_hint.
+	ClientID string
+	// Subject and SID are the user and session id_token_hint was issued
+	// for, if one was given.
+	Subject string
+	SID     string
+	// RedirectURI is where to send the user agent after logging out, with
+	// the client's state, or "" when the client named no
+	// post_logout_redirect_uri.
+	RedirectURI string
+}
+
+// tokenHint are the claims used of an id_token_hint.
+type tokenHint struct {
+	ClientID string `json:"client_id,omitempty"`
+	SID      string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// EndSession validates an RP-initiated logout request. The service issues
+// no ID tokens, so id_token_hint may be any JWT access token it issued,
+// expired or not; its client_id and sid name the client and the session.
+// post_logout_redirect_uri must be registered by that client.
+func (s *Service) EndSession(ctx context.Context, params url.Values) (*EndSessionRequest, error) {
+	req := &EndSessionRequest{ClientID: params.Get("client_id")}
+
+	if hint := params.Get("id_token_hint"); hint != "" {
+		var claims tokenHint
+		// The user may log out long after the token expired, so only the
+		// signature and the issuer are checked.
+		err := verify.ParseSigned(ctx, hint, managerKeys{s.mgr}, &claims, jwt.WithoutClaimsValidation())
+		if err == nil && claims.Issuer != s.mgr.Issuer {
+			err = fmt.Errorf("%w: issued by %q", verify.ErrInvalidToken, claims.Issuer)
+		}
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid id_token_hint", err)
+		}
+		if req.ClientID != "" && req.ClientID != claims.ClientID {
+			return nil, invalidRequest("client_id does not match id_token_hint")
+		}
+		req.ClientID = claims.ClientID
+		req.Subject = claims.Subject
+		req.SID = claims.SID
+	}
+
+	redirectURI := params.Get("post_logout_redirect_uri")
+	if redirectURI == "" {
+		return req, nil
+	}
+	if req.ClientID == "" {
+		return nil, invalidRequest("post_logout_redirect_uri requires client_id or id_token_hint")
+	}
+	client, _, err := s.loadClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(client.PostLogoutRedirectURIs, redirectURI) {
+		return nil, invalidRequest("post_logout_redirect_uri is not registered for this client")
+	}
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	if state := params.Get("state"); state != "" {
+		q := u.Query()
+		q.Set("state", state)
+		u.RawQuery = q.Encode()
+	}
+	req.RedirectURI = u.String()
+	return req, nil
+}
+
+// SessionEnded tells the clients the session sid of sub was used with that
+// it ended. Logout tokens are queued for the clients with a back-channel
+// logout URI and delivered right away, and the front-channel logout URLs
+// of the clients are returned to be loaded in iframes (OpenID Connect
+// Front-Channel Logout 1.0 section 2). Clients that cannot be loaded, such
+// as deleted ones, are skipped.
+func (s *Service) SessionEnded(ctx context.Context, sub, sid string, clientIDs []string) ([]string, error) {
+	var frontchannel []string
+	var queued []db.ClaimLogoutDeliveriesRow
+	for _, id := range clientIDs {
+		client, _, err := s.loadClient(ctx, id)
+		if err != nil {
+			log.Warnf("oauth: logout of session %s: skipping client %s: %v", sid, id, err)
+			continue
+		}
+		if client.FrontchannelLogoutURI != "" {
+			frontchannel = append(frontchannel, s.frontchannelLogoutURL(client.FrontchannelLogoutURI, sid))
+		}
+		if client.BackchannelLogoutURI != "" {
+			d, err := s.queueLogout(ctx, client, sub, sid)
+			if err != nil {
+				return frontchannel, serverError(err)
+			}
+			queued = append(queued, d)
+		}
+	}
+
+	if len(queued) > 0 {
+		// The request context ends with the response, the deliveries
+		// should not.
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*s.cfg.Logout.Timeout)
+			defer cancel()
+			for _, d := range queued {
+				s.deliverLogout(ctx, d)
+			}
+		}()
+	}
+	return frontchannel, nil
+}
+
+// RetryLogouts delivers the back-channel logout tokens due for another
+// attempt every Logout.RetryInterval until ctx is done. Tokens are stored
+// signed, so one service retries the deliveries of every issuer.
+func (s *Service) RetryLogouts(ctx context.Context) {
+	t := time.NewTicker(s.cfg.Logout.RetryInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := s.retryLogouts(ctx); err != nil {
+			log.Errorf("oauth: retry back-channel logouts: %v", err)
+		}
+	}
+}
+
+func (s *Service) retryLogouts(ctx context.Context) error {
+	if _, err := s.q.DeleteExpiredLogoutDeliveries(ctx); err != nil {
+		return err
+	}
+	for {
+		// Claiming moves the next attempt past the deliveries below, so
+		// other instances leave them alone.
+		due, err := s.q.ClaimLogoutDeliveries(ctx, db.ClaimLogoutDeliveriesParams{
+			NextAttemptAt: s.now().Add(2 * s.cfg.Logout.Timeout),
+			Limit:         logoutBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, d := range due {
+			s.deliverLogout(ctx, d)
+		}
+		if len(due) < logoutBatchSize {
+			return nil
+		}
+	}
+}
+
+// queueLogout signs a logout token for client and stores its delivery,
+// claimed for the first attempt.
+func (s *Service) queueLogout(ctx context.Context, client *Client, sub, sid string) (db.ClaimLogoutDeliveriesRow, error) {
+	signer, err := key.NewSigner(ctx, s.mgr, client.ID, s.mgr.Issuer, s.cfg.Logout.TokenTTL)
+	if err != nil {
+		return db.ClaimLogoutDeliveriesRow{}, err
+	}
+	token, err := signer.SignLogout(sub, sid)
+	if err != nil {
+		return db.ClaimLogoutDeliveriesRow{}, err
+	}
+	id, err := randomToken(16)
+	if err != nil {
+		return db.ClaimLogoutDeliveriesRow{}, err
+	}
+
+	now := s.now()
+	d := db.ClaimLogoutDeliveriesRow{
+		ID:          id,
+		Tenant:      s.tenant,
+		ClientID:    client.ID,
+		URI:         client.BackchannelLogoutURI,
+		LogoutToken: token,
+		ExpiresAt:   now.Add(s.cfg.Logout.TokenTTL),
+	}
+	err = s.q.CreateLogoutDelivery(ctx, db.CreateLogoutDeliveryParams{
+		ID:            d.ID,
+		Tenant:        d.Tenant,
+		ClientID:      d.ClientID,
+		URI:           d.URI,
+		LogoutToken:   d.LogoutToken,
+		NextAttemptAt: now.Add(2 * s.cfg.Logout.Timeout),
+		ExpiresAt:     d.ExpiresAt,
+	})
+	return d, err
+}
+
+// deliverLogout posts the logout token of d (OpenID Connect Back-Channel
+// Logout 1.0 section 2.5). Delivered tokens and tokens the client rejected
+// are dropped; other failures are retried, waiting twice as long after
+// every attempt, until the token expires.
+func (s *Service) deliverLogout(ctx context.Context, d db.ClaimLogoutDeliveriesRow) {
+	err := s.postLogoutToken(ctx, d.URI, d.LogoutToken)
+	var rejected *logoutRejectedError
+	switch {
+	case err == nil:
+	case errors.As(err, &rejected):
+		log.Warnf("oauth: client %s rejected back-channel logout: %v", d.ClientID, err)
+	default:
+		next := s.now().Add(s.cfg.Logout.RetryInterval << min(d.Attempts, 16))
+		if next.Before(d.ExpiresAt) {
+			msg := err.Error()
+			if len(msg) > maxLogoutErrorSize {
+				msg = msg[:maxLogoutErrorSize]
+			}
+			if err := s.q.RetryLogoutDelivery(ctx, db.RetryLogoutDeliveryParams{
+				ID:            d.ID,
+				LastError:     msg,
+				NextAttemptAt: next,
+			}); err != nil {
+				log.Errorf("oauth: reschedule back-channel logout %s: %v", d.ID, err)
+			}
+			return
+		}
+		log.Warnf("oauth: giving up back-channel logout of client %s after %d attempts: %v", d.ClientID, d.Attempts+1, err)
+	}
+
+	if err := s.q.DeleteLogoutDelivery(ctx, d.ID); err != nil {
+		log.Errorf("oauth: delete back-channel logout %s: %v", d.ID, err)
+	}
+}
+
+// logoutRejectedError is a 4xx answer to a logout token, which sending it
+// again would not change.
+type logoutRejectedError struct {
+	status string
+}
+
+func (e *logoutRejectedError) Error() string {
+	return "logout token rejected with " + e.status
+}
+
+func (s *Service) postLogoutToken(ctx context.Context, uri, token string) error {
+	body := url.Values{"logout_token": {token}}.Encode()
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.logoutClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return &logoutRejectedError{status: resp.Status}
+	}
+	return fmt.Errorf("back-channel logout endpoint returned %s", resp.Status)
+}
+
+// newLogoutClient returns the client logout tokens are posted with.
+func newLogoutClient(timeout time.Duration) *http.Client {
+	return newPublicClient(timeout, errLogoutAddress, errLogoutRedirect)
+}
+
+// newPublicClient returns a client for URLs clients register themselves,
+// which only connects to public addresses: its dialer checks the address a
+// host resolved to, which catches names pointing inside the server's
+// network as well. Dialing anything else fails with errAddress, and
+// redirects with errRedirect.
+func newPublicClient(timeout time.Duration, errAddress, errRedirect error) *http.Client {
+	dialer := &net.Dialer{
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || internalIP(ip) {
+				return errAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Through a proxy, the dialer would only see the proxy's address.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// A redirect could lead anywhere the checks above do not see.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return errRedirect
+		},
+	}
+}
+
+// internalIP reports whether ip is a loopback, private, link-local or
+// unspecified address, which a client must not make the server post to.
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// frontchannelLogoutURL adds iss and sid to the front-channel logout URI of
+// a client (OpenID Connect Front-Channel Logout 1.0 section 2).
+func (s *Service) frontchannelLogoutURL(uri, sid string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	q := u.Query()
+	q.Set("iss", s.mgr.Issuer)
+	q.Set("sid", sid)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
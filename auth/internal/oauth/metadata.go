@@ -0,0 +1,77 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+const (
+	// MetadataPath is where the authorization server metadata is published
+	// (RFC 8414 section 3). For an issuer with a path, such as a tenant's,
+	// the path follows it.
+	MetadataPath = "/.well-known/oauth-authorization-server"
+	JWKSPath     = "/.well-known/jwks.json"
+)
+
+// Metadata is the authorization server metadata (RFC 8414 section 2, RFC
+// 8628 section 4, RFC 8705 section 3.3, RFC 9126 section 5, RFC 9207
+// section 3, RFC 9449 section 5.1 and the OpenID Connect logout
+// specifications).
+type Metadata struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	JWKSURI                                    string   `json:"jwks_uri"`
+	RegistrationEndpoint                       string   `json:"registration_endpoint,omitempty"`
+	PushedAuthorizationRequestEndpoint         string   `json:"pushed_authorization_request_endpoint"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint"`
+	EndSessionEndpoint                         string   `json:"end_session_endpoint"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported"`
+	PromptValuesSupported                      []string `json:"prompt_values_supported"`
+	DPoPSigningAlgValuesSupported              []string `json:"dpop_signing_alg_values_supported"`
+	TLSClientCertificateBoundAccessTokens      bool     `json:"tls_client_certificate_bound_access_tokens"`
+	AuthorizationResponseISSParameterSupported bool     `json:"authorization_response_iss_parameter_supported"`
+	FrontchannelLogoutSupported                bool     `json:"frontchannel_logout_supported"`
+	FrontchannelLogoutSessionSupported         bool     `json:"frontchannel_logout_session_supported"`
+	BackchannelLogoutSupported                 bool     `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported          bool     `json:"backchannel_logout_session_supported"`
+}
+
+// Metadata describes the endpoints of the service, all below BaseURL.
+func (s *Service) Metadata(ctx context.Context) (Metadata, error) {
+	base := strings.TrimRight(s.cfg.BaseURL, "/")
+	md := Metadata{
+		Issuer:                                     s.mgr.Issuer,
+		AuthorizationEndpoint:                      base + AuthorizePath,
+		TokenEndpoint:                              base + TokenPath,
+		JWKSURI:                                    base + JWKSPath,
+		PushedAuthorizationRequestEndpoint:         base + PARPath,
+		DeviceAuthorizationEndpoint:                base + DeviceAuthorizationPath,
+		EndSessionEndpoint:                         base + EndSessionPath,
+		ResponseTypesSupported:                     []string{"code"},
+		GrantTypesSupported:                        registrableGrants,
+		TokenEndpointAuthMethodsSupported:          registrableAuthMethods,
+		CodeChallengeMethodsSupported:              []string{CodeChallengeS256},
+		PromptValuesSupported:                      promptValues,
+		DPoPSigningAlgValuesSupported:              verify.DPoPAlgs(),
+		TLSClientCertificateBoundAccessTokens:      true,
+		AuthorizationResponseISSParameterSupported: true,
+		FrontchannelLogoutSupported:                true,
+		FrontchannelLogoutSessionSupported:         true,
+		BackchannelLogoutSupported:                 true,
+		BackchannelLogoutSessionSupported:          true,
+	}
+	registration, err := s.registrationEnabled(ctx)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if registration {
+		md.RegistrationEndpoint = base + RegistrationPath
+	}
+	return md, nil
+}
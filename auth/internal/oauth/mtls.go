@@ -0,0 +1,122 @@
+package oauth
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"time"
+)
+
+var (
+	errNoCertificate     = errors.New("no client certificate")
+	errNoMTLSRoots       = errors.New("no trusted client CAs configured")
+	errCertMismatch      = errors.New("client certificate does not match the registered identity")
+	errNoTLSRegistration = errors.New("client has no registered certificate identity")
+)
+
+// loadCertPool reads PEM certificates from path, or returns nil when path is
+// empty.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// authenticateTLSClient checks the client certificate of a mutual-TLS
+// connection against the client's registration (RFC 8705 section 2).
+func (s *Service) authenticateTLSClient(client *Client, chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errNoCertificate
+	}
+	leaf := chain[0]
+
+	if client.AuthMethod == AuthMethodSelfSignedTLSAuth {
+		return matchSelfSigned(client, leaf, s.now())
+	}
+
+	if s.mtlsRoots == nil {
+		return errNoMTLSRoots
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         s.mtlsRoots,
+		Intermediates: intermediates,
+		CurrentTime:   s.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return err
+	}
+
+	return matchTLSIdentity(client.TLS, leaf)
+}
+
+func matchTLSIdentity(id TLSClientAuth, leaf *x509.Certificate) error {
+	switch {
+	case id.SubjectDN != "":
+		if leaf.Subject.String() == id.SubjectDN {
+			return nil
+		}
+	case id.SANDNS != "":
+		if slices.Contains(leaf.DNSNames, id.SANDNS) {
+			return nil
+		}
+	case id.SANURI != "":
+		for _, u := range leaf.URIs {
+			if u.String() == id.SANURI {
+				return nil
+			}
+		}
+	case id.SANIP != "":
+		want := net.ParseIP(id.SANIP)
+		for _, ip := range leaf.IPAddresses {
+			if want != nil && ip.Equal(want) {
+				return nil
+			}
+		}
+	case id.SANEmail != "":
+		if slices.Contains(leaf.EmailAddresses, id.SANEmail) {
+			return nil
+		}
+	default:
+		return errNoTLSRegistration
+	}
+	return errCertMismatch
+}
+
+// matchSelfSigned accepts leaf when it is one of the certificates registered
+// in the x5c of the client's JWKS.
+func matchSelfSigned(client *Client, leaf *x509.Certificate, now time.Time) error {
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return errCertMismatch
+	}
+	for _, k := range client.JWKS.Keys {
+		if len(k.X5C) == 0 {
+			continue
+		}
+		der, err := base64.StdEncoding.DecodeString(k.X5C[0])
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(der, leaf.Raw) {
+			return nil
+		}
+	}
+	return errCertMismatch
+}
@@ -0,0 +1,68 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const nonceMACSize = 16
+
+// NonceSource issues stateless DPoP server nonces (RFC 9449 section 8): an
+// issue timestamp followed by a truncated HMAC over it, so any instance
+// sharing the secret can check a nonce without storage.
+type NonceSource struct {
+	secret   []byte
+	lifetime time.Duration
+	now      func() time.Time
+}
+
+// NewNonceSource decodes a hex secret, or generates a random one when
+// secretHex is empty.
+func NewNonceSource(secretHex string, lifetime time.Duration) (*NonceSource, error) {
+	secret := make([]byte, 32)
+	if secretHex == "" {
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err := hex.DecodeString(secretHex)
+		if err != nil || len(raw) < 32 {
+			return nil, fmt.Errorf("dpop nonce secret must be at least 32 bytes hex")
+		}
+		secret = raw
+	}
+	return &NonceSource{secret: secret, lifetime: lifetime, now: time.Now}, nil
+}
+
+func (s *NonceSource) Issue() string {
+	b := make([]byte, 8, 8+nonceMACSize)
+	binary.BigEndian.PutUint64(b, uint64(s.now().Unix()))
+	b = append(b, s.mac(b)...)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (s *NonceSource) Valid(nonce string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(b) != 8+nonceMACSize {
+		return false
+	}
+	if !hmac.Equal(b[8:], s.mac(b[:8])) {
+		return false
+	}
+	issued := time.Unix(int64(binary.BigEndian.Uint64(b[:8])), 0)
+	age := s.now().Sub(issued)
+	return age >= -time.Minute && age <= s.lifetime
+}
+
+func (s *NonceSource) mac(ts []byte) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte("dpop-nonce"))
+	h.Write(ts)
+	return h.Sum(nil)[:nonceMACSize]
+}
@@ -0,0 +1,61 @@
+package oauth
+
+import "github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+
+type Option func(*Service)
+
+// WithTenant makes the service the issuer of tenant, which only knows the
+// clients registered with it.
+func WithTenant(tenant string) Option {
+	return func(s *Service) {
+		s.tenant = tenant
+	}
+}
+
+// WithReplayCache sets where DPoP proof and assertion jti values are
+// remembered.
+func WithReplayCache(c verify.ReplayCache) Option {
+	return func(s *Service) {
+		s.replay = c
+	}
+}
+
+// WithDeviceStore sets where device authorizations are kept.
+func WithDeviceStore(d DeviceStore) Option {
+	return func(s *Service) {
+		s.devices = d
+	}
+}
+
+// WithKVStore sets where pushed authorization requests, pending sign-ins and
+// authorization codes are kept.
+func WithKVStore(kv KVStore) Option {
+	return func(s *Service) {
+		s.kv = kv
+	}
+}
+
+// WithPermissionsProvider sets where the permissions claim of access tokens
+// comes from. Tokens carry no permissions without one.
+func WithPermissionsProvider(p PermissionsProvider) Option {
+	return func(s *Service) {
+		s.perms = p
+	}
+}
+
+// WithRolesProvider sets where the ${roles} of claim templates come from.
+func WithRolesProvider(p RolesProvider) Option {
+	return func(s *Service) {
+		s.roles = p
+	}
+}
+
+// WithTokenHook calls hook for the claims of other systems before signing
+// access tokens. failure is the TokenHookFailOpen or TokenHookFailClosed
+// policy of clients without their own.
+func WithTokenHook(hook TokenHook, failure string) Option {
+	return func(s *Service) {
+		s.hook = hook
+		s.hookFailure = failure
+	}
+}
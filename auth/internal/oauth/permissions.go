@@ -0,0 +1,58 @@
+package oauth
+
+import (
+	"context"
+	"slices"
+)
+
+// PermissionsRequest describes the token being issued when its permissions
+// are looked up.
+type PermissionsRequest struct {
+	// Tenant is the issuing tenant, "" for the root issuer.
+	Tenant   string
+	Subject  string
+	ClientID string
+	Audience []string
+	Scopes   []string
+}
+
+// PermissionsProvider decides the permissions claim of access tokens. It is
+// consulted once per token, after the subject, audience and scopes are
+// settled; an error fails the token request.
+type PermissionsProvider interface {
+	Permissions(ctx context.Context, req PermissionsRequest) ([]string, error)
+}
+
+// PermissionsFunc adapts a function to a PermissionsProvider.
+type PermissionsFunc func(ctx context.Context, req PermissionsRequest) ([]string, error)
+
+func (f PermissionsFunc) Permissions(ctx context.Context, req PermissionsRequest) ([]string, error) {
+	return f(ctx, req)
+}
+
+// StaticPermissions is a PermissionsProvider granting fixed permissions per
+// subject, standing in for the policies service in tests and local setups.
+type StaticPermissions map[string][]string
+
+func (p StaticPermissions) Permissions(ctx context.Context, req PermissionsRequest) ([]string, error) {
+	return slices.Clone(p[req.Subject]), nil
+}
+
+// permissions returns the permissions of a token for sub, or none without a
+// provider.
+func (s *Service) permissions(ctx context.Context, client *Client, sub string, aud, scopes []string) ([]string, error) {
+	if s.perms == nil {
+		return nil, nil
+	}
+	perms, err := s.perms.Permissions(ctx, PermissionsRequest{
+		Tenant:   s.tenant,
+		Subject:  sub,
+		ClientID: client.ID,
+		Audience: aud,
+		Scopes:   scopes,
+	})
+	if err != nil {
+		return nil, serverError(err)
+	}
+	return perms, nil
+}
@@ -0,0 +1,663 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const RegistrationPath = "/oauth/register"
+
+// registrableGrants are the grant types a client may register for.
+var registrableGrants = []string{
+	GrantAuthorizationCode,
+	GrantClientCredentials,
+	GrantDeviceCode,
+	GrantJWTBearer,
+	GrantTokenExchange,
+}
+
+var registrableAuthMethods = []string{
+	AuthMethodSecretBasic,
+	AuthMethodSecretPost,
+	AuthMethodPrivateKeyJWT,
+	AuthMethodTLSClientAuth,
+	AuthMethodSelfSignedTLSAuth,
+	AuthMethodNone,
+}
+
+// ClientMetadata is the registrable client metadata (RFC 7591 section 2,
+// RFC 8705 section 2.1.2, RFC 9126 section 6, RFC 9449 section 5.2 and the
+// OpenID Connect logout specifications), plus access_token_format to ask
+// for PASETO access tokens. Unknown fields are ignored.
+type ClientMetadata struct {
+	RedirectURIs            []string     `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string       `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string     `json:"grant_types,omitempty"`
+	ResponseTypes           []string     `json:"response_types,omitempty"`
+	ClientName              string       `json:"client_name,omitempty"`
+	Scope                   string       `json:"scope,omitempty"`
+	JWKS                    *verify.JWKS `json:"jwks,omitempty"`
+	JWKSURI                 string       `json:"jwks_uri,omitempty"`
+
+	TLSClientAuthSubjectDN                string `json:"tls_client_auth_subject_dn,omitempty"`
+	TLSClientAuthSANDNS                   string `json:"tls_client_auth_san_dns,omitempty"`
+	TLSClientAuthSANURI                   string `json:"tls_client_auth_san_uri,omitempty"`
+	TLSClientAuthSANIP                    string `json:"tls_client_auth_san_ip,omitempty"`
+	TLSClientAuthSANEmail                 string `json:"tls_client_auth_san_email,omitempty"`
+	TLSClientCertificateBoundAccessTokens bool   `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+
+	DPoPBoundAccessTokens              bool `json:"dpop_bound_access_tokens,omitempty"`
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests,omitempty"`
+
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris,omitempty"`
+	BackchannelLogoutURI   string   `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI  string   `json:"frontchannel_logout_uri,omitempty"`
+
+	AccessTokenFormat string `json:"access_token_format,omitempty"`
+}
+
+// ClientUpdateRequest is the body of a client update request (RFC 7592
+// section 2.2). It replaces every metadata value of the client.
+type ClientUpdateRequest struct {
+	ClientMetadata
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// ClientInformation is the client information response (RFC 7591 section
+// 3.2.1, RFC 7592 section 3).
+type ClientInformation struct {
+	ClientID              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt      int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt *int64 `json:"client_secret_expires_at,omitempty"`
+
+	RegistrationAccessToken string `json:"registration_access_token"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+
+	ClientMetadata
+}
+
+var errRegistrationToken = errors.New("unknown client or registration access token mismatch")
+
+// RegisterClient registers a client (RFC 7591 section 3) for a caller holding
+// the initial access token of the issuer.
+func (s *Service) RegisterClient(ctx context.Context, initialAccessToken string, md ClientMetadata) (*ClientInformation, error) {
+	if err := s.checkInitialAccessToken(ctx, initialAccessToken); err != nil {
+		return nil, err
+	}
+
+	md, err := s.validateMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	secret, secretHash, err := issueSecret(md.TokenEndpointAuthMethod)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	token, tokenHash, err := issueRegistrationToken()
+	if err != nil {
+		return nil, serverError(err)
+	}
+
+	row := metadataRow(id, md)
+	if err := s.q.CreateClient(ctx, db.CreateClientParams{
+		ClientID:                              id,
+		ClientSecretHash:                      secretHash,
+		Name:                                  row.Name,
+		GrantTypes:                            row.GrantTypes,
+		Scopes:                                row.Scopes,
+		TokenEndpointAuthMethod:               row.TokenEndpointAuthMethod,
+		DPoPBoundAccessTokens:                 row.DPoPBoundAccessTokens,
+		TLSClientAuthSubjectDN:                row.TLSClientAuthSubjectDN,
+		TLSClientAuthSANDNS:                   row.TLSClientAuthSANDNS,
+		TLSClientAuthSANURI:                   row.TLSClientAuthSANURI,
+		TLSClientAuthSANIP:                    row.TLSClientAuthSANIP,
+		TLSClientAuthSANEmail:                 row.TLSClientAuthSANEmail,
+		JWKS:                                  row.JWKS,
+		TLSClientCertificateBoundAccessTokens: row.TLSClientCertificateBoundAccessTokens,
+		JWKSURI:                               row.JWKSURI,
+		RedirectURIs:                          row.RedirectURIs,
+		RequirePushedAuthorizationRequests:    row.RequirePushedAuthorizationRequests,
+		RegistrationAccessTokenHash:           tokenHash,
+		AccessTokenFormat:                     row.AccessTokenFormat,
+		Tenant:                                s.tenant,
+		PostLogoutRedirectURIs:                row.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:                  row.BackchannelLogoutURI,
+		FrontchannelLogoutURI:                 row.FrontchannelLogoutURI,
+	}); err != nil {
+		return nil, serverError(err)
+	}
+
+	row.ClientSecretHash = secretHash
+	row.CreatedAt = s.now()
+	info := s.clientInformation(row, token)
+	info.ClientSecret = secret
+	return info, nil
+}
+
+// ReadClient returns the current registration of a client (RFC 7592 section
+// 2.1).
+//
+// Only a hash of the registration access token is stored, so every response
+// carries a new token and the one used for the request stops working.
+func (s *Service) ReadClient(ctx context.Context, clientID, registrationToken string) (*ClientInformation, error) {
+	row, err := s.registeredClient(ctx, clientID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenHash, err := issueRegistrationToken()
+	if err != nil {
+		return nil, serverError(err)
+	}
+	n, err := s.q.UpdateClientRegistrationToken(ctx, db.UpdateClientRegistrationTokenParams{
+		ClientID:                    row.ClientID,
+		RegistrationAccessTokenHash: tokenHash,
+		Tenant:                      s.tenant,
+	})
+	if err != nil {
+		return nil, serverError(err)
+	}
+	if n == 0 {
+		return nil, invalidToken(errRegistrationToken)
+	}
+
+	return s.clientInformation(row, token), nil
+}
+
+// UpdateClient replaces the metadata of a client (RFC 7592 section 2.2). A
+// client secret is issued when the client switches to a secret based
+// authentication method and dropped when it switches away from one.
+func (s *Service) UpdateClient(ctx context.Context, clientID, registrationToken string, req ClientUpdateRequest) (*ClientInformation, error) {
+	row, err := s.registeredClient(ctx, clientID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ClientID != row.ClientID {
+		return nil, invalidRequest("client_id does not match the registration")
+	}
+	if req.ClientSecret != "" {
+		if !row.ClientSecretHash.Valid ||
+			bcrypt.CompareHashAndPassword([]byte(row.ClientSecretHash.String), []byte(req.ClientSecret)) != nil {
+			return nil, invalidRequest("client_secret does not match the registration")
+		}
+	}
+
+	md, err := s.validateMetadata(req.ClientMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret string
+	secretHash := row.ClientSecretHash
+	switch {
+	case !usesSecret(md.TokenEndpointAuthMethod):
+		secretHash = sql.NullString{}
+	case !secretHash.Valid:
+		if secret, secretHash, err = issueSecret(md.TokenEndpointAuthMethod); err != nil {
+			return nil, serverError(err)
+		}
+	}
+	token, tokenHash, err := issueRegistrationToken()
+	if err != nil {
+		return nil, serverError(err)
+	}
+
+	updated := metadataRow(row.ClientID, md)
+	n, err := s.q.UpdateClient(ctx, db.UpdateClientParams{
+		ClientID:                              row.ClientID,
+		ClientSecretHash:                      secretHash,
+		Name:                                  updated.Name,
+		GrantTypes:                            updated.GrantTypes,
+		Scopes:                                updated.Scopes,
+		TokenEndpointAuthMethod:               updated.TokenEndpointAuthMethod,
+		DPoPBoundAccessTokens:                 updated.DPoPBoundAccessTokens,
+		TLSClientAuthSubjectDN:                updated.TLSClientAuthSubjectDN,
+		TLSClientAuthSANDNS:                   updated.TLSClientAuthSANDNS,
+		TLSClientAuthSANURI:                   updated.TLSClientAuthSANURI,
+		TLSClientAuthSANIP:                    updated.TLSClientAuthSANIP,
+		TLSClientAuthSANEmail:                 updated.TLSClientAuthSANEmail,
+		JWKS:                                  updated.JWKS,
+		TLSClientCertificateBoundAccessTokens: updated.TLSClientCertificateBoundAccessTokens,
+		JWKSURI:                               updated.JWKSURI,
+		RedirectURIs:                          updated.RedirectURIs,
+		RequirePushedAuthorizationRequests:    updated.RequirePushedAuthorizationRequests,
+		RegistrationAccessTokenHash:           tokenHash,
+		AccessTokenFormat:                     updated.AccessTokenFormat,
+		Tenant:                                s.tenant,
+		PostLogoutRedirectURIs:                updated.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:                  updated.BackchannelLogoutURI,
+		FrontchannelLogoutURI:                 updated.FrontchannelLogoutURI,
+	})
+	if err != nil {
+		return nil, serverError(err)
+	}
+	if n == 0 {
+		return nil, invalidToken(errRegistrationToken)
+	}
+
+	updated.ClientSecretHash = secretHash
+	updated.CreatedAt = row.CreatedAt
+	info := s.clientInformation(updated, token)
+	info.ClientSecret = secret
+	return info, nil
+}
+
+// DeleteClient deregisters a client (RFC 7592 section 2.3).
+func (s *Service) DeleteClient(ctx context.Context, clientID, registrationToken string) error {
+	row, err := s.registeredClient(ctx, clientID, registrationToken)
+	if err != nil {
+		return err
+	}
+	n, err := s.q.DeleteClient(ctx, db.DeleteClientParams{
+		ClientID: row.ClientID,
+		Tenant:   s.tenant,
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	if n == 0 {
+		return invalidToken(errRegistrationToken)
+	}
+	return nil
+}
+
+// RegistrationClientURI is the client configuration endpoint of clientID.
+func (s *Service) RegistrationClientURI(clientID string) string {
+	return strings.TrimRight(s.cfg.BaseURL, "/") + RegistrationPath + "/" + url.PathEscape(clientID)
+}
+
+// registeredClient loads a dynamically registered client and checks the
+// registration access token. Unknown clients are reported as a bad token
+// so that client IDs cannot be probed (RFC 7592 section 2.1).
+func (s *Service) registeredClient(ctx context.Context, clientID, registrationToken string) (db.OauthClient, error) {
+	row, err := s.q.GetClient(ctx, db.GetClientParams{
+		ClientID: clientID,
+		Tenant:   s.tenant,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return row, invalidToken(errRegistrationToken)
+	}
+	if err != nil {
+		return row, serverError(err)
+	}
+
+	presented := hashRegistrationToken(registrationToken)
+	if registrationToken == "" || !row.RegistrationAccessTokenHash.Valid ||
+		subtle.ConstantTimeCompare([]byte(presented), []byte(row.RegistrationAccessTokenHash.String)) != 1 {
+		return row, invalidToken(errRegistrationToken)
+	}
+	return row, nil
+}
+
+// validateMetadata applies defaults to md and rejects metadata the server
+// cannot honour.
+func (s *Service) validateMetadata(md ClientMetadata) (ClientMetadata, error) {
+	if len(md.GrantTypes) == 0 {
+		md.GrantTypes = []string{GrantAuthorizationCode}
+	}
+	slices.Sort(md.GrantTypes)
+	md.GrantTypes = slices.Compact(md.GrantTypes)
+	for _, g := range md.GrantTypes {
+		if !slices.Contains(registrableGrants, g) {
+			return md, invalidMetadata("unsupported grant type " + g)
+		}
+	}
+
+	code := slices.Contains(md.GrantTypes, GrantAuthorizationCode)
+	if len(md.ResponseTypes) == 0 && code {
+		md.ResponseTypes = []string{"code"}
+	}
+	for _, rt := range md.ResponseTypes {
+		if rt != "code" {
+			return md, invalidMetadata("unsupported response type " + rt)
+		}
+	}
+	if code != (len(md.ResponseTypes) > 0) {
+		return md, invalidMetadata("response_types code and grant_types authorization_code must be registered together")
+	}
+
+	if code && len(md.RedirectURIs) == 0 {
+		return md, newError(http.StatusBadRequest, CodeInvalidRedirectURI, "redirect_uris is required for the authorization_code grant", nil)
+	}
+	for _, uri := range md.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return md, newError(http.StatusBadRequest, CodeInvalidRedirectURI, "invalid redirect URI "+uri, err)
+		}
+	}
+
+	for _, uri := range md.PostLogoutRedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return md, newError(http.StatusBadRequest, CodeInvalidClientMetadata, "invalid post_logout_redirect_uri "+uri, err)
+		}
+	}
+	if md.FrontchannelLogoutURI != "" {
+		if err := validateRedirectURI(md.FrontchannelLogoutURI); err != nil {
+			return md, newError(http.StatusBadRequest, CodeInvalidClientMetadata, "invalid frontchannel_logout_uri", err)
+		}
+	}
+	// Logout tokens are posted by the server, so hosts inside its network
+	// are not allowed here; the logout client refuses names resolving to
+	// them.
+	if md.BackchannelLogoutURI != "" {
+		u, err := url.Parse(md.BackchannelLogoutURI)
+		if err != nil || u.Scheme != "https" || u.Host == "" || u.Fragment != "" {
+			return md, invalidMetadata("backchannel_logout_uri must be an https URL without a fragment")
+		}
+		if internalHost(u.Hostname()) {
+			return md, invalidMetadata("backchannel_logout_uri must not be on a loopback, private or link-local address")
+		}
+	}
+
+	if md.TokenEndpointAuthMethod == "" {
+		md.TokenEndpointAuthMethod = AuthMethodSecretBasic
+	}
+	if !slices.Contains(registrableAuthMethods, md.TokenEndpointAuthMethod) {
+		return md, invalidMetadata("unsupported token_endpoint_auth_method")
+	}
+	if md.TokenEndpointAuthMethod == AuthMethodNone && slices.Contains(md.GrantTypes, GrantClientCredentials) {
+		return md, invalidMetadata("public clients cannot use client_credentials")
+	}
+
+	if md.JWKS != nil && md.JWKSURI != "" {
+		return md, invalidMetadata("jwks and jwks_uri are mutually exclusive")
+	}
+	// Key sets are fetched by the server, so hosts inside its network are
+	// not allowed, as for backchannel_logout_uri.
+	if md.JWKSURI != "" {
+		u, err := url.Parse(md.JWKSURI)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return md, invalidMetadata("jwks_uri must be an https URL")
+		}
+		if internalHost(u.Hostname()) {
+			return md, invalidMetadata("jwks_uri must not be on a loopback, private or link-local address")
+		}
+	}
+	if md.JWKS != nil {
+		for _, k := range md.JWKS.Keys {
+			if _, err := k.PublicKey(); err != nil && len(k.X5C) == 0 {
+				return md, newError(http.StatusBadRequest, CodeInvalidClientMetadata, "invalid key in jwks", err)
+			}
+		}
+	}
+	hasKeys := md.JWKS != nil && len(md.JWKS.Keys) > 0
+	switch md.TokenEndpointAuthMethod {
+	case AuthMethodPrivateKeyJWT:
+		if !hasKeys && md.JWKSURI == "" {
+			return md, invalidMetadata("private_key_jwt requires jwks or jwks_uri")
+		}
+	case AuthMethodSelfSignedTLSAuth:
+		// Self-signed certificates are matched against x5c of jwks only.
+		if !hasKeys {
+			return md, invalidMetadata("self_signed_tls_client_auth requires jwks")
+		}
+	}
+
+	identities := 0
+	for _, v := range []string{md.TLSClientAuthSubjectDN, md.TLSClientAuthSANDNS, md.TLSClientAuthSANURI, md.TLSClientAuthSANIP, md.TLSClientAuthSANEmail} {
+		if v != "" {
+			identities++
+		}
+	}
+	switch {
+	case md.TokenEndpointAuthMethod == AuthMethodTLSClientAuth && identities != 1:
+		return md, invalidMetadata("tls_client_auth requires exactly one tls_client_auth_* value")
+	case md.TokenEndpointAuthMethod != AuthMethodTLSClientAuth && identities > 0:
+		return md, invalidMetadata("tls_client_auth_* values require tls_client_auth")
+	}
+	if md.TLSClientAuthSANIP != "" && net.ParseIP(md.TLSClientAuthSANIP) == nil {
+		return md, invalidMetadata("tls_client_auth_san_ip is not an IP address")
+	}
+
+	if md.AccessTokenFormat == "" {
+		md.AccessTokenFormat = key.FormatJWT
+	}
+	if !slices.Contains(tokenFormats, md.AccessTokenFormat) {
+		return md, invalidMetadata("access_token_format must be jwt or paseto")
+	}
+
+	scopes := splitScope(md.Scope)
+	for _, sc := range scopes {
+		if !slices.Contains(s.cfg.Registration.AllowedScopes, sc) {
+			return md, invalidMetadata("scope " + sc + " cannot be registered")
+		}
+	}
+	md.Scope = strings.Join(scopes, " ")
+	md.ClientName = strings.TrimSpace(md.ClientName)
+
+	return md, nil
+}
+
+// validateRedirectURI accepts absolute https URIs without a fragment, and
+// http ones for loopback addresses of native clients (RFC 8252 section 7.3).
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if u.Fragment != "" || strings.Contains(uri, "#") {
+		return errors.New("redirect URI must not contain a fragment")
+	}
+	switch {
+	case u.Scheme == "https" && u.Host != "":
+		return nil
+	case u.Scheme == "http" && isLoopback(u.Hostname()):
+		return nil
+	}
+	return errors.New("redirect URI must use https, or http on a loopback address")
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// internalHost reports whether host is localhost or an internalIP literal.
+func internalHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && internalIP(ip)
+}
+
+// metadataRow maps validated metadata onto a client row.
+func metadataRow(id string, md ClientMetadata) db.OauthClient {
+	jwks := json.RawMessage(`{"keys": []}`)
+	if md.JWKS != nil {
+		// Marshalling a JWKS cannot fail.
+		jwks, _ = json.Marshal(md.JWKS)
+	}
+	return db.OauthClient{
+		ClientID:                              id,
+		Name:                                  md.ClientName,
+		GrantTypes:                            md.GrantTypes,
+		Scopes:                                splitScope(md.Scope),
+		TokenEndpointAuthMethod:               md.TokenEndpointAuthMethod,
+		DPoPBoundAccessTokens:                 md.DPoPBoundAccessTokens,
+		TLSClientAuthSubjectDN:                nullString(md.TLSClientAuthSubjectDN),
+		TLSClientAuthSANDNS:                   nullString(md.TLSClientAuthSANDNS),
+		TLSClientAuthSANURI:                   nullString(md.TLSClientAuthSANURI),
+		TLSClientAuthSANIP:                    nullString(md.TLSClientAuthSANIP),
+		TLSClientAuthSANEmail:                 nullString(md.TLSClientAuthSANEmail),
+		JWKS:                                  jwks,
+		TLSClientCertificateBoundAccessTokens: md.TLSClientCertificateBoundAccessTokens,
+		JWKSURI:                               nullString(md.JWKSURI),
+		RedirectURIs:                          md.RedirectURIs,
+		RequirePushedAuthorizationRequests:    md.RequirePushedAuthorizationRequests,
+		AccessTokenFormat:                     md.AccessTokenFormat,
+		PostLogoutRedirectURIs:                md.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:                  md.BackchannelLogoutURI,
+		FrontchannelLogoutURI:                 md.FrontchannelLogoutURI,
+	}
+}
+
+func (s *Service) clientInformation(row db.OauthClient, registrationToken string) *ClientInformation {
+	md := ClientMetadata{
+		RedirectURIs:                          row.RedirectURIs,
+		TokenEndpointAuthMethod:               row.TokenEndpointAuthMethod,
+		GrantTypes:                            row.GrantTypes,
+		ClientName:                            row.Name,
+		Scope:                                 strings.Join(row.Scopes, " "),
+		JWKSURI:                               row.JWKSURI.String,
+		TLSClientAuthSubjectDN:                row.TLSClientAuthSubjectDN.String,
+		TLSClientAuthSANDNS:                   row.TLSClientAuthSANDNS.String,
+		TLSClientAuthSANURI:                   row.TLSClientAuthSANURI.String,
+		TLSClientAuthSANIP:                    row.TLSClientAuthSANIP.String,
+		TLSClientAuthSANEmail:                 row.TLSClientAuthSANEmail.String,
+		TLSClientCertificateBoundAccessTokens: row.TLSClientCertificateBoundAccessTokens,
+		DPoPBoundAccessTokens:                 row.DPoPBoundAccessTokens,
+		RequirePushedAuthorizationRequests:    row.RequirePushedAuthorizationRequests,
+		PostLogoutRedirectURIs:                row.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:                  row.BackchannelLogoutURI,
+		FrontchannelLogoutURI:                 row.FrontchannelLogoutURI,
+		AccessTokenFormat:                     row.AccessTokenFormat,
+	}
+	if slices.Contains(row.GrantTypes, GrantAuthorizationCode) {
+		md.ResponseTypes = []string{"code"}
+	}
+	var jwks verify.JWKS
+	if json.Unmarshal(row.JWKS, &jwks) == nil && len(jwks.Keys) > 0 {
+		md.JWKS = &jwks
+	}
+
+	info := &ClientInformation{
+		ClientID:                row.ClientID,
+		ClientIDIssuedAt:        row.CreatedAt.Unix(),
+		RegistrationAccessToken: registrationToken,
+		RegistrationClientURI:   s.RegistrationClientURI(row.ClientID),
+		ClientMetadata:          md,
+	}
+	if row.ClientSecretHash.Valid {
+		// Secrets do not expire.
+		var never int64
+		info.ClientSecretExpiresAt = &never
+	}
+	return info
+}
+
+func usesSecret(method string) bool {
+	return method == AuthMethodSecretBasic || method == AuthMethodSecretPost
+}
+
+// issueSecret returns a new client secret and its bcrypt hash for secret
+// based authentication methods, or nothing for the others.
+func issueSecret(method string) (string, sql.NullString, error) {
+	if !usesSecret(method) {
+		return "", sql.NullString{}, nil
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+	return secret, nullString(string(hash)), nil
+}
+
+func issueRegistrationToken() (string, sql.NullString, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+	return token, nullString(hashRegistrationToken(token)), nil
+}
+
+// NewInitialAccessToken returns a new initial access token for a tenant and
+// the hash to store for it.
+func NewInitialAccessToken() (token, hash string, err error) {
+	token, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, hashRegistrationToken(token), nil
+}
+
+// checkInitialAccessToken checks token against the initial access token of
+// the issuer: the configured one at the root issuer, and at a tenant its
+// own, stored hashed, so the token of one tenant registers no clients at
+// another. Registration is disabled where there is none.
+func (s *Service) checkInitialAccessToken(ctx context.Context, token string) error {
+	errMismatch := invalidToken(errors.New("initial access token mismatch"))
+	if s.tenant == "" {
+		want := s.cfg.Registration.InitialAccessToken
+		if want == "" || subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+			return errMismatch
+		}
+		return nil
+	}
+
+	want, err := s.q.GetTenantRegistrationTokenHash(ctx, s.tenant)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errMismatch
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	if want == "" || token == "" || subtle.ConstantTimeCompare([]byte(hashRegistrationToken(token)), []byte(want)) != 1 {
+		return errMismatch
+	}
+	return nil
+}
+
+// registrationEnabled reports whether the issuer has an initial access
+// token.
+func (s *Service) registrationEnabled(ctx context.Context) (bool, error) {
+	if s.tenant == "" {
+		return s.cfg.Registration.InitialAccessToken != "", nil
+	}
+	hash, err := s.q.GetTenantRegistrationTokenHash(ctx, s.tenant)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return hash != "", err
+}
+
+// hashRegistrationToken hashes a registration access token for storage. The
+// tokens are random, so an unsalted hash is enough.
+func hashRegistrationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{
+		String: s,
+		Valid:  s != "",
+	}
+}
+
+func invalidMetadata(description string) *Error {
+	return newError(http.StatusBadRequest, CodeInvalidClientMetadata, description, nil)
+}
+
+func invalidToken(err error) *Error {
+	return newError(http.StatusUnauthorized, CodeInvalidToken, "invalid access token", err)
+}
@@ -0,0 +1,25 @@
+package oauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+// RedisReplayCache is a verify.ReplayCache shared by every instance.
+type RedisReplayCache struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+var _ verify.ReplayCache = (*RedisReplayCache)(nil)
+
+func NewRedisReplayCache(rdb *redis.Client) *RedisReplayCache {
+	return &RedisReplayCache{rdb: rdb, prefix: "auth:replay:"}
+}
+
+func (c *RedisReplayCache) Remember(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, c.prefix+id, 1, ttl).Result()
+}
@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+// requestObjectClaims are JWT claims of a request object that are not
+// authorization request parameters.
+var requestObjectClaims = []string{"iss", "aud", "exp", "iat", "nbf", "jti"}
+
+// requestObjectParams verifies a request object (RFC 9101) signed with one of
+// the client's registered keys and returns the authorization request
+// parameters it carries. Parameters outside the request object are ignored
+// (RFC 9101 section 6.3).
+func (s *Service) requestObjectParams(ctx context.Context, client *Client, request string) (url.Values, error) {
+	claims := jwt.MapClaims{}
+	if err := verify.ParseSigned(ctx, request, s.clientKeys(client.JWKS, client.JWKSURI), claims,
+		jwt.WithIssuer(client.ID),
+		jwt.WithLeeway(assertionLeeway),
+		jwt.WithTimeFunc(s.now),
+	); err != nil {
+		return nil, invalidRequestObject(err)
+	}
+
+	aud, err := claims.GetAudience()
+	if err != nil || !slices.ContainsFunc(aud, s.isOwnAudience) {
+		return nil, invalidRequestObject(errAssertionAudience)
+	}
+	if id, _ := claims["client_id"].(string); id != client.ID {
+		return nil, invalidRequestObject(errAssertionSubject)
+	}
+
+	params := url.Values{}
+	for name, value := range claims {
+		if slices.Contains(requestObjectClaims, name) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			params.Set(name, v)
+		case float64:
+			params.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			params.Set(name, strconv.FormatBool(v))
+		default:
+			// Repeatable parameters such as resource are arrays of strings.
+			if values, ok := stringValues(v); ok {
+				params[name] = values
+				continue
+			}
+			// Structured parameters such as claims are JSON encoded, as they
+			// would be in a query string.
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, invalidRequestObject(err)
+			}
+			params.Set(name, string(raw))
+		}
+	}
+	return params, nil
+}
+
+func invalidRequestObject(err error) *Error {
+	return newError(http.StatusBadRequest, CodeInvalidRequestObject, "invalid request object", err)
+}
+
+func stringValues(v any) ([]string, bool) {
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+	values := make([]string, 0, len(arr))
+	for _, e := range arr {
+		s, ok := e.(string)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, s)
+	}
+	return values, true
+}
@@ -0,0 +1,43 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// checkResources validates resource indicators (RFC 8707 section 2): each
+// must be an absolute URI without a fragment, registered for the client.
+func checkResources(client *Client, resources []string) error {
+	for _, r := range resources {
+		u, err := url.Parse(r)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return newError(http.StatusBadRequest, CodeInvalidTarget, "resource "+r+" is not an absolute URI", err)
+		}
+		if !slices.Contains(client.Resources, r) {
+			return newError(http.StatusBadRequest, CodeInvalidTarget, "resource "+r+" is not allowed for this client", nil)
+		}
+	}
+	return nil
+}
+
+// resourceAudience returns the audience of a token requested for resources.
+// granted are the audiences the grant already settled on, e.g. the resources
+// of the authorization request or the audience of a subject token; the
+// requested resources may only narrow them.
+func resourceAudience(client *Client, resources, granted []string) ([]string, error) {
+	if len(resources) == 0 {
+		return granted, nil
+	}
+	if err := checkResources(client, resources); err != nil {
+		return nil, err
+	}
+	if len(granted) > 0 {
+		for _, r := range resources {
+			if !slices.Contains(granted, r) {
+				return nil, newError(http.StatusBadRequest, CodeInvalidTarget, "resource "+r+" was not granted", nil)
+			}
+		}
+	}
+	return resources, nil
+}
@@ -0,0 +1,129 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+var (
+	ErrInvalidResource         = errors.New("oauth: resource must be an absolute URI without a fragment")
+	ErrPrivateEncryptionKey    = errors.New("oauth: encryption key must not contain private key material")
+	ErrEncryptionKeyUse        = errors.New("oauth: encryption key use must be enc")
+	ErrResourceServerNotFound  = errors.New("oauth: resource server not found")
+	errMultipleEncryptedTarget = errors.New("a token for an encrypted resource cannot have other audiences")
+)
+
+// ResourceServer is a resource server that receives its access tokens
+// encrypted to EncryptionKey, so that their claims are not readable by the
+// client or the browser.
+type ResourceServer struct {
+	Resource      string     `json:"resource"`
+	EncryptionKey verify.JWK `json:"encryption_jwk"`
+	EncryptionAlg string     `json:"encryption_alg"`
+}
+
+// RegisterResourceServer sets the encryption key of resource, replacing any
+// earlier one. alg may be empty to pick the algorithm from the key type.
+func (s *Service) RegisterResourceServer(ctx context.Context, resource string, jwk verify.JWK, alg string) (*ResourceServer, error) {
+	if !s.validResource(resource) {
+		return nil, ErrInvalidResource
+	}
+	if jwk.D != "" {
+		return nil, ErrPrivateEncryptionKey
+	}
+	if jwk.Use != "" && jwk.Use != "enc" {
+		return nil, ErrEncryptionKeyUse
+	}
+	pub, err := jwk.PublicKey()
+	if err != nil {
+		return nil, key.ErrUnsupportedEncryptionKey
+	}
+	if alg == "" {
+		alg = jwk.Alg
+	}
+	if alg, err = key.EncryptionAlgFor(pub, alg); err != nil {
+		return nil, err
+	}
+	jwk.Alg = alg
+
+	aud, err := s.q.GetAudience(ctx, db.GetAudienceParams{
+		Audience: resource,
+		Tenant:   s.tenant,
+	})
+	switch {
+	case err == nil && aud.AccessTokenFormat == key.FormatPASETO:
+		return nil, ErrEncryptedPASETO
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	raw, err := json.Marshal(jwk)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.q.UpsertResourceServer(ctx, db.UpsertResourceServerParams{
+		Resource:      resource,
+		EncryptionJWK: raw,
+		EncryptionAlg: alg,
+		Tenant:        s.tenant,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &ResourceServer{Resource: resource, EncryptionKey: jwk, EncryptionAlg: alg}, nil
+}
+
+// DeleteResourceServer stops encrypting tokens for resource.
+func (s *Service) DeleteResourceServer(ctx context.Context, resource string) error {
+	n, err := s.q.DeleteResourceServer(ctx, db.DeleteResourceServerParams{
+		Resource: resource,
+		Tenant:   s.tenant,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrResourceServerNotFound
+	}
+	return nil
+}
+
+// encryptionKey returns the key to encrypt a token for aud to, or nil when
+// no audience registered one. A JWE has a single recipient, so an encrypted
+// audience must be the only one.
+func (s *Service) encryptionKey(ctx context.Context, aud []string) (*key.EncryptionKey, error) {
+	var ek *key.EncryptionKey
+	for _, a := range aud {
+		row, err := s.q.GetResourceServer(ctx, db.GetResourceServerParams{
+			Resource: a,
+			Tenant:   s.tenant,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, serverError(err)
+		}
+		if len(aud) > 1 {
+			return nil, newError(http.StatusBadRequest, CodeInvalidTarget, "resource "+a+" must be requested alone", errMultipleEncryptedTarget)
+		}
+
+		var jwk verify.JWK
+		if err := json.Unmarshal(row.EncryptionJWK, &jwk); err != nil {
+			return nil, serverError(err)
+		}
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			return nil, serverError(err)
+		}
+		ek = &key.EncryptionKey{KID: jwk.Kid, Alg: row.EncryptionAlg, Key: pub}
+	}
+	return ek, nil
+}
@@ -0,0 +1,388 @@
+// Package oauth implements the OAuth 2.0 authorization, pushed authorization
+// request, token, device authorization and client registration endpoints.
+package oauth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+const (
+	GrantClientCredentials = "client_credentials"
+
+	TokenTypeBearer = "Bearer"
+	TokenTypeDPoP   = "DPoP"
+
+	TokenPath = "/oauth/token"
+)
+
+type Service struct {
+	cfg       Config
+	tenant    string
+	q         db.Querier
+	mgr       *key.Manager
+	nonces    *NonceSource
+	replay    verify.ReplayCache
+	devices   DeviceStore
+	kv        KVStore
+	perms     PermissionsProvider
+	roles     RolesProvider
+	mtlsRoots *x509.CertPool
+	// tokens validates tokens issued by this service, e.g. subject tokens
+	// of a token exchange.
+	tokens *verify.Verifier
+	now    func() time.Time
+
+	// hook is called before signing access tokens; hookFailure is the
+	// policy of clients without their own for when it fails.
+	hook        TokenHook
+	hookFailure string
+
+	// logoutClient posts logout tokens to back-channel logout URIs.
+	logoutClient *http.Client
+
+	// jwksClient fetches the jwks_uri of clients.
+	jwksClient *http.Client
+	jwksMu     sync.Mutex
+	remoteJWKS map[string]*verify.RemoteJWKS
+}
+
+// NewService returns the token service. Without options, one-time values
+// such as assertion jti, device codes and authorization codes are kept in
+// memory, which is only correct for a single instance.
+func NewService(cfg Config, q db.Querier, mgr *key.Manager, opts ...Option) (*Service, error) {
+	nonces, err := NewNonceSource(cfg.DPoP.NonceSecret, cfg.DPoP.NonceLifetime)
+	if err != nil {
+		return nil, err
+	}
+	mtlsRoots, err := loadCertPool(cfg.MTLS.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		cfg:       cfg,
+		q:         q,
+		mgr:       mgr,
+		nonces:    nonces,
+		replay:    verify.NewMemoryReplayCache(),
+		devices:   NewMemoryDeviceStore(),
+		kv:        NewMemoryKVStore(),
+		mtlsRoots: mtlsRoots,
+		tokens: verify.NewVerifier(managerKeys{mgr},
+			verify.WithIssuer(mgr.Issuer),
+			verify.WithTokenType(verify.TokenTypeAccessToken),
+			verify.WithPASETOKeys(managerPASERKs{mgr}),
+		),
+		now: time.Now,
+
+		logoutClient: newLogoutClient(cfg.Logout.Timeout),
+
+		jwksClient: newPublicClient(jwksFetchTimeout, errJWKSAddress, errJWKSRedirect),
+		remoteJWKS: make(map[string]*verify.RemoteJWKS),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// TokenEndpoint is the absolute token endpoint URL DPoP proofs are checked
+// against.
+func (s *Service) TokenEndpoint() string {
+	return strings.TrimRight(s.cfg.BaseURL, "/") + TokenPath
+}
+
+// Nonce returns a fresh DPoP server nonce, or "" when nonces are disabled.
+func (s *Service) Nonce() string {
+	if !s.cfg.DPoP.RequireNonce {
+		return ""
+	}
+	return s.nonces.Issue()
+}
+
+// VerifyAccessToken checks the signature and registered claims of an access
+// token issued by this service. Sender constraints are left to the resource
+// server the token was presented to.
+func (s *Service) VerifyAccessToken(ctx context.Context, token string) (*verify.Claims, error) {
+	return s.tokens.Verify(ctx, token)
+}
+
+type TokenRequest struct {
+	GrantType string
+	Scope     string
+	Client    ClientCredentials
+	// DPoP holds every DPoP header sent with the request.
+	DPoP []string
+
+	// Resource are the resource indicators (RFC 8707 section 2) the token
+	// is requested for; they become its audience.
+	Resource []string
+
+	// Code, RedirectURI and CodeVerifier redeem an authorization code
+	// (RFC 6749 section 4.1.3, RFC 7636 section 4.5).
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+
+	// Assertion is the JWT of the jwt-bearer grant (RFC 7523 section 2.1).
+	Assertion string
+
+	// DeviceCode is polled with the device_code grant (RFC 8628 section
+	// 3.4).
+	DeviceCode string
+
+	// Token exchange parameters (RFC 8693 section 2.1).
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	RequestedTokenType string
+	Audience           []string
+}
+
+type TokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+// grant is what a grant type handler decided to issue.
+type grant struct {
+	sub             string
+	scopes          []string
+	aud             []string
+	act             *key.Actor
+	ttl             time.Duration
+	issuedTokenType string
+	// authTime, amr and acr describe the user authentication behind the
+	// grant, if there was one, and sid the browser session it was made in.
+	authTime time.Time
+	amr      []string
+	acr      string
+	sid      string
+	// subjectCnf is the sender constraint of an exchanged subject token,
+	// which the request must satisfy too.
+	subjectCnf *verify.Confirmation
+}
+
+func (s *Service) Token(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GrantType == "" {
+		return nil, invalidRequest("grant_type is required")
+	}
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, newError(http.StatusBadRequest, CodeUnauthorizedClient, "grant type not allowed for this client", nil)
+	}
+
+	var g *grant
+	switch req.GrantType {
+	case GrantClientCredentials:
+		g, err = s.clientCredentials(client, req)
+	case GrantTokenExchange:
+		g, err = s.tokenExchange(ctx, client, req)
+	case GrantJWTBearer:
+		g, err = s.jwtBearer(ctx, client, req)
+	case GrantDeviceCode:
+		g, err = s.deviceCode(ctx, client, req)
+	case GrantAuthorizationCode:
+		g, err = s.authorizationCodeGrant(ctx, client, req)
+	default:
+		return nil, newError(http.StatusBadRequest, CodeUnsupportedGrantType, "unsupported grant_type", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if g.aud, err = resourceAudience(client, req.Resource, g.aud); err != nil {
+		return nil, err
+	}
+	cnf := &key.Confirmation{}
+	if cnf.JKT, err = s.checkDPoP(ctx, client, req.DPoP); err != nil {
+		return nil, err
+	}
+	if cnf.X5TS256, err = certificateBinding(client, req.Client.Certificates); err != nil {
+		return nil, err
+	}
+	if g.subjectCnf != nil {
+		if err := requireBinding(cnf, g.subjectCnf, req.Client.Certificates); err != nil {
+			return nil, err
+		}
+	}
+
+	aud := g.aud
+	if len(aud) == 0 {
+		aud = []string{s.cfg.Audience}
+	}
+	ek, err := s.encryptionKey(ctx, aud)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.tokenPolicy(ctx, client, aud)
+	if err != nil {
+		return nil, err
+	}
+	if g.ttl <= 0 || g.ttl > policy.lifetimes.AccessToken {
+		g.ttl = policy.lifetimes.AccessToken
+	}
+
+	newSigner := key.NewSigner
+	// An encrypted token is a nested JWT, so a resource server with an
+	// encryption key gets JWTs even from clients preferring PASETO.
+	if policy.format == key.FormatPASETO && ek == nil {
+		newSigner = key.NewPASETOSigner
+	}
+	signer, err := newSigner(ctx, s.mgr, s.cfg.Audience, s.mgr.Issuer, g.ttl)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	perms, err := s.permissions(ctx, client, g.sub, aud, g.scopes)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.customClaims(ctx, policy.claims, PermissionsRequest{
+		Tenant:   s.tenant,
+		Subject:  g.sub,
+		ClientID: client.ID,
+		Audience: aud,
+		Scopes:   g.scopes,
+	}, perms)
+	if err != nil {
+		return nil, err
+	}
+	opts := []key.SignOption{
+		key.WithClientID(client.ID),
+		key.WithAuthentication(g.authTime, g.acr),
+		key.WithAMR(g.amr),
+		key.WithSessionID(g.sid),
+		key.WithPermissions(perms),
+		key.WithClaims(claims),
+	}
+	tokenType := TokenTypeBearer
+	if *cnf != (key.Confirmation{}) {
+		opts = append(opts, key.WithConfirmation(cnf))
+	}
+	if cnf.JKT != "" {
+		tokenType = TokenTypeDPoP
+	}
+	if len(g.aud) > 0 {
+		opts = append(opts, key.WithAudience(g.aud...))
+	}
+	if g.act != nil {
+		opts = append(opts, key.WithActor(g.act))
+	}
+	rc := signer.Claims(g.sub, g.scopes, opts...)
+	if err := s.hookClaims(ctx, client, req.GrantType, &rc); err != nil {
+		return nil, err
+	}
+	token, err := signer.SignClaims(rc)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	if ek != nil {
+		// Sign then encrypt, so the resource server can still check who
+		// issued the token after decrypting it.
+		if token, err = key.Encrypt(token, *ek); err != nil {
+			return nil, serverError(err)
+		}
+	}
+
+	return &TokenResponse{
+		AccessToken:     token,
+		IssuedTokenType: g.issuedTokenType,
+		TokenType:       tokenType,
+		ExpiresIn:       int64(g.ttl.Seconds()),
+		Scope:           strings.Join(g.scopes, " "),
+	}, nil
+}
+
+func (s *Service) clientCredentials(client *Client, req TokenRequest) (*grant, error) {
+	if client.AuthMethod == AuthMethodNone {
+		return nil, newError(http.StatusBadRequest, CodeUnauthorizedClient, "public clients cannot use client_credentials", nil)
+	}
+	scopes, err := grantScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	return &grant{sub: client.ID, scopes: scopes}, nil
+}
+
+// checkDPoP validates the DPoP proof of a token request and returns the JWK
+// thumbprint to bind the token to, or "" when no proof was sent.
+func (s *Service) checkDPoP(ctx context.Context, client *Client, proofs []string) (string, error) {
+	switch {
+	case len(proofs) == 0 && client.DPoPBoundAccessTokens:
+		return "", invalidRequest("client requires DPoP-bound access tokens")
+	case len(proofs) == 0:
+		return "", nil
+	case len(proofs) > 1:
+		return "", newError(http.StatusBadRequest, CodeInvalidDPoPProof, "multiple DPoP headers", nil)
+	}
+
+	opts := verify.DPoPOptions{
+		Method: http.MethodPost,
+		URL:    s.TokenEndpoint(),
+		MaxAge: s.cfg.DPoP.ProofMaxAge,
+		Replay: s.replay,
+	}
+	if s.cfg.DPoP.RequireNonce {
+		opts.Nonce = s.nonces.Valid
+	}
+
+	proof, err := verify.VerifyDPoPProof(ctx, proofs[0], opts)
+	switch {
+	case errors.Is(err, verify.ErrUseDPoPNonce):
+		return "", newError(http.StatusBadRequest, CodeUseDPoPNonce, "authorization server requires nonce in DPoP proof", err)
+	case errors.Is(err, verify.ErrInvalidDPoPProof), errors.Is(err, verify.ErrDPoPReplay):
+		return "", newError(http.StatusBadRequest, CodeInvalidDPoPProof, "invalid DPoP proof", err)
+	case err != nil:
+		return "", serverError(err)
+	}
+
+	return proof.JKT, nil
+}
+
+// certificateBinding returns the certificate thumbprint to bind the token
+// to, or "" when the client does not use certificate-bound tokens.
+func certificateBinding(client *Client, chain []*x509.Certificate) (string, error) {
+	if !client.CertificateBoundAccessTokens {
+		return "", nil
+	}
+	if len(chain) == 0 {
+		return "", invalidRequest("client requires certificate-bound access tokens")
+	}
+	return verify.CertificateThumbprint(chain[0]), nil
+}
+
+// grantScopes returns the requested scopes, or every scope of the client when
+// none were requested.
+func grantScopes(client *Client, scope string) ([]string, error) {
+	requested := splitScope(scope)
+	if len(requested) == 0 {
+		return client.Scopes, nil
+	}
+	for _, sc := range requested {
+		if !slices.Contains(client.Scopes, sc) {
+			return nil, newError(http.StatusBadRequest, CodeInvalidScope, "scope "+sc+" is not allowed for this client", nil)
+		}
+	}
+	return requested, nil
+}
+
+func splitScope(scope string) []string {
+	return strings.Fields(scope)
+}
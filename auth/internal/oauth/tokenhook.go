@@ -0,0 +1,91 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+)
+
+// What happens to a token when the token hook fails or times out.
+const (
+	// TokenHookFailOpen issues the token without the hook's claims.
+	TokenHookFailOpen = "open"
+	// TokenHookFailClosed fails the token request.
+	TokenHookFailClosed = "closed"
+)
+
+var ErrInvalidTokenHookFailure = errors.New("oauth: token_hook_failure must be open or closed")
+
+// TokenHookRequest describes an access token about to be signed.
+type TokenHookRequest struct {
+	Tenant    string
+	ClientID  string
+	GrantType string
+	// Claims are the claims the token would carry without the hook.
+	Claims map[string]any
+}
+
+// TokenHook adds claims kept by other systems to access tokens. It is called
+// once per token, right before signing, and must answer quickly: what
+// happens when it does not is up to the client's TokenHookFailure.
+type TokenHook interface {
+	Claims(ctx context.Context, req TokenHookRequest) (map[string]any, error)
+}
+
+// ReservedClaim reports whether name is a claim the service sets itself,
+// which neither claim templates nor the token hook may set.
+func ReservedClaim(name string) bool {
+	return slices.Contains(reservedClaims, name)
+}
+
+// hookClaims runs the token hook for rc and adds the claims it returned.
+// Reserved claims are dropped, and so is everything when the hook failed on
+// a client failing open.
+func (s *Service) hookClaims(ctx context.Context, client *Client, grantType string, rc *key.CustomClaims) error {
+	if s.hook == nil {
+		return nil
+	}
+	raw, err := json.Marshal(rc)
+	if err != nil {
+		return serverError(err)
+	}
+	var pending map[string]any
+	if err := json.Unmarshal(raw, &pending); err != nil {
+		return serverError(err)
+	}
+
+	claims, err := s.hook.Claims(ctx, TokenHookRequest{
+		Tenant:    s.tenant,
+		ClientID:  client.ID,
+		GrantType: grantType,
+		Claims:    pending,
+	})
+	if err != nil {
+		failure := client.TokenHookFailure
+		if failure == "" {
+			failure = s.hookFailure
+		}
+		if failure == TokenHookFailOpen {
+			log.Warnf("oauth: issuing token for %s without token hook claims: %v", client.ID, err)
+			return nil
+		}
+		return serverError(err)
+	}
+
+	extra := maps.Clone(rc.Extra)
+	if extra == nil {
+		extra = make(map[string]any, len(claims))
+	}
+	for name, v := range claims {
+		if name != "" && !ReservedClaim(name) {
+			extra[name] = v
+		}
+	}
+	rc.Extra = extra
+	return nil
+}
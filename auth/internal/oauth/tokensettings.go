@@ -0,0 +1,416 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+)
+
+var (
+	ErrInvalidTokenTTL      = errors.New("oauth: token lifetimes must not be negative")
+	ErrReservedClaim        = errors.New("oauth: claim templates cannot set registered or reserved claims")
+	ErrInvalidClaimTemplate = errors.New("oauth: claim templates may only refer to ${sub}, ${client_id}, ${tenant}, ${scope}, ${aud}, ${roles} and ${permissions}")
+	ErrClaimTemplateSize    = errors.New("oauth: claim template is too large")
+	ErrClientNotFound       = errors.New("oauth: client not found")
+	errMixedClaims          = errors.New("audiences with different values for a claim")
+)
+
+// maxClaimTemplateSize bounds the JSON size of a claim template, which is
+// copied into every token.
+const maxClaimTemplateSize = 4096
+
+// reservedClaims cannot be set by a claim template: the registered claims
+// of RFC 7519 section 4.1 and those this service, RFC 9068 or OpenID
+// Connect give a meaning.
+var reservedClaims = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti",
+	"scope", "scopes", "client_id", "auth_time", "acr", "amr", "permissions",
+	"cnf", "act", "may_act", "azp", "nonce", "sid", "at_hash", "c_hash",
+	"s_hash", "typ", "events",
+}
+
+// Attributes of a token that claim templates can refer to.
+const (
+	attrSub         = "sub"
+	attrClientID    = "client_id"
+	attrTenant      = "tenant"
+	attrScope       = "scope"
+	attrAud         = "aud"
+	attrRoles       = "roles"
+	attrPermissions = "permissions"
+)
+
+var (
+	templateAttrs = []string{attrSub, attrClientID, attrTenant, attrScope, attrAud, attrRoles, attrPermissions}
+	templateRef   = regexp.MustCompile(`\$\{([^}]*)\}`)
+)
+
+// RolesProvider returns the roles of a token's subject for the ${roles}
+// reference of claim templates. Without one, ${roles} is empty.
+type RolesProvider interface {
+	Roles(ctx context.Context, req PermissionsRequest) ([]string, error)
+}
+
+// TokenSettings are the token lifetimes and custom claims of a client or an
+// audience. Lifetimes are in seconds; zero leaves the choice to the client,
+// then to the service config.
+type TokenSettings struct {
+	AccessTokenTTL int64 `json:"access_token_ttl,omitempty"`
+	// RefreshTokenTTL and IDTokenTTL are stored and validated but not yet
+	// applied, as no refresh or ID tokens are issued.
+	RefreshTokenTTL int64 `json:"refresh_token_ttl,omitempty"`
+	IDTokenTTL      int64 `json:"id_token_ttl,omitempty"`
+	// Claims is added to the claims of every access token.
+	Claims ClaimTemplate `json:"claims,omitempty"`
+}
+
+// Validate checks the lifetimes and the claim template.
+func (t TokenSettings) Validate() error {
+	if t.AccessTokenTTL < 0 || t.RefreshTokenTTL < 0 || t.IDTokenTTL < 0 {
+		return ErrInvalidTokenTTL
+	}
+	return t.Claims.Validate()
+}
+
+// ClaimTemplate maps claim names to JSON values. Strings may refer to
+// attributes of the token as ${name}: a string that is only a reference
+// takes the attribute's value, so "${roles}" becomes an array, while a
+// reference within a longer string is replaced by the value's text, list
+// items separated by spaces.
+type ClaimTemplate map[string]any
+
+// Validate rejects reserved claim names, unknown references and templates
+// larger than 4 KiB.
+func (t ClaimTemplate) Validate() error {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if len(raw) > maxClaimTemplateSize {
+		return ErrClaimTemplateSize
+	}
+	for name, v := range t {
+		if name == "" || ReservedClaim(name) {
+			return ErrReservedClaim
+		}
+		for ref := range templateRefs(v) {
+			if !slices.Contains(templateAttrs, ref) {
+				return ErrInvalidClaimTemplate
+			}
+		}
+	}
+	return nil
+}
+
+// refers reports whether any claim of t refers to attr.
+func (t ClaimTemplate) refers(attr string) bool {
+	for _, v := range t {
+		if _, ok := templateRefs(v)[attr]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expand returns the claims of t with references replaced by attrs.
+func (t ClaimTemplate) expand(attrs map[string]any) map[string]any {
+	if len(t) == 0 {
+		return nil
+	}
+	claims := make(map[string]any, len(t))
+	for name, v := range t {
+		claims[name] = expandValue(v, attrs)
+	}
+	return claims
+}
+
+func expandValue(v any, attrs map[string]any) any {
+	switch v := v.(type) {
+	case string:
+		if m := templateRef.FindStringSubmatch(v); m != nil && m[0] == v {
+			return attrs[m[1]]
+		}
+		return templateRef.ReplaceAllStringFunc(v, func(ref string) string {
+			switch a := attrs[ref[2:len(ref)-1]].(type) {
+			case string:
+				return a
+			case []string:
+				return strings.Join(a, " ")
+			default:
+				return ""
+			}
+		})
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = expandValue(e, attrs)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = expandValue(e, attrs)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// templateRefs returns the attributes v refers to.
+func templateRefs(v any) map[string]struct{} {
+	refs := make(map[string]struct{})
+	var walk func(any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			for _, m := range templateRef.FindAllStringSubmatch(v, -1) {
+				refs[m[1]] = struct{}{}
+			}
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(v)
+	return refs
+}
+
+// Lifetimes are the lifetimes of the tokens of a grant. Only AccessToken
+// is used; the others are decided for when refresh and ID tokens are
+// issued.
+type Lifetimes struct {
+	AccessToken  time.Duration
+	RefreshToken time.Duration
+	IDToken      time.Duration
+}
+
+// tokenPolicy is what the settings of the client and the audiences decided
+// about a token.
+type tokenPolicy struct {
+	format    string
+	lifetimes Lifetimes
+	claims    ClaimTemplate
+}
+
+// tokenPolicy returns the policy of a token for aud issued to client.
+// Settings of an audience win over the client's, which win over the config.
+// Across audiences, the shortest lifetime applies, and audiences that
+// disagree on the format or on a claim cannot share a token.
+func (s *Service) tokenPolicy(ctx context.Context, client *Client, aud []string) (*tokenPolicy, error) {
+	var (
+		format string
+		ttls   [3]int64
+		claims = make(ClaimTemplate)
+		set    = make(map[string]bool)
+	)
+	for _, a := range aud {
+		row, err := s.q.GetAudience(ctx, db.GetAudienceParams{
+			Audience: a,
+			Tenant:   s.tenant,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, serverError(err)
+		}
+		if row.AccessTokenFormat != "" {
+			if format != "" && format != row.AccessTokenFormat {
+				return nil, newError(http.StatusBadRequest, CodeInvalidTarget, "resource "+a+" must be requested separately", errMixedTokenFormats)
+			}
+			format = row.AccessTokenFormat
+		}
+		for i, ttl := range []int64{row.AccessTokenTTL, row.RefreshTokenTTL, row.IDTokenTTL} {
+			if ttl > 0 && (ttls[i] == 0 || ttl < ttls[i]) {
+				ttls[i] = ttl
+			}
+		}
+		tmpl, err := decodeClaimTemplate(row.ClaimsTemplate)
+		if err != nil {
+			return nil, serverError(err)
+		}
+		for name, v := range tmpl {
+			if set[name] && !reflect.DeepEqual(claims[name], v) {
+				return nil, newError(http.StatusBadRequest, CodeInvalidTarget, "resource "+a+" must be requested separately", errMixedClaims)
+			}
+			claims[name] = v
+			set[name] = true
+		}
+	}
+
+	if format == "" {
+		format = client.AccessTokenFormat
+	}
+	if format == "" {
+		format = key.FormatJWT
+	}
+	for name, v := range client.Tokens.Claims {
+		if !set[name] {
+			claims[name] = v
+		}
+	}
+	return &tokenPolicy{
+		format: format,
+		lifetimes: Lifetimes{
+			AccessToken:  lifetime(ttls[0], client.Tokens.AccessTokenTTL, s.cfg.AccessTokenTTL),
+			RefreshToken: lifetime(ttls[1], client.Tokens.RefreshTokenTTL, s.cfg.RefreshTokenTTL),
+			IDToken:      lifetime(ttls[2], client.Tokens.IDTokenTTL, s.cfg.IDTokenTTL),
+		},
+		claims: claims,
+	}, nil
+}
+
+// lifetime returns the first lifetime set of an audience's and a client's,
+// in seconds, or def.
+func lifetime(audience, client int64, def time.Duration) time.Duration {
+	switch {
+	case audience > 0:
+		return time.Duration(audience) * time.Second
+	case client > 0:
+		return time.Duration(client) * time.Second
+	default:
+		return def
+	}
+}
+
+// customClaims expands the claim template of a token.
+func (s *Service) customClaims(ctx context.Context, tmpl ClaimTemplate, req PermissionsRequest, perms []string) (map[string]any, error) {
+	if len(tmpl) == 0 {
+		return nil, nil
+	}
+	roles := []string{}
+	if s.roles != nil && tmpl.refers(attrRoles) {
+		r, err := s.roles.Roles(ctx, req)
+		if err != nil {
+			return nil, serverError(err)
+		}
+		if r != nil {
+			roles = r
+		}
+	}
+	if perms == nil {
+		perms = []string{}
+	}
+	return tmpl.expand(map[string]any{
+		attrSub:         req.Subject,
+		attrClientID:    req.ClientID,
+		attrTenant:      s.tenant,
+		attrScope:       strings.Join(req.Scopes, " "),
+		attrAud:         req.Audience,
+		attrRoles:       roles,
+		attrPermissions: perms,
+	}), nil
+}
+
+// ClientTokenSettings are the token settings of a client.
+type ClientTokenSettings struct {
+	TokenSettings
+	// TokenHookFailure is TokenHookFailOpen or TokenHookFailClosed, or
+	// empty to follow the service default.
+	TokenHookFailure string `json:"token_hook_failure,omitempty"`
+}
+
+// Validate checks the token settings and the token hook failure policy.
+func (t ClientTokenSettings) Validate() error {
+	if t.TokenHookFailure != "" && t.TokenHookFailure != TokenHookFailOpen && t.TokenHookFailure != TokenHookFailClosed {
+		return ErrInvalidTokenHookFailure
+	}
+	return t.TokenSettings.Validate()
+}
+
+// ClientTokenSettings returns the token settings of a client.
+func (s *Service) ClientTokenSettings(ctx context.Context, clientID string) (*ClientTokenSettings, error) {
+	row, err := s.q.GetClient(ctx, db.GetClientParams{
+		ClientID: clientID,
+		Tenant:   s.tenant,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := tokenSettingsFromRow(row.AccessTokenTTL, row.RefreshTokenTTL, row.IDTokenTTL, row.ClaimsTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientTokenSettings{TokenSettings: *tokens, TokenHookFailure: row.TokenHookFailure}, nil
+}
+
+// SetClientTokenSettings replaces the token settings of a client. Clients
+// cannot set these themselves through registration.
+func (s *Service) SetClientTokenSettings(ctx context.Context, clientID string, settings ClientTokenSettings) (*ClientTokenSettings, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	claims, err := encodeClaimTemplate(settings.Claims)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.q.UpdateClientTokenSettings(ctx, db.UpdateClientTokenSettingsParams{
+		ClientID:         clientID,
+		Tenant:           s.tenant,
+		AccessTokenTTL:   settings.AccessTokenTTL,
+		RefreshTokenTTL:  settings.RefreshTokenTTL,
+		IDTokenTTL:       settings.IDTokenTTL,
+		ClaimsTemplate:   claims,
+		TokenHookFailure: settings.TokenHookFailure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrClientNotFound
+	}
+	return &settings, nil
+}
+
+func tokenSettingsFromRow(access, refresh, id int64, claims json.RawMessage) (*TokenSettings, error) {
+	tmpl, err := decodeClaimTemplate(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenSettings{
+		AccessTokenTTL:  access,
+		RefreshTokenTTL: refresh,
+		IDTokenTTL:      id,
+		Claims:          tmpl,
+	}, nil
+}
+
+func decodeClaimTemplate(raw json.RawMessage) (ClaimTemplate, error) {
+	var tmpl ClaimTemplate
+	if len(raw) == 0 {
+		return tmpl, nil
+	}
+	if err := json.Unmarshal(raw, &tmpl); err != nil {
+		return nil, err
+	}
+	if len(tmpl) == 0 {
+		return nil, nil
+	}
+	return tmpl, nil
+}
+
+func encodeClaimTemplate(tmpl ClaimTemplate) (json.RawMessage, error) {
+	if tmpl == nil {
+		tmpl = ClaimTemplate{}
+	}
+	return json.Marshal(tmpl)
+}
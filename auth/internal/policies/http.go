@@ -0,0 +1,80 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+const maxResponseSize = 1 << 20
+
+// permissionsRequest and permissionsResponse are the bodies of the JSON API:
+// a POST of the former to the configured URL answered by the latter.
+type permissionsRequest struct {
+	Tenant   string   `json:"tenant,omitempty"`
+	Subject  string   `json:"subject"`
+	ClientID string   `json:"client_id"`
+	Audience []string `json:"audience"`
+	Scopes   []string `json:"scopes"`
+}
+
+type permissionsResponse struct {
+	Permissions []string `json:"permissions"`
+}
+
+type httpTransport struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newHTTPTransport(url, token string) *httpTransport {
+	return &httpTransport{url: url, token: token, client: &http.Client{}}
+}
+
+func (t *httpTransport) permissions(ctx context.Context, req oauth.PermissionsRequest) ([]string, error) {
+	body, err := json.Marshal(permissionsRequest{
+		Tenant:   req.Tenant,
+		Subject:  req.Subject,
+		ClientID: req.ClientID,
+		Audience: req.Audience,
+		Scopes:   req.Scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	if t.token != "" {
+		r.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("permissions endpoint returned %s", resp.Status)
+	}
+
+	var out permissionsResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode permissions: %w", err)
+	}
+	return out.Permissions, nil
+}
+
+func (t *httpTransport) close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
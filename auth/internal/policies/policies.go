@@ -0,0 +1,189 @@
+// Package policies is a client of the policies service, which decides the
+// permissions carried by access tokens. It speaks either the service's JSON
+// API over HTTP or its gRPC API (policies.proto), caches answers and falls
+// back on a configured policy when the service cannot answer in time.
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+// What to do when the policies service fails or times out.
+const (
+	// FallbackFail fails the token request.
+	FallbackFail = "fail"
+	// FallbackEmpty issues the token without permissions.
+	FallbackEmpty = "empty"
+	// FallbackStale issues the token with the last permissions fetched for
+	// the same request, if they are younger than MaxStale, and fails it
+	// otherwise.
+	FallbackStale = "stale"
+)
+
+var (
+	ErrInvalidFallback = errors.New("policies: fallback must be fail, empty or stale")
+	// ErrStaleWithoutCache rejects FallbackStale with caching disabled,
+	// which would leave it nothing to fall back on.
+	ErrStaleWithoutCache = errors.New("policies: the stale fallback needs a positive cache TTL, cache size and max stale")
+)
+
+type Config struct {
+	// URL is the policies service: an http:// or https:// URL of its
+	// permissions endpoint, or grpc://host:port (grpcs:// for TLS). The
+	// built-in roles of package rbac are used when it is empty.
+	URL string `env:"URL"`
+	// Token is sent as a bearer token with every request when set.
+	Token   string        `env:"TOKEN"`
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"2s"`
+	// CacheTTL is how long an answer is reused; zero disables caching.
+	CacheTTL  time.Duration `env:"CACHE_TTL" envDefault:"1m"`
+	CacheSize int           `env:"CACHE_SIZE" envDefault:"10000"`
+	Fallback  string        `env:"FALLBACK" envDefault:"fail"`
+	// MaxStale bounds the age of permissions handed out by FallbackStale.
+	MaxStale time.Duration `env:"MAX_STALE" envDefault:"1h"`
+}
+
+// transport asks the policies service for the permissions of a token.
+type transport interface {
+	permissions(ctx context.Context, req oauth.PermissionsRequest) ([]string, error)
+	close() error
+}
+
+// Client is an oauth.PermissionsProvider backed by the policies service.
+type Client struct {
+	cfg Config
+	t   transport
+	now func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	perms   []string
+	fetched time.Time
+}
+
+// NewClient returns a client of the policies service at cfg.URL.
+func NewClient(cfg Config) (*Client, error) {
+	if !slices.Contains([]string{FallbackFail, FallbackEmpty, FallbackStale}, cfg.Fallback) {
+		return nil, ErrInvalidFallback
+	}
+	if cfg.Fallback == FallbackStale && (cfg.CacheTTL <= 0 || cfg.CacheSize <= 0 || cfg.MaxStale <= 0) {
+		return nil, ErrStaleWithoutCache
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("policies: url: %w", err)
+	}
+
+	var t transport
+	switch u.Scheme {
+	case "http", "https":
+		t = newHTTPTransport(cfg.URL, cfg.Token)
+	case "grpc", "grpcs":
+		if t, err = newGRPCTransport(u.Host, u.Scheme == "grpcs", cfg.Token); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("policies: unsupported url scheme %q", u.Scheme)
+	}
+
+	return &Client{
+		cfg:   cfg,
+		t:     t,
+		now:   time.Now,
+		cache: make(map[string]cacheEntry),
+	}, nil
+}
+
+// Close releases the connection to the policies service.
+func (c *Client) Close() error {
+	return c.t.close()
+}
+
+// Permissions returns the permissions of the token described by req, from
+// the cache while it is fresh and from the policies service otherwise.
+func (c *Client) Permissions(ctx context.Context, req oauth.PermissionsRequest) ([]string, error) {
+	k := cacheKey(req)
+	now := c.now()
+	if e, ok := c.lookup(k); ok && now.Sub(e.fetched) < c.cfg.CacheTTL {
+		return slices.Clone(e.perms), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	perms, err := c.t.permissions(ctx, req)
+	if err == nil {
+		c.store(k, cacheEntry{perms: perms, fetched: now})
+		return slices.Clone(perms), nil
+	}
+
+	switch c.cfg.Fallback {
+	case FallbackEmpty:
+		log.Warnf("policies: issuing token for %s without permissions: %v", req.Subject, err)
+		return nil, nil
+	case FallbackStale:
+		if e, ok := c.lookup(k); ok && now.Sub(e.fetched) < c.cfg.MaxStale {
+			log.Warnf("policies: issuing token for %s with permissions from %s: %v", req.Subject, e.fetched.Format(time.RFC3339), err)
+			return slices.Clone(e.perms), nil
+		}
+	}
+	return nil, fmt.Errorf("policies: %w", err)
+}
+
+func (c *Client) lookup(k string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.cache[k]
+	return e, ok
+}
+
+// store caches e under k. Entries past both CacheTTL and MaxStale are of no
+// use and go first; when the cache is still full, arbitrary entries do.
+func (c *Client) store(k string, e cacheEntry) {
+	if c.cfg.CacheTTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.cache) >= c.cfg.CacheSize {
+		keep := max(c.cfg.CacheTTL, c.cfg.MaxStale)
+		for ck, ce := range c.cache {
+			if e.fetched.Sub(ce.fetched) >= keep {
+				delete(c.cache, ck)
+			}
+		}
+		for ck := range c.cache {
+			if len(c.cache) < c.cfg.CacheSize {
+				break
+			}
+			delete(c.cache, ck)
+		}
+	}
+	if c.cfg.CacheSize > 0 {
+		c.cache[k] = e
+	}
+}
+
+// cacheKey identifies req regardless of the order of its audience and
+// scopes. The same subject and client may have other permissions in another
+// tenant.
+func cacheKey(req oauth.PermissionsRequest) string {
+	aud := slices.Sorted(slices.Values(req.Audience))
+	scopes := slices.Sorted(slices.Values(req.Scopes))
+	b, _ := json.Marshal([]any{req.Tenant, req.Subject, req.ClientID, aud, scopes})
+	return string(b)
+}
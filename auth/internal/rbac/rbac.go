@@ -0,0 +1,282 @@
+// Package rbac is the built-in role based access control: permissions are
+// granted to roles, and roles are bound to subjects either everywhere or for
+// a single resource. Each tenant has roles and permissions of its own, the
+// root issuer's being tenant "". It fills the permissions claim of access
+// tokens when no policies service is configured.
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+const maxNameLength = 128
+
+var (
+	ErrInvalidName        = errors.New("rbac: names must be 1 to 128 printable characters without spaces")
+	ErrInvalidSubject     = errors.New("rbac: subject is required")
+	ErrPermissionExists   = errors.New("rbac: permission already exists")
+	ErrPermissionNotFound = errors.New("rbac: permission not found")
+	ErrRoleExists         = errors.New("rbac: role already exists")
+	ErrRoleNotFound       = errors.New("rbac: role not found")
+	ErrBindingExists      = errors.New("rbac: role binding already exists")
+	ErrBindingNotFound    = errors.New("rbac: role binding not found")
+)
+
+// Postgres error codes.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type Permission struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Permissions is only filled by GetRole.
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Binding grants Role to Subject, the sub of its tokens. A binding with a
+// Resource only counts for tokens with that resource in their audience.
+type Binding struct {
+	Subject   string    `json:"subject"`
+	Role      string    `json:"role"`
+	Resource  string    `json:"resource,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Service struct {
+	q db.Querier
+}
+
+func NewService(q db.Querier) *Service {
+	return &Service{q: q}
+}
+
+func (s *Service) CreatePermission(ctx context.Context, tenant, name, description string) (*Permission, error) {
+	if !validName(name) {
+		return nil, ErrInvalidName
+	}
+	err := s.q.CreatePermission(ctx, db.CreatePermissionParams{Name: name, Description: description, Tenant: tenant})
+	if pgCode(err) == uniqueViolation {
+		return nil, ErrPermissionExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Permission{Name: name, Description: description, CreatedAt: time.Now()}, nil
+}
+
+func (s *Service) ListPermissions(ctx context.Context, tenant string) ([]Permission, error) {
+	rows, err := s.q.ListPermissions(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	perms := make([]Permission, 0, len(rows))
+	for _, r := range rows {
+		perms = append(perms, Permission{Name: r.Name, Description: r.Description, CreatedAt: r.CreatedAt})
+	}
+	return perms, nil
+}
+
+// DeletePermission deletes the permission and takes it away from every role.
+func (s *Service) DeletePermission(ctx context.Context, tenant, name string) error {
+	n, err := s.q.DeletePermission(ctx, db.DeletePermissionParams{Name: name, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrPermissionNotFound
+	}
+	return nil
+}
+
+func (s *Service) CreateRole(ctx context.Context, tenant, name, description string) (*Role, error) {
+	if !validName(name) {
+		return nil, ErrInvalidName
+	}
+	err := s.q.CreateRole(ctx, db.CreateRoleParams{Name: name, Description: description, Tenant: tenant})
+	if pgCode(err) == uniqueViolation {
+		return nil, ErrRoleExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Role{Name: name, Description: description, CreatedAt: time.Now()}, nil
+}
+
+// GetRole returns the role with the permissions it grants.
+func (s *Service) GetRole(ctx context.Context, tenant, name string) (*Role, error) {
+	row, err := s.q.GetRole(ctx, db.GetRoleParams{Name: name, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	perms, err := s.q.ListRolePermissions(ctx, db.ListRolePermissionsParams{Role: name, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	return &Role{
+		Name:        row.Name,
+		Description: row.Description,
+		CreatedAt:   row.CreatedAt,
+		Permissions: perms,
+	}, nil
+}
+
+func (s *Service) ListRoles(ctx context.Context, tenant string) ([]Role, error) {
+	rows, err := s.q.ListRoles(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]Role, 0, len(rows))
+	for _, r := range rows {
+		roles = append(roles, Role{Name: r.Name, Description: r.Description, CreatedAt: r.CreatedAt})
+	}
+	return roles, nil
+}
+
+// DeleteRole deletes the role along with its bindings.
+func (s *Service) DeleteRole(ctx context.Context, tenant, name string) error {
+	n, err := s.q.DeleteRole(ctx, db.DeleteRoleParams{Name: name, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// Grant lets role grant permission. Granting it again is not an error.
+func (s *Service) Grant(ctx context.Context, tenant, role, permission string) error {
+	err := s.q.AddRolePermission(ctx, db.AddRolePermissionParams{Role: role, Permission: permission, Tenant: tenant})
+	if pgCode(err) == foreignKeyViolation {
+		return s.missing(ctx, tenant, role)
+	}
+	return err
+}
+
+// Revoke stops role from granting permission.
+func (s *Service) Revoke(ctx context.Context, tenant, role, permission string) error {
+	n, err := s.q.RemoveRolePermission(ctx, db.RemoveRolePermissionParams{Role: role, Permission: permission, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return s.missing(ctx, tenant, role)
+	}
+	return nil
+}
+
+// missing tells whether role or permission was missing after a write
+// touching both failed to find them.
+func (s *Service) missing(ctx context.Context, tenant, role string) error {
+	_, err := s.q.GetRole(ctx, db.GetRoleParams{Name: role, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrPermissionNotFound
+}
+
+// Bind grants role to subject, for resource only unless it is empty.
+func (s *Service) Bind(ctx context.Context, tenant, subject, role, resource string) (*Binding, error) {
+	if subject == "" {
+		return nil, ErrInvalidSubject
+	}
+	err := s.q.CreateRoleBinding(ctx, db.CreateRoleBindingParams{Subject: subject, Role: role, Resource: resource, Tenant: tenant})
+	switch pgCode(err) {
+	case uniqueViolation:
+		return nil, ErrBindingExists
+	case foreignKeyViolation:
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Binding{Subject: subject, Role: role, Resource: resource, CreatedAt: time.Now()}, nil
+}
+
+func (s *Service) Unbind(ctx context.Context, tenant, subject, role, resource string) error {
+	n, err := s.q.DeleteRoleBinding(ctx, db.DeleteRoleBindingParams{Subject: subject, Role: role, Resource: resource, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBindingNotFound
+	}
+	return nil
+}
+
+func (s *Service) ListBindings(ctx context.Context, tenant, subject string) ([]Binding, error) {
+	if subject == "" {
+		return nil, ErrInvalidSubject
+	}
+	rows, err := s.q.ListRoleBindings(ctx, db.ListRoleBindingsParams{Subject: subject, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]Binding, 0, len(rows))
+	for _, r := range rows {
+		bindings = append(bindings, Binding{Subject: r.Subject, Role: r.Role, Resource: r.Resource, CreatedAt: r.CreatedAt})
+	}
+	return bindings, nil
+}
+
+// Permissions implements oauth.PermissionsProvider: a token gets the
+// permissions of the subject's roles in the issuing tenant bound everywhere
+// or to one of its audiences.
+func (s *Service) Permissions(ctx context.Context, req oauth.PermissionsRequest) ([]string, error) {
+	return s.q.ListEffectivePermissions(ctx, db.ListEffectivePermissionsParams{
+		Tenant:    req.Tenant,
+		Subject:   req.Subject,
+		Resources: req.Audience,
+	})
+}
+
+// Roles implements oauth.RolesProvider with the same bindings as
+// Permissions.
+func (s *Service) Roles(ctx context.Context, req oauth.PermissionsRequest) ([]string, error) {
+	return s.q.ListEffectiveRoles(ctx, db.ListEffectiveRolesParams{
+		Tenant:    req.Tenant,
+		Subject:   req.Subject,
+		Resources: req.Audience,
+	})
+}
+
+func validName(name string) bool {
+	if name == "" || len(name) > maxNameLength {
+		return false
+	}
+	return !strings.ContainsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsPrint(r)
+	})
+}
+
+func pgCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+// Package session keeps the browser sign-in sessions of users, so one sign-in
+// serves later authorization requests of any client of the same issuer. The
+// session cookie holds the session ID, which is also the sid claim of tokens,
+// and a secret only its hash is stored of, so knowing a sid is not enough to
+// take over the session.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+// Authentication methods (RFC 8176 section 2).
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	// AMRRecoveryCode is a single-use recovery code entered instead of a
+	// TOTP code. RFC 8176 registers no value for these.
+	AMRRecoveryCode = "recovery_code"
+	// AMRHardwareKey and AMRSoftwareKey are passkeys and security keys,
+	// the former kept in hardware they cannot leave.
+	AMRHardwareKey = "hwk"
+	AMRSoftwareKey = "swk"
+)
+
+// Authentication context classes, levels of assurance in the sense of
+// ISO/IEC 29115.
+const (
+	// ACRSingleFactor is the acr of a sign-in with one factor.
+	ACRSingleFactor = "1"
+	// ACRMultiFactor is the acr of a sign-in with two factors or more.
+	ACRMultiFactor = "2"
+)
+
+var ErrNotFound = errors.New("session: session not found or expired")
+
+type Config struct {
+	// Lifetime is how long a sign-in is reused before the user must sign
+	// in again.
+	Lifetime time.Duration `env:"LIFETIME" envDefault:"24h"`
+	// CookieName is the name of the root issuer's session cookie; tenants
+	// append their ID.
+	CookieName string `env:"COOKIE_NAME" envDefault:"authd_session"`
+	// CookieSecure sets the Secure attribute and the __Host- name prefix.
+	// Only turn it off to develop over plain HTTP on a host other than
+	// localhost.
+	CookieSecure bool `env:"COOKIE_SECURE" envDefault:"true"`
+}
+
+// Session is a user's sign-in at one issuer.
+type Session struct {
+	ID string `json:"id"`
+	// SecretHash is the base64url SHA-256 of the secret in the cookie.
+	SecretHash string `json:"secret_hash"`
+	Tenant     string `json:"tenant,omitempty"`
+	Subject    string `json:"sub"`
+	// Username is shown to the user when the session is reused.
+	Username  string    `json:"username,omitempty"`
+	AuthTime  time.Time `json:"auth_time"`
+	AMR       []string  `json:"amr,omitempty"`
+	ACR       string    `json:"acr,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Clients are the clients the session was used to sign in to, which
+	// are told when it ends.
+	Clients []string `json:"clients,omitempty"`
+}
+
+// Authentication returns how the user of s signed in, or nil for a nil s.
+func (s *Session) Authentication() *oauth.Authentication {
+	if s == nil {
+		return nil
+	}
+	return &oauth.Authentication{
+		Subject:  s.Subject,
+		AuthTime: s.AuthTime,
+		AMR:      slices.Clone(s.AMR),
+		ACR:      s.ACR,
+		SID:      s.ID,
+	}
+}
+
+// Store keeps sessions until they expire.
+type Store interface {
+	Put(ctx context.Context, s *Session) error
+	// Get returns the session with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Session, error)
+	// AddClient adds clientID to the clients of the session with the given
+	// ID.
+	AddClient(ctx context.Context, id, clientID string) error
+	Delete(ctx context.Context, id string) error
+}
+
+type Manager struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+}
+
+func NewManager(cfg Config, store Store) *Manager {
+	return &Manager{cfg: cfg, store: store, now: time.Now}
+}
+
+// CookieName returns the name of the session cookie of tenant.
+func (m *Manager) CookieName(tenant string) string {
+	name := m.cfg.CookieName
+	if tenant != "" {
+		name += "-" + tenant
+	}
+	if m.cfg.CookieSecure {
+		// The prefix makes browsers refuse the cookie unless it is Secure,
+		// host-only and for the whole site (RFC 6265bis section 4.1.3.2).
+		name = "__Host-" + name
+	}
+	return name
+}
+
+// CookieSecure reports whether the session cookie must only be sent over
+// HTTPS.
+func (m *Manager) CookieSecure() bool {
+	return m.cfg.CookieSecure
+}
+
+// Create starts a session for the user who signed in at tenant as authn
+// describes, and returns it with the value of its cookie.
+func (m *Manager) Create(ctx context.Context, tenant, username string, authn oauth.Authentication) (*Session, string, error) {
+	id, err := randomString(24)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return nil, "", err
+	}
+	now := m.now()
+	s := &Session{
+		ID:         id,
+		SecretHash: hashSecret(secret),
+		Tenant:     tenant,
+		Subject:    authn.Subject,
+		Username:   username,
+		AuthTime:   authn.AuthTime,
+		AMR:        authn.AMR,
+		ACR:        authn.ACR,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(m.cfg.Lifetime),
+	}
+	if err := m.store.Put(ctx, s); err != nil {
+		return nil, "", err
+	}
+	return s, id + "." + secret, nil
+}
+
+// Lookup returns the session of the cookie value at tenant, or ErrNotFound
+// when it is unknown, expired, for another tenant or has the wrong secret.
+func (m *Manager) Lookup(ctx context.Context, tenant, value string) (*Session, error) {
+	id, secret, ok := strings.Cut(value, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrNotFound
+	}
+	s, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Tenant != tenant || !m.now().Before(s.ExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(s.SecretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrNotFound
+	}
+	return s, nil
+}
+
+// AddClient records that the session with the given ID was used to sign in
+// to clientID.
+func (m *Manager) AddClient(ctx context.Context, id, clientID string) error {
+	return m.store.AddClient(ctx, id, clientID)
+}
+
+// Delete ends the session with the given ID.
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	return m.store.Delete(ctx, id)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,306 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+)
+
+// MemoryStore is a Store for a single process.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]Session)}
+}
+
+func (m *MemoryStore) Put(ctx context.Context, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, e := range m.sessions {
+		if !now.Before(e.ExpiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+	m.sessions[s.ID] = *s
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok || !time.Now().Before(s.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	s.Clients = slices.Clone(s.Clients)
+	return &s, nil
+}
+
+func (m *MemoryStore) AddClient(ctx context.Context, id, clientID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if !slices.Contains(s.Clients, clientID) {
+		s.Clients = append(slices.Clone(s.Clients), clientID)
+		m.sessions[id] = s
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+// RedisStore is a Store shared by every instance. Sessions expire with
+// their keys; the clients of a session are a set next to it.
+type RedisStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+func NewRedisStore(rdb *redis.Client) *RedisStore {
+	return &RedisStore{rdb: rdb, prefix: "auth:session:"}
+}
+
+func (r *RedisStore) Put(ctx context.Context, s *Session) error {
+	stored := *s
+	stored.Clients = nil
+	raw, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, r.prefix+s.ID, raw, time.Until(s.ExpiresAt))
+		if len(s.Clients) > 0 {
+			p.SAdd(ctx, r.clientsKey(s.ID), s.Clients)
+			p.ExpireAt(ctx, r.clientsKey(s.ID), s.ExpiresAt)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
+	var get *redis.StringCmd
+	var clients *redis.StringSliceCmd
+	_, err := r.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, r.prefix+id)
+		clients = p.SMembers(ctx, r.clientsKey(id))
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal([]byte(get.Val()), &s); err != nil {
+		return nil, err
+	}
+	s.Clients = clients.Val()
+	return &s, nil
+}
+
+func (r *RedisStore) AddClient(ctx context.Context, id, clientID string) error {
+	ttl, err := r.rdb.PTTL(ctx, r.prefix+id).Result()
+	if err != nil {
+		return err
+	}
+	// PTTL answers negative values for keys that are missing or do not
+	// expire.
+	if ttl <= 0 {
+		return ErrNotFound
+	}
+	_, err = r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.SAdd(ctx, r.clientsKey(id), clientID)
+		p.PExpire(ctx, r.clientsKey(id), ttl)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) Delete(ctx context.Context, id string) error {
+	return r.rdb.Del(ctx, r.prefix+id, r.clientsKey(id)).Err()
+}
+
+func (r *RedisStore) clientsKey(id string) string {
+	return r.prefix + id + ":clients"
+}
+
+// PostgresStore is a Store in the sessions and session_clients tables.
+// Expired rows are removed whenever a session is added.
+type PostgresStore struct {
+	q db.Querier
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+func NewPostgresStore(q db.Querier) *PostgresStore {
+	return &PostgresStore{q: q}
+}
+
+func (p *PostgresStore) Put(ctx context.Context, s *Session) error {
+	if _, err := p.q.DeleteExpiredSessions(ctx); err != nil {
+		return err
+	}
+	err := p.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:         s.ID,
+		SecretHash: s.SecretHash,
+		Tenant:     s.Tenant,
+		Subject:    s.Subject,
+		Username:   s.Username,
+		AuthTime:   s.AuthTime,
+		AMR:        s.AMR,
+		ACR:        s.ACR,
+		CreatedAt:  s.CreatedAt,
+		ExpiresAt:  s.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	for _, clientID := range s.Clients {
+		if err := p.AddClient(ctx, s.ID, clientID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PostgresStore) Get(ctx context.Context, id string) (*Session, error) {
+	row, err := p.q.GetSession(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	clients, err := p.q.ListSessionClients(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		ID:         row.ID,
+		SecretHash: row.SecretHash,
+		Tenant:     row.Tenant,
+		Subject:    row.Subject,
+		Username:   row.Username,
+		AuthTime:   row.AuthTime,
+		AMR:        row.AMR,
+		ACR:        row.ACR,
+		CreatedAt:  row.CreatedAt,
+		ExpiresAt:  row.ExpiresAt,
+		Clients:    clients,
+	}, nil
+}
+
+func (p *PostgresStore) AddClient(ctx context.Context, id, clientID string) error {
+	return p.q.AddSessionClient(ctx, db.AddSessionClientParams{
+		SessionID: id,
+		ClientID:  clientID,
+	})
+}
+
+func (p *PostgresStore) Delete(ctx context.Context, id string) error {
+	_, err := p.q.DeleteSession(ctx, id)
+	return err
+}
+
+// FallbackStore keeps sessions in a fast primary store, such as Redis, and
+// a durable fallback, such as Postgres. Writes go to both, and a session
+// the primary fails to return or has lost is read from the fallback, and
+// copied back in the latter case. A primary that is down slows sign-ins
+// down but does not break them.
+type FallbackStore struct {
+	primary  Store
+	fallback Store
+}
+
+var _ Store = (*FallbackStore)(nil)
+
+func NewFallbackStore(primary, fallback Store) *FallbackStore {
+	return &FallbackStore{primary: primary, fallback: fallback}
+}
+
+func (f *FallbackStore) Put(ctx context.Context, s *Session) error {
+	if err := f.fallback.Put(ctx, s); err != nil {
+		return err
+	}
+	if err := f.primary.Put(ctx, s); err != nil {
+		log.Warnf("session: primary store: %v", err)
+	}
+	return nil
+}
+
+func (f *FallbackStore) Get(ctx context.Context, id string) (*Session, error) {
+	s, err := f.primary.Get(ctx, id)
+	if err == nil {
+		return s, nil
+	}
+	missing := errors.Is(err, ErrNotFound)
+	if !missing {
+		log.Warnf("session: primary store: %v", err)
+	}
+
+	s, err = f.fallback.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if missing {
+		if err := f.primary.Put(ctx, s); err != nil {
+			log.Warnf("session: primary store: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// AddClient records the client in both stores. When the primary fails, its
+// copy of the session is dropped so the next Get reads the clients from the
+// fallback.
+func (f *FallbackStore) AddClient(ctx context.Context, id, clientID string) error {
+	if err := f.fallback.AddClient(ctx, id, clientID); err != nil {
+		return err
+	}
+	err := f.primary.AddClient(ctx, id, clientID)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	log.Warnf("session: primary store: %v", err)
+	if err := f.primary.Delete(ctx, id); err != nil {
+		log.Warnf("session: primary store: %v", err)
+	}
+	return nil
+}
+
+// Delete removes the session from both stores. It fails when either
+// fails, since a session left in the primary would come back to life.
+func (f *FallbackStore) Delete(ctx context.Context, id string) error {
+	return errors.Join(f.fallback.Delete(ctx, id), f.primary.Delete(ctx, id))
+}
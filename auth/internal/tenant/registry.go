@@ -0,0 +1,78 @@
+package tenant
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/key"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+// Issuer is what serves the routes of a tenant.
+type Issuer struct {
+	Tenant Tenant
+	Keys   *key.Manager
+	OAuth  *oauth.Service
+}
+
+// Registry builds the issuer of a tenant on first use and keeps it. Issuers
+// share the stores and options of the root issuer's token service.
+type Registry struct {
+	tenants *Service
+	root    *key.Manager
+	q       db.Querier
+	cfg     oauth.Config
+	opts    []oauth.Option
+
+	mu      sync.Mutex
+	issuers map[string]*Issuer
+}
+
+// NewRegistry returns a registry of the tenants of s. root is the root
+// issuer's key manager, and cfg and opts configure its token service.
+func NewRegistry(s *Service, root *key.Manager, q db.Querier, cfg oauth.Config, opts ...oauth.Option) *Registry {
+	return &Registry{
+		tenants: s,
+		root:    root,
+		q:       q,
+		cfg:     cfg,
+		opts:    opts,
+		issuers: make(map[string]*Issuer),
+	}
+}
+
+// Issuer returns the issuer of tenant id, or ErrNotFound. The tenant's
+// first signing keys are created when it is built.
+func (r *Registry) Issuer(ctx context.Context, id string) (*Issuer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if iss, ok := r.issuers[id]; ok {
+		return iss, nil
+	}
+
+	t, err := r.tenants.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	mgr := r.root.ForTenant(t.ID, t.Issuer)
+	if err := mgr.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	cfg := r.cfg
+	cfg.BaseURL = t.Issuer
+	cfg.Issuer = t.Issuer
+	// Tenants register clients with their own initial access token.
+	cfg.Registration.InitialAccessToken = ""
+	svc, err := oauth.NewService(cfg, r.q, mgr, append(slices.Clone(r.opts), oauth.WithTenant(t.ID))...)
+	if err != nil {
+		return nil, err
+	}
+
+	iss := &Issuer{Tenant: *t, Keys: mgr, OAuth: svc}
+	r.issuers[id] = iss
+	return iss, nil
+}
@@ -0,0 +1,193 @@
+// Package tenant manages the customer tenants of the service. Each tenant is
+// an issuer of its own below BASE_URL/t/{id}, with its own signing keys,
+// rotation schedule and clients; the keys and clients of one tenant are
+// never used for another.
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+// PathPrefix is where the routes of tenants are mounted, followed by the
+// tenant ID.
+const PathPrefix = "/t/"
+
+var (
+	ErrInvalidID             = errors.New("tenant: id must be 1 to 63 lowercase letters, digits or dashes, starting with a letter or digit")
+	ErrInvalidRotationPeriod = errors.New("tenant: key_rotation_period must not be negative")
+	ErrExists                = errors.New("tenant: tenant already exists")
+	ErrNotFound              = errors.New("tenant: tenant not found")
+)
+
+const uniqueViolation = "23505"
+
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Issuer is the iss of the tenant's tokens.
+	Issuer string `json:"issuer"`
+	// KeyRotationPeriod is the age in seconds at which cmd/rotate replaces
+	// the tenant's signing keys. Zero replaces them on every run.
+	KeyRotationPeriod int64 `json:"key_rotation_period"`
+	// RegistrationEnabled reports whether the tenant has an initial access
+	// token for dynamic client registration.
+	RegistrationEnabled bool      `json:"registration_enabled"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// RotationPeriod returns KeyRotationPeriod as a duration.
+func (t Tenant) RotationPeriod() time.Duration {
+	return time.Duration(t.KeyRotationPeriod) * time.Second
+}
+
+// IssuerURL returns the issuer of tenant id for a service at baseURL.
+func IssuerURL(baseURL, id string) string {
+	return strings.TrimRight(baseURL, "/") + PathPrefix + id
+}
+
+type Service struct {
+	q       db.Querier
+	baseURL string
+}
+
+// NewService returns the tenant service of the auth service at baseURL.
+func NewService(q db.Querier, baseURL string) *Service {
+	return &Service{q: q, baseURL: baseURL}
+}
+
+func (s *Service) Create(ctx context.Context, id, name string, keyRotationPeriod int64) (*Tenant, error) {
+	if !validID.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+	if keyRotationPeriod < 0 {
+		return nil, ErrInvalidRotationPeriod
+	}
+	err := s.q.CreateTenant(ctx, db.CreateTenantParams{
+		ID:                id,
+		Name:              name,
+		KeyRotationPeriod: keyRotationPeriod,
+	})
+	if pgCode(err) == uniqueViolation {
+		return nil, ErrExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return s.tenant(db.Tenant{
+		ID:                id,
+		Name:              name,
+		KeyRotationPeriod: keyRotationPeriod,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}), nil
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*Tenant, error) {
+	row, err := s.q.GetTenant(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.tenant(row), nil
+}
+
+func (s *Service) List(ctx context.Context) ([]Tenant, error) {
+	rows, err := s.q.ListTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenants := make([]Tenant, 0, len(rows))
+	for _, r := range rows {
+		tenants = append(tenants, *s.tenant(r))
+	}
+	return tenants, nil
+}
+
+// Update replaces the name and key rotation period of a tenant. A new period
+// applies from the next run of cmd/rotate.
+func (s *Service) Update(ctx context.Context, id, name string, keyRotationPeriod int64) (*Tenant, error) {
+	if keyRotationPeriod < 0 {
+		return nil, ErrInvalidRotationPeriod
+	}
+	n, err := s.q.UpdateTenant(ctx, db.UpdateTenantParams{
+		ID:                id,
+		Name:              name,
+		KeyRotationPeriod: keyRotationPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+	return s.Get(ctx, id)
+}
+
+// IssueRegistrationToken replaces the initial access token for dynamic
+// client registration at tenant id and returns the new one, which is only
+// kept hashed. Until a token is issued, a tenant registers no clients.
+func (s *Service) IssueRegistrationToken(ctx context.Context, id string) (string, error) {
+	token, hash, err := oauth.NewInitialAccessToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.setRegistrationTokenHash(ctx, id, hash); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// DisableRegistration removes the initial access token of tenant id.
+// Clients already registered are kept.
+func (s *Service) DisableRegistration(ctx context.Context, id string) error {
+	return s.setRegistrationTokenHash(ctx, id, "")
+}
+
+func (s *Service) setRegistrationTokenHash(ctx context.Context, id, hash string) error {
+	n, err := s.q.UpdateTenantRegistrationTokenHash(ctx, db.UpdateTenantRegistrationTokenHashParams{
+		ID:                    id,
+		RegistrationTokenHash: hash,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Service) tenant(r db.Tenant) *Tenant {
+	return &Tenant{
+		ID:                  r.ID,
+		Name:                r.Name,
+		Issuer:              IssuerURL(s.baseURL, r.ID),
+		KeyRotationPeriod:   r.KeyRotationPeriod,
+		RegistrationEnabled: r.RegistrationTokenHash != "",
+		CreatedAt:           r.CreatedAt,
+		UpdatedAt:           r.UpdatedAt,
+	}
+}
+
+func pgCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
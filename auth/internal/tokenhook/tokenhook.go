@@ -0,0 +1,218 @@
+// Package tokenhook calls an external HTTP endpoint for claims kept by other
+// systems, such as a billing plan or feature flags, before access tokens are
+// signed. Requests are signed following Standard Webhooks
+// (https://www.standardwebhooks.com) so the hook can tell they come from
+// this service, and only claims on an allow-list are taken from the answer.
+package tokenhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+)
+
+// Standard Webhooks headers.
+const (
+	HeaderID        = "webhook-id"
+	HeaderTimestamp = "webhook-timestamp"
+	HeaderSignature = "webhook-signature"
+)
+
+// EventType is the type of the requests sent to the hook.
+const EventType = "token.issuing"
+
+const (
+	secretPrefix    = "whsec_"
+	minSecretSize   = 24
+	maxResponseSize = 64 << 10
+)
+
+var (
+	ErrInvalidURL      = errors.New("tokenhook: url must be an http:// or https:// URL")
+	ErrInvalidSecret   = errors.New("tokenhook: secret must be whsec_ followed by at least 24 base64 encoded bytes")
+	ErrNoAllowedClaims = errors.New("tokenhook: allowed_claims must name the claims the hook may set")
+	ErrReservedClaim   = errors.New("tokenhook: allowed_claims cannot include registered or reserved claims")
+	ErrInvalidFailure  = errors.New("tokenhook: failure must be open or closed")
+	errRedirect        = errors.New("token hook redirects are not followed")
+	errClaimsNotObject = errors.New("token hook claims must be a JSON object")
+)
+
+type Config struct {
+	// URL is the endpoint of the hook. Tokens are issued without one when
+	// it is empty.
+	URL string `env:"URL"`
+	// Secret is the Standard Webhooks signing secret shared with the hook,
+	// whsec_ followed by the base64 encoded key.
+	Secret string `env:"SECRET"`
+	// Timeout bounds the whole call, including connecting.
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"500ms"`
+	// AllowedClaims are the only claims taken from the hook's answer.
+	AllowedClaims []string `env:"ALLOWED_CLAIMS" envSeparator:","`
+	// Failure is what happens to tokens of clients without a policy of
+	// their own when the hook fails: open or closed.
+	Failure string `env:"FAILURE" envDefault:"closed"`
+}
+
+// request and response are the bodies of a call: a POST of the former to
+// the hook answered by the latter, or by 204 No Content to add nothing.
+type request struct {
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      requestData `json:"data"`
+}
+
+type requestData struct {
+	Tenant    string         `json:"tenant"`
+	ClientID  string         `json:"client_id"`
+	GrantType string         `json:"grant_type"`
+	Claims    map[string]any `json:"claims"`
+}
+
+type response struct {
+	Claims json.RawMessage `json:"claims"`
+}
+
+// Client is an oauth.TokenHook calling the hook at Config.URL.
+type Client struct {
+	cfg    Config
+	key    []byte
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewClient returns a client of the hook configured by cfg.
+func NewClient(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(cfg.Secret, secretPrefix))
+	if err != nil || len(key) < minSecretSize {
+		return nil, ErrInvalidSecret
+	}
+	if len(cfg.AllowedClaims) == 0 {
+		return nil, ErrNoAllowedClaims
+	}
+	if slices.ContainsFunc(cfg.AllowedClaims, oauth.ReservedClaim) {
+		return nil, ErrReservedClaim
+	}
+	if cfg.Failure != oauth.TokenHookFailOpen && cfg.Failure != oauth.TokenHookFailClosed {
+		return nil, ErrInvalidFailure
+	}
+
+	return &Client{
+		cfg: cfg,
+		key: key,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// A redirect would send the signed claims somewhere else.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return errRedirect
+			},
+		},
+		now: time.Now,
+	}, nil
+}
+
+// Close releases idle connections to the hook.
+func (c *Client) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// Claims asks the hook for the claims to add to the token described by req
+// and returns those on the allow-list.
+func (c *Client) Claims(ctx context.Context, req oauth.TokenHookRequest) (map[string]any, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	now := c.now()
+	body, err := json.Marshal(request{
+		Type:      EventType,
+		Timestamp: now.UTC(),
+		Data: requestData{
+			Tenant:    req.Tenant,
+			ClientID:  req.ClientID,
+			GrantType: req.GrantType,
+			Claims:    req.Claims,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	id := "msg_" + randomID()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set(HeaderID, id)
+	r.Header.Set(HeaderTimestamp, ts)
+	r.Header.Set(HeaderSignature, Sign(c.key, id, ts, body))
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("tokenhook: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("tokenhook: hook returned %s", resp.Status)
+	}
+
+	var out response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("tokenhook: decode response: %w", err)
+	}
+	var claims map[string]any
+	if len(out.Claims) > 0 {
+		if err := json.Unmarshal(out.Claims, &claims); err != nil {
+			return nil, fmt.Errorf("tokenhook: %w", errClaimsNotObject)
+		}
+	}
+	for name := range claims {
+		if !slices.Contains(c.cfg.AllowedClaims, name) {
+			log.Debugf("tokenhook: dropping claim %q not on the allow-list", name)
+			delete(claims, name)
+		}
+	}
+	return claims, nil
+}
+
+// Sign returns the webhook-signature header of a request with the given id,
+// timestamp and body: the base64 HMAC-SHA256 of "id.timestamp.body".
+func Sign(key []byte, id, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
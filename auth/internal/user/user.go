@@ -0,0 +1,90 @@
+// Package user holds end-user accounts and password authentication.
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("user: invalid username or password")
+	ErrUsernameTaken      = errors.New("user: username already taken")
+	ErrWeakPassword       = errors.New("user: password too short")
+	ErrInvalidUsername    = errors.New("user: username is required")
+)
+
+// dummyHash is compared against when a username does not exist, so unknown
+// and known usernames take the same time to reject.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// User is an account of a tenant, "" for the root issuer. Usernames are
+// unique per tenant, and a user only signs in at its own tenant's issuer.
+type User struct {
+	ID       string
+	Tenant   string
+	Username string
+}
+
+type Service struct {
+	q db.Querier
+}
+
+func NewService(q db.Querier) *Service {
+	return &Service{q: q}
+}
+
+func (s *Service) Create(ctx context.Context, tenant, username, password string) (*User, error) {
+	if username == "" {
+		return nil, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &User{ID: uuid.NewString(), Tenant: tenant, Username: username}
+	err = s.q.CreateUser(ctx, db.CreateUserParams{
+		ID:           u.ID,
+		Username:     u.Username,
+		PasswordHash: string(hash),
+		Tenant:       u.Tenant,
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *Service) Authenticate(ctx context.Context, tenant, username, password string) (*User, error) {
+	row, err := s.q.GetUserByUsername(ctx, db.GetUserByUsernameParams{
+		Username: username,
+		Tenant:   tenant,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(row.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &User{ID: row.ID, Tenant: row.Tenant, Username: row.Username}, nil
+}
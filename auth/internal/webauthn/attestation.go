@@ -0,0 +1,127 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"slices"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Attestation statement formats (WebAuthn section 8).
+const (
+	FormatNone   = "none"
+	FormatPacked = "packed"
+)
+
+// oidAAGUID is the id-fido-gen-ce-aaguid certificate extension holding the
+// AAGUID of the authenticator model.
+var oidAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+type attestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+// packedStatement is a packed attestation statement (WebAuthn section
+// 8.2): self attestation without x5c.
+type packedStatement struct {
+	Alg int64    `cbor:"alg"`
+	Sig []byte   `cbor:"sig"`
+	X5C [][]byte `cbor:"x5c,omitempty"`
+}
+
+// verifyAttestation checks the attestation statement of a registration. It
+// reports whether the statement shows, through a certificate chaining to
+// the configured roots, that the credential is kept by hardware.
+func (s *Service) verifyAttestation(obj attestationObject, ad *authenticatorData, key *coseKey, clientDataHash []byte) (bool, error) {
+	switch obj.Fmt {
+	case FormatNone:
+		var stmt map[string]cbor.RawMessage
+		if err := cbor.Unmarshal(obj.AttStmt, &stmt); err != nil || len(stmt) != 0 {
+			return false, fmt.Errorf("%w: none attestation statement must be empty", ErrInvalidResponse)
+		}
+		return false, nil
+	case FormatPacked:
+	default:
+		return false, fmt.Errorf("%w: %q", ErrUnsupportedAttestation, obj.Fmt)
+	}
+
+	var stmt packedStatement
+	if err := cbor.Unmarshal(obj.AttStmt, &stmt); err != nil {
+		return false, fmt.Errorf("%w: packed attestation statement: %v", ErrInvalidResponse, err)
+	}
+	signed := slices.Concat(obj.AuthData, clientDataHash)
+
+	if len(stmt.X5C) == 0 {
+		if stmt.Alg != key.alg {
+			return false, fmt.Errorf("%w: self attestation alg does not match the credential", ErrInvalidResponse)
+		}
+		if err := key.verify(signed, stmt.Sig); err != nil {
+			return false, fmt.Errorf("%w: self attestation: %v", ErrInvalidResponse, err)
+		}
+		return false, nil
+	}
+
+	certs := make([]*x509.Certificate, len(stmt.X5C))
+	for i, der := range stmt.X5C {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return false, fmt.Errorf("%w: attestation certificate: %v", ErrInvalidResponse, err)
+		}
+		certs[i] = cert
+	}
+	leaf := certs[0]
+	if err := verifySignature(stmt.Alg, leaf.PublicKey, signed, stmt.Sig); err != nil {
+		return false, fmt.Errorf("%w: attestation: %v", ErrInvalidResponse, err)
+	}
+	if err := checkAttestationCert(leaf, ad.aaguid); err != nil {
+		return false, err
+	}
+
+	if s.roots == nil {
+		return false, nil
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         s.roots,
+		Intermediates: intermediates,
+		CurrentTime:   s.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	// An authenticator the roots do not vouch for may still be used, as a
+	// software key.
+	return err == nil, nil
+}
+
+// checkAttestationCert checks the requirements on packed attestation
+// certificates (WebAuthn section 8.2.1).
+func checkAttestationCert(cert *x509.Certificate, aaguid []byte) error {
+	subject := cert.Subject
+	switch {
+	case cert.Version != 3:
+		return fmt.Errorf("%w: attestation certificate must be X.509 version 3", ErrInvalidResponse)
+	case len(subject.Country) == 0 || len(subject.Organization) == 0 || subject.CommonName == "" ||
+		!slices.Equal(subject.OrganizationalUnit, []string{"Authenticator Attestation"}):
+		return fmt.Errorf("%w: attestation certificate subject", ErrInvalidResponse)
+	case !cert.BasicConstraintsValid || cert.IsCA:
+		return fmt.Errorf("%w: attestation certificate must not be a CA", ErrInvalidResponse)
+	}
+
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidAAGUID) {
+			continue
+		}
+		var value []byte
+		if _, err := asn1.Unmarshal(ext.Value, &value); err != nil || ext.Critical || !bytes.Equal(value, aaguid) {
+			return fmt.Errorf("%w: attestation certificate AAGUID does not match", ErrInvalidResponse)
+		}
+	}
+	return nil
+}
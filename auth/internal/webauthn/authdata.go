@@ -0,0 +1,88 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Authenticator data flags (WebAuthn section 6.1).
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagAttestedData   = 0x40
+	flagExtensionData  = 0x80
+)
+
+const (
+	rpIDHashSize      = 32
+	minAuthDataSize   = rpIDHashSize + 1 + 4
+	aaguidSize        = 16
+	maxCredentialSize = 1023
+)
+
+// authenticatorData is the data an authenticator signs (WebAuthn section
+// 6.1), with the attested credential data of a registration.
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+
+	aaguid       []byte
+	credentialID []byte
+	// publicKey is the COSE_Key of the credential as the authenticator
+	// encoded it.
+	publicKey []byte
+}
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < minAuthDataSize {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrInvalidResponse)
+	}
+	ad := &authenticatorData{
+		rpIDHash:  b[:rpIDHashSize],
+		flags:     b[rpIDHashSize],
+		signCount: binary.BigEndian.Uint32(b[rpIDHashSize+1:]),
+	}
+	rest := b[minAuthDataSize:]
+
+	if ad.has(flagAttestedData) {
+		if len(rest) < aaguidSize+2 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidResponse)
+		}
+		ad.aaguid = rest[:aaguidSize]
+		n := int(binary.BigEndian.Uint16(rest[aaguidSize:]))
+		rest = rest[aaguidSize+2:]
+		if n > maxCredentialSize || len(rest) < n {
+			return nil, fmt.Errorf("%w: credential ID too long", ErrInvalidResponse)
+		}
+		ad.credentialID = rest[:n]
+		rest = rest[n:]
+
+		var key cbor.RawMessage
+		after, err := cbor.UnmarshalFirst(rest, &key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidResponse, err)
+		}
+		ad.publicKey = key
+		rest = after
+	}
+	if ad.has(flagExtensionData) {
+		var ext cbor.RawMessage
+		after, err := cbor.UnmarshalFirst(rest, &ext)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %v", ErrInvalidResponse, err)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing authenticator data", ErrInvalidResponse)
+	}
+	return ad, nil
+}
+
+func (ad *authenticatorData) has(flag byte) bool {
+	return ad.flags&flag != 0
+}
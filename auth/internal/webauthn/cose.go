@@ -0,0 +1,158 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithms (RFC 9053) offered at registration, in order of
+// preference.
+const (
+	algES256 = -7
+	algEdDSA = -8
+	algRS256 = -257
+)
+
+var supportedAlgs = []int64{algES256, algEdDSA, algRS256}
+
+// COSE key parameters (RFC 9052 section 7 and RFC 9053 section 7).
+const (
+	coseKty = 1
+	coseAlg = 3
+	// coseCrv is also an RSA key's n, and coseX its e.
+	coseCrv = -1
+	coseX   = -2
+	coseY   = -3
+
+	ktyOKP = 1
+	ktyEC2 = 2
+	ktyRSA = 3
+
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+const minRSAKeySize = 2048
+
+var errBadSignature = errors.New("signature does not verify")
+
+// coseKey is a credential public key.
+type coseKey struct {
+	alg int64
+	pub crypto.PublicKey
+}
+
+// parseCOSEKey parses a COSE_Key of one of the supported algorithms.
+func parseCOSEKey(b []byte) (*coseKey, error) {
+	var m map[int64]cbor.RawMessage
+	if err := cbor.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidResponse, err)
+	}
+	var kty, alg int64
+	if err := unmarshalParam(m, coseKty, &kty); err != nil {
+		return nil, err
+	}
+	if err := unmarshalParam(m, coseAlg, &alg); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case kty == ktyEC2 && alg == algES256:
+		var crv int64
+		var x, y []byte
+		if err := unmarshalParams(m, map[int64]any{coseCrv: &crv, coseX: &x, coseY: &y}); err != nil {
+			return nil, err
+		}
+		if crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: ES256 key must be on P-256", ErrUnsupportedAlgorithm)
+		}
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidResponse, err)
+		}
+		return &coseKey{alg: alg, pub: pub}, nil
+	case kty == ktyOKP && alg == algEdDSA:
+		var crv int64
+		var x []byte
+		if err := unmarshalParams(m, map[int64]any{coseCrv: &crv, coseX: &x}); err != nil {
+			return nil, err
+		}
+		if crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: EdDSA key must be on Ed25519", ErrUnsupportedAlgorithm)
+		}
+		return &coseKey{alg: alg, pub: ed25519.PublicKey(x)}, nil
+	case kty == ktyRSA && alg == algRS256:
+		var n, e []byte
+		if err := unmarshalParams(m, map[int64]any{coseCrv: &n, coseX: &e}); err != nil {
+			return nil, err
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < minRSAKeySize || pub.E < 3 {
+			return nil, fmt.Errorf("%w: RSA key too weak", ErrUnsupportedAlgorithm)
+		}
+		return &coseKey{alg: alg, pub: pub}, nil
+	default:
+		return nil, fmt.Errorf("%w: kty %d alg %d", ErrUnsupportedAlgorithm, kty, alg)
+	}
+}
+
+// verify checks sig, made by the credential over data.
+func (k *coseKey) verify(data, sig []byte) error {
+	return verifySignature(k.alg, k.pub, data, sig)
+}
+
+// verifySignature checks a WebAuthn signature of alg: ASN.1 DER for ECDSA,
+// as authenticators produce them.
+func verifySignature(alg int64, pub crypto.PublicKey, data, sig []byte) error {
+	switch alg {
+	case algES256:
+		pub, ok := pub.(*ecdsa.PublicKey)
+		digest := sha256.Sum256(data)
+		if !ok || !ecdsa.VerifyASN1(pub, digest[:], sig) {
+			return errBadSignature
+		}
+	case algEdDSA:
+		pub, ok := pub.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, data, sig) {
+			return errBadSignature
+		}
+	case algRS256:
+		pub, ok := pub.(*rsa.PublicKey)
+		digest := sha256.Sum256(data)
+		if !ok || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return errBadSignature
+		}
+	default:
+		return fmt.Errorf("%w: alg %d", ErrUnsupportedAlgorithm, alg)
+	}
+	return nil
+}
+
+func unmarshalParams(m map[int64]cbor.RawMessage, params map[int64]any) error {
+	for label, v := range params {
+		if err := unmarshalParam(m, label, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalParam(m map[int64]cbor.RawMessage, label int64, v any) error {
+	raw, ok := m[label]
+	if !ok {
+		return fmt.Errorf("%w: credential public key has no parameter %d", ErrInvalidResponse, label)
+	}
+	if err := cbor.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: credential public key parameter %d: %v", ErrInvalidResponse, label, err)
+	}
+	return nil
+}
@@ -0,0 +1,601 @@
+// Package webauthn signs users in with passkeys and security keys (Web
+// Authentication Level 2, https://www.w3.org/TR/webauthn-2/). It runs the
+// registration and authentication ceremonies with the browser, accepting
+// none and packed attestation, and keeps the credentials with their
+// signature counters. Only discoverable credentials are registered, so users
+// sign in without typing a username.
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+)
+
+// Paths of the ceremony endpoints, below the issuer's.
+const (
+	RegisterPath        = "/webauthn/register"
+	RegisterOptionsPath = "/webauthn/register/options"
+	LoginOptionsPath    = "/webauthn/login/options"
+	ScriptPath          = "/webauthn.js"
+)
+
+// User verification requirements (WebAuthn section 5.8.6).
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+// Client data types (WebAuthn section 5.8.1).
+const (
+	typeCreate = "webauthn.create"
+	typeGet    = "webauthn.get"
+)
+
+const (
+	credentialType = "public-key"
+	challengeSize  = 32
+)
+
+var (
+	ErrInvalidConfig          = errors.New("webauthn: invalid configuration")
+	ErrUserNotFound           = errors.New("webauthn: user not found")
+	ErrChallengeNotFound      = errors.New("webauthn: challenge not found or expired")
+	ErrInvalidResponse        = errors.New("webauthn: invalid response")
+	ErrUnsupportedAlgorithm   = errors.New("webauthn: unsupported credential algorithm")
+	ErrUnsupportedAttestation = errors.New("webauthn: unsupported attestation format")
+	ErrCredentialExists       = errors.New("webauthn: credential already registered")
+	ErrCredentialNotFound     = errors.New("webauthn: credential not found")
+	ErrSignCount              = errors.New("webauthn: signature counter did not increase, the authenticator may be cloned")
+)
+
+type Config struct {
+	// RPID is the relying party ID credentials are scoped to: the host of
+	// OAUTH_BASE_URL when empty, or a registrable suffix of it. Changing it
+	// makes every credential unusable.
+	RPID   string `env:"RP_ID"`
+	RPName string `env:"RP_NAME" envDefault:"authd"`
+	// Origins are the origins of the pages ceremonies may run on, the
+	// origin of OAUTH_BASE_URL when empty.
+	Origins []string `env:"ORIGINS" envSeparator:","`
+	// UserVerification is required, preferred or discouraged.
+	UserVerification string `env:"USER_VERIFICATION" envDefault:"preferred"`
+	// Timeout is how long the user has to complete a ceremony.
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"5m"`
+	// AttestationCAFile holds the PEM roots packed attestation certificates
+	// must chain to for credentials to count as hardware keys. Without it
+	// attestation is not requested and every credential is a software key.
+	AttestationCAFile string `env:"ATTESTATION_CA_FILE"`
+}
+
+// Bytes is binary data, base64url encoded in JSON as in the WebAuthn JSON
+// serialization.
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Bytes  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         Bytes    `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions are the options of navigator.credentials.create
+// (WebAuthn section 5.4).
+type CreationOptions struct {
+	Challenge              Bytes                  `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the options of navigator.credentials.get (WebAuthn
+// section 5.5). They allow no credentials, for the authenticator to offer
+// the user's passkeys.
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the PublicKeyCredential navigator.credentials.create
+// returned, in its JSON serialization.
+type RegistrationResponse struct {
+	RawID    Bytes                            `json:"rawId"`
+	Type     string                           `json:"type"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    Bytes    `json:"clientDataJSON"`
+	AttestationObject Bytes    `json:"attestationObject"`
+	Transports        []string `json:"transports"`
+}
+
+// AssertionResponse is the PublicKeyCredential navigator.credentials.get
+// returned, in its JSON serialization.
+type AssertionResponse struct {
+	RawID    Bytes                          `json:"rawId"`
+	Type     string                         `json:"type"`
+	Response AuthenticatorAssertionResponse `json:"response"`
+}
+
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    Bytes `json:"clientDataJSON"`
+	AuthenticatorData Bytes `json:"authenticatorData"`
+	Signature         Bytes `json:"signature"`
+	UserHandle        Bytes `json:"userHandle"`
+}
+
+// Credential is a registered credential.
+type Credential struct {
+	ID                Bytes  `json:"id"`
+	AAGUID            string `json:"aaguid"`
+	AttestationFormat string `json:"attestation_format"`
+	// AMR is hwk for hardware keys and swk for the others.
+	AMR            string     `json:"amr"`
+	BackupEligible bool       `json:"backup_eligible"`
+	Transports     []string   `json:"transports,omitempty"`
+	SignCount      int64      `json:"sign_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Login is a user who signed in with a credential.
+type Login struct {
+	UserID   string
+	Username string
+	// AMR is the authentication method of the credential, hwk or swk.
+	AMR string
+}
+
+// clientData is the part of the collected client data checked here
+// (WebAuthn section 5.8.1).
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ceremony is a challenge handed to the browser, until a response uses it.
+type ceremony struct {
+	Type   string `json:"type"`
+	Tenant string `json:"tenant,omitempty"`
+	// UserID is the user registering a credential.
+	UserID string `json:"user_id,omitempty"`
+}
+
+type Service struct {
+	cfg      Config
+	rpIDHash [sha256.Size]byte
+	origins  []string
+	roots    *x509.CertPool
+	q        db.Querier
+	kv       oauth.KVStore
+	now      func() time.Time
+}
+
+// NewService returns a service for the relying party cfg describes, serving
+// pages under baseURL unless cfg sets the RP ID and origins.
+func NewService(cfg Config, baseURL string, q db.Querier, kv oauth.KVStore) (*Service, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if cfg.RPID == "" {
+		cfg.RPID = base.Hostname()
+	}
+	if len(cfg.Origins) == 0 {
+		cfg.Origins = []string{base.Scheme + "://" + base.Host}
+	}
+	switch cfg.UserVerification {
+	case UserVerificationRequired, UserVerificationPreferred, UserVerificationDiscouraged:
+	default:
+		return nil, fmt.Errorf("%w: user verification must be required, preferred or discouraged", ErrInvalidConfig)
+	}
+
+	s := &Service{
+		cfg:      cfg,
+		rpIDHash: sha256.Sum256([]byte(cfg.RPID)),
+		origins:  cfg.Origins,
+		q:        q,
+		kv:       kv,
+		now:      time.Now,
+	}
+	if cfg.AttestationCAFile != "" {
+		pem, err := os.ReadFile(cfg.AttestationCAFile)
+		if err != nil {
+			return nil, err
+		}
+		s.roots = x509.NewCertPool()
+		if !s.roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificates in %s", ErrInvalidConfig, cfg.AttestationCAFile)
+		}
+	}
+	return s, nil
+}
+
+// BeginRegistration returns the options for the user with the given ID to
+// create a passkey at tenant.
+func (s *Service) BeginRegistration(ctx context.Context, tenant, userID string) (*CreationOptions, error) {
+	u, err := s.q.GetUser(ctx, db.GetUserParams{ID: userID, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.q.ListWebAuthnCredentials(ctx, db.ListWebAuthnCredentialsParams{UserID: u.ID, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.challenge(ctx, ceremony{Type: typeCreate, Tenant: tenant, UserID: u.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingParty{ID: s.cfg.RPID, Name: s.cfg.RPName},
+		// The user handle is returned by discoverable logins to tell who
+		// signed in.
+		User:               UserEntity{ID: Bytes(u.ID), Name: u.Username, DisplayName: u.Username},
+		Timeout:            s.cfg.Timeout.Milliseconds(),
+		ExcludeCredentials: []CredentialDescriptor{},
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   s.cfg.UserVerification,
+		},
+		Attestation: "none",
+	}
+	if s.roots != nil {
+		opts.Attestation = "direct"
+	}
+	for _, alg := range supportedAlgs {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, CredentialParameters{Type: credentialType, Alg: alg})
+	}
+	for _, row := range rows {
+		opts.ExcludeCredentials = append(opts.ExcludeCredentials, CredentialDescriptor{
+			Type:       credentialType,
+			ID:         row.ID,
+			Transports: row.Transports,
+		})
+	}
+	return opts, nil
+}
+
+// FinishRegistration verifies the response to the options BeginRegistration
+// returned for the user with the given ID at tenant, and stores the new
+// credential (WebAuthn section 7.1).
+func (s *Service) FinishRegistration(ctx context.Context, tenant, userID string, resp RegistrationResponse) (*Credential, error) {
+	if resp.Type != credentialType {
+		return nil, fmt.Errorf("%w: type must be public-key", ErrInvalidResponse)
+	}
+	if _, err := s.useChallenge(ctx, resp.Response.ClientDataJSON, ceremony{Type: typeCreate, Tenant: tenant, UserID: userID}); err != nil {
+		return nil, err
+	}
+
+	var obj attestationObject
+	if err := cbor.Unmarshal(resp.Response.AttestationObject, &obj); err != nil {
+		return nil, fmt.Errorf("%w: attestation object: %v", ErrInvalidResponse, err)
+	}
+	ad, err := s.checkAuthenticatorData(obj.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if !ad.has(flagAttestedData) || !bytes.Equal(ad.credentialID, resp.RawID) {
+		return nil, fmt.Errorf("%w: attested credential data missing or for another credential", ErrInvalidResponse)
+	}
+	key, err := parseCOSEKey(ad.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	trusted, err := s.verifyAttestation(obj, ad, key, clientDataHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// A key that may be synced to other devices is not hardware bound
+	// whatever its attestation says.
+	amr := session.AMRSoftwareKey
+	if trusted && !ad.has(flagBackupEligible) {
+		amr = session.AMRHardwareKey
+	}
+	transports := resp.Response.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+	row := db.CreateWebAuthnCredentialParams{
+		ID:                ad.credentialID,
+		UserID:            userID,
+		PublicKey:         ad.publicKey,
+		SignCount:         int64(ad.signCount),
+		AAGUID:            ad.aaguid,
+		AttestationFormat: obj.Fmt,
+		AMR:               amr,
+		BackupEligible:    ad.has(flagBackupEligible),
+		Transports:        transports,
+		Tenant:            tenant,
+	}
+	err = s.q.CreateWebAuthnCredential(ctx, row)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return nil, ErrCredentialExists
+		case "23503":
+			return nil, ErrUserNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return credential(db.WebauthnCredential{
+		ID:                row.ID,
+		AAGUID:            row.AAGUID,
+		AttestationFormat: row.AttestationFormat,
+		AMR:               row.AMR,
+		BackupEligible:    row.BackupEligible,
+		Transports:        row.Transports,
+		SignCount:         row.SignCount,
+		CreatedAt:         s.now(),
+	}), nil
+}
+
+// BeginLogin returns the options for a user to sign in at tenant with a
+// passkey.
+func (s *Service) BeginLogin(ctx context.Context, tenant string) (*RequestOptions, error) {
+	challenge, err := s.challenge(ctx, ceremony{Type: typeGet, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          s.cfg.Timeout.Milliseconds(),
+		RPID:             s.cfg.RPID,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: s.cfg.UserVerification,
+	}, nil
+}
+
+// FinishLogin verifies the response to the options BeginLogin returned for
+// tenant and returns who signed in (WebAuthn section 7.2).
+func (s *Service) FinishLogin(ctx context.Context, tenant string, resp AssertionResponse) (*Login, error) {
+	if resp.Type != credentialType {
+		return nil, fmt.Errorf("%w: type must be public-key", ErrInvalidResponse)
+	}
+	if _, err := s.useChallenge(ctx, resp.Response.ClientDataJSON, ceremony{Type: typeGet, Tenant: tenant}); err != nil {
+		return nil, err
+	}
+
+	cred, err := s.q.GetWebAuthnCredential(ctx, db.GetWebAuthnCredentialParams{ID: resp.RawID, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The options allowed no credentials, so the authenticator must say
+	// whose credential it used.
+	if !bytes.Equal(resp.Response.UserHandle, []byte(cred.UserID)) {
+		return nil, fmt.Errorf("%w: user handle does not match the credential", ErrInvalidResponse)
+	}
+
+	ad, err := s.checkAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	if err := key.verify(slices.Concat(resp.Response.AuthenticatorData, clientDataHash[:]), resp.Response.Signature); err != nil {
+		return nil, fmt.Errorf("%w: assertion: %v", ErrInvalidResponse, err)
+	}
+
+	// Authenticators without a counter always report 0; any other must
+	// report more than last time, or it may have been cloned.
+	n, err := s.q.UpdateWebAuthnSignCount(ctx, db.UpdateWebAuthnSignCountParams{
+		SignCount: int64(ad.signCount),
+		ID:        cred.ID,
+		Tenant:    tenant,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrSignCount
+	}
+
+	u, err := s.q.GetUser(ctx, db.GetUserParams{ID: cred.UserID, Tenant: tenant})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Login{UserID: u.ID, Username: u.Username, AMR: cred.AMR}, nil
+}
+
+// Credentials returns the credentials of the user of tenant with the given
+// ID.
+func (s *Service) Credentials(ctx context.Context, tenant, userID string) ([]Credential, error) {
+	rows, err := s.q.ListWebAuthnCredentials(ctx, db.ListWebAuthnCredentialsParams{UserID: userID, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	creds := make([]Credential, 0, len(rows))
+	for _, row := range rows {
+		creds = append(creds, *credential(row))
+	}
+	return creds, nil
+}
+
+// DeleteCredential removes the credential with the given ID of the user of
+// tenant with the given ID.
+func (s *Service) DeleteCredential(ctx context.Context, tenant, userID string, id []byte) error {
+	n, err := s.q.DeleteWebAuthnCredential(ctx, db.DeleteWebAuthnCredentialParams{ID: id, UserID: userID, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// checkAuthenticatorData parses authenticator data and checks it is for
+// this relying party and the user was present, and verified when required.
+func (s *Service) checkAuthenticatorData(b []byte) (*authenticatorData, error) {
+	ad, err := parseAuthenticatorData(b)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !bytes.Equal(ad.rpIDHash, s.rpIDHash[:]):
+		return nil, fmt.Errorf("%w: credential is for another relying party", ErrInvalidResponse)
+	case !ad.has(flagUserPresent):
+		return nil, fmt.Errorf("%w: user not present", ErrInvalidResponse)
+	case s.cfg.UserVerification == UserVerificationRequired && !ad.has(flagUserVerified):
+		return nil, fmt.Errorf("%w: user not verified", ErrInvalidResponse)
+	}
+	return ad, nil
+}
+
+// challenge returns a new challenge for c.
+func (s *Service) challenge(ctx context.Context, c ceremony) (Bytes, error) {
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.kv.Put(ctx, challengeKey(base64.RawURLEncoding.EncodeToString(b)), raw, s.cfg.Timeout); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// useChallenge checks the client data of a response to a challenge for
+// want, and uses the challenge up.
+func (s *Service) useChallenge(ctx context.Context, clientDataJSON []byte, want ceremony) (*clientData, error) {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return nil, fmt.Errorf("%w: client data: %v", ErrInvalidResponse, err)
+	}
+	if cd.Type != want.Type {
+		return nil, fmt.Errorf("%w: client data type must be %s", ErrInvalidResponse, want.Type)
+	}
+	if !slices.Contains(s.origins, cd.Origin) || cd.CrossOrigin {
+		return nil, fmt.Errorf("%w: origin %q not allowed", ErrInvalidResponse, cd.Origin)
+	}
+
+	raw, err := s.kv.Take(ctx, challengeKey(strings.TrimRight(cd.Challenge, "=")))
+	if errors.Is(err, oauth.ErrKeyNotFound) {
+		return nil, ErrChallengeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var got ceremony
+	if err := json.Unmarshal(raw, &got); err != nil {
+		return nil, err
+	}
+	if got != want {
+		return nil, ErrChallengeNotFound
+	}
+	return &cd, nil
+}
+
+func credential(row db.WebauthnCredential) *Credential {
+	c := &Credential{
+		ID:                row.ID,
+		AttestationFormat: row.AttestationFormat,
+		AMR:               row.AMR,
+		BackupEligible:    row.BackupEligible,
+		Transports:        row.Transports,
+		SignCount:         row.SignCount,
+		CreatedAt:         row.CreatedAt,
+	}
+	if id, err := uuid.FromBytes(row.AAGUID); err == nil {
+		c.AAGUID = id.String()
+	}
+	if row.LastUsedAt.Valid {
+		c.LastUsedAt = &row.LastUsedAt.Time
+	}
+	return c
+}
+
+func challengeKey(challenge string) string {
+	return "webauthn:" + challenge
+}
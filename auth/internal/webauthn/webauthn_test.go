@@ -0,0 +1,646 @@
+package webauthn_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/lib/pq"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/db"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/oauth"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/session"
+	"github.com/yokeTH/yoketh-backend-oss/auth/internal/webauthn"
+)
+
+const (
+	baseURL = "https://auth.example.com"
+	origin  = "https://auth.example.com"
+	rpID    = "auth.example.com"
+
+	userID   = "6f1c2a0e-3c1b-4d8e-9a57-2f0b6f3c1d11"
+	username = "alice"
+)
+
+// Authenticator data flags (WebAuthn section 6.1).
+const (
+	flagUP = 0x01
+	flagUV = 0x04
+	flagBE = 0x08
+	flagAT = 0x40
+)
+
+var oidAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// fakeQuerier keeps users and credentials in memory, enforcing what the
+// webauthn_credentials table and its queries do.
+type fakeQuerier struct {
+	db.Querier
+	users map[string]db.User
+	creds map[string]db.WebauthnCredential
+}
+
+func newFakeQuerier() *fakeQuerier {
+	q := &fakeQuerier{users: map[string]db.User{}, creds: map[string]db.WebauthnCredential{}}
+	q.users[userID] = db.User{ID: userID, Username: username}
+	return q
+}
+
+func credKey(tenant string, id []byte) string {
+	return tenant + "/" + string(id)
+}
+
+func (q *fakeQuerier) GetUser(ctx context.Context, arg db.GetUserParams) (db.User, error) {
+	u, ok := q.users[arg.ID]
+	if !ok || u.Tenant != arg.Tenant {
+		return db.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (q *fakeQuerier) ListWebAuthnCredentials(ctx context.Context, arg db.ListWebAuthnCredentialsParams) ([]db.WebauthnCredential, error) {
+	var rows []db.WebauthnCredential
+	for _, c := range q.creds {
+		if c.UserID == arg.UserID && c.Tenant == arg.Tenant {
+			rows = append(rows, c)
+		}
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) CreateWebAuthnCredential(ctx context.Context, arg db.CreateWebAuthnCredentialParams) error {
+	if _, ok := q.creds[credKey(arg.Tenant, arg.ID)]; ok {
+		return &pq.Error{Code: "23505"}
+	}
+	q.creds[credKey(arg.Tenant, arg.ID)] = db.WebauthnCredential{
+		ID:                arg.ID,
+		UserID:            arg.UserID,
+		PublicKey:         arg.PublicKey,
+		SignCount:         arg.SignCount,
+		AAGUID:            arg.AAGUID,
+		AttestationFormat: arg.AttestationFormat,
+		AMR:               arg.AMR,
+		BackupEligible:    arg.BackupEligible,
+		Transports:        arg.Transports,
+		CreatedAt:         time.Now(),
+		Tenant:            arg.Tenant,
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetWebAuthnCredential(ctx context.Context, arg db.GetWebAuthnCredentialParams) (db.WebauthnCredential, error) {
+	c, ok := q.creds[credKey(arg.Tenant, arg.ID)]
+	if !ok {
+		return db.WebauthnCredential{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (q *fakeQuerier) UpdateWebAuthnSignCount(ctx context.Context, arg db.UpdateWebAuthnSignCountParams) (int64, error) {
+	c, ok := q.creds[credKey(arg.Tenant, arg.ID)]
+	if !ok || !(c.SignCount < arg.SignCount || c.SignCount == 0 && arg.SignCount == 0) {
+		return 0, nil
+	}
+	c.SignCount = arg.SignCount
+	c.LastUsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	q.creds[credKey(arg.Tenant, arg.ID)] = c
+	return 1, nil
+}
+
+// authenticator is a software authenticator holding one ES256 credential.
+type authenticator struct {
+	key    *ecdsa.PrivateKey
+	id     []byte
+	aaguid []byte
+	flags  byte
+	// counter is the signature counter the next ceremony reports. It
+	// counts up unless zero, as for authenticators without one.
+	counter uint32
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	t.Helper()
+	return &authenticator{
+		key:     newKey(t),
+		id:      randomBytes(t, 16),
+		aaguid:  randomBytes(t, 16),
+		flags:   flagUP | flagUV,
+		counter: 1,
+	}
+}
+
+// coseKey returns the COSE_Key of the credential.
+func (a *authenticator) coseKey(t *testing.T) []byte {
+	t.Helper()
+	b, err := cbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func (a *authenticator) authData(t *testing.T, attested bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	b := slices.Concat(rpIDHash[:], []byte{a.flags}, binary.BigEndian.AppendUint32(nil, a.counter))
+	if a.counter != 0 {
+		a.counter++
+	}
+	if !attested {
+		return b
+	}
+	b[len(rpIDHash)] |= flagAT
+	b = append(b, a.aaguid...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(a.id)))
+	b = append(b, a.id...)
+	return append(b, a.coseKey(t)...)
+}
+
+// attestation is how a registration is attested: none, packed self
+// attestation, or packed with a certificate chain.
+type attestation struct {
+	fmt   string
+	chain []*x509.Certificate
+	// key signs the statement: the key of the first certificate in chain,
+	// or the credential's when nil.
+	key *ecdsa.PrivateKey
+}
+
+func (a *authenticator) register(t *testing.T, opts *webauthn.CreationOptions, att attestation) webauthn.RegistrationResponse {
+	t.Helper()
+	clientDataJSON := clientData(t, "webauthn.create", opts.Challenge)
+	authData := a.authData(t, true)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := slices.Concat(authData, clientDataHash[:])
+
+	stmt := map[string]any{}
+	if att.fmt == webauthn.FormatPacked {
+		signer := a.key
+		if att.key != nil {
+			signer = att.key
+		}
+		stmt["alg"] = -7
+		stmt["sig"] = sign(t, signer, signed)
+		if att.chain != nil {
+			x5c := make([][]byte, len(att.chain))
+			for i, cert := range att.chain {
+				x5c[i] = cert.Raw
+			}
+			stmt["x5c"] = x5c
+		}
+	}
+	obj, err := cbor.Marshal(map[string]any{"fmt": att.fmt, "attStmt": stmt, "authData": authData})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webauthn.RegistrationResponse{
+		RawID: a.id,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAttestationResponse{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: obj,
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+// login answers opts with the credential, as a discoverable credential
+// does: with the user handle it was registered for.
+func (a *authenticator) login(t *testing.T, opts *webauthn.RequestOptions) webauthn.AssertionResponse {
+	t.Helper()
+	clientDataJSON := clientData(t, "webauthn.get", opts.Challenge)
+	authData := a.authData(t, false)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	return webauthn.AssertionResponse{
+		RawID: a.id,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         sign(t, a.key, slices.Concat(authData, clientDataHash[:])),
+			UserHandle:        []byte(userID),
+		},
+	}
+}
+
+func clientData(t *testing.T, typ string, challenge []byte) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// newCA returns a self-signed attestation root and its key.
+func newCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Attestation Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// newAttestationCert returns a packed attestation certificate (WebAuthn
+// section 8.2.1) for the authenticator model aaguid, issued by ca.
+func newAttestationCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, aaguid []byte) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	ext, err := asn1.Marshal(aaguid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{
+			Country:            []string{"US"},
+			Organization:       []string{"Test Authenticators"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Test Authenticator",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: oidAAGUID, Value: ext}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// newService returns a service trusting roots for hardware keys, or
+// without attestation roots when there are none.
+func newService(t *testing.T, q db.Querier, roots ...*x509.Certificate) *webauthn.Service {
+	t.Helper()
+	cfg := webauthn.Config{
+		RPName:           "authd",
+		UserVerification: webauthn.UserVerificationPreferred,
+		Timeout:          time.Minute,
+	}
+	if len(roots) > 0 {
+		var b []byte
+		for _, cert := range roots {
+			b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+		cfg.AttestationCAFile = filepath.Join(t.TempDir(), "roots.pem")
+		if err := os.WriteFile(cfg.AttestationCAFile, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := webauthn.NewService(cfg, baseURL, q, oauth.NewMemoryKVStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func register(t *testing.T, s *webauthn.Service, a *authenticator, att attestation) (*webauthn.Credential, error) {
+	t.Helper()
+	ctx := context.Background()
+	opts, err := s.BeginRegistration(ctx, "", userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.FinishRegistration(ctx, "", userID, a.register(t, opts, att))
+}
+
+func login(t *testing.T, s *webauthn.Service, a *authenticator) (*webauthn.Login, error) {
+	t.Helper()
+	ctx := context.Background()
+	opts, err := s.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.AllowCredentials) != 0 {
+		t.Fatalf("AllowCredentials = %v, want none for discoverable login", opts.AllowCredentials)
+	}
+	return s.FinishLogin(ctx, "", a.login(t, opts))
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	ca, caKey := newCA(t)
+	otherCA, _ := newCA(t)
+
+	tests := []struct {
+		name string
+		// roots are the attestation roots the service trusts.
+		roots          []*x509.Certificate
+		backupEligible bool
+		// x5c attests with a certificate from ca instead of the
+		// credential's own key.
+		x5c     bool
+		fmt     string
+		wantAMR string
+	}{
+		{name: "None", fmt: webauthn.FormatNone, wantAMR: session.AMRSoftwareKey},
+		{name: "NoneWithRoots", roots: []*x509.Certificate{ca}, fmt: webauthn.FormatNone, wantAMR: session.AMRSoftwareKey},
+		{name: "PackedSelf", fmt: webauthn.FormatPacked, wantAMR: session.AMRSoftwareKey},
+		{name: "PackedSelfWithRoots", roots: []*x509.Certificate{ca}, fmt: webauthn.FormatPacked, wantAMR: session.AMRSoftwareKey},
+		{name: "PackedX5CWithoutRoots", x5c: true, fmt: webauthn.FormatPacked, wantAMR: session.AMRSoftwareKey},
+		{name: "PackedX5CTrusted", roots: []*x509.Certificate{ca}, x5c: true, fmt: webauthn.FormatPacked, wantAMR: session.AMRHardwareKey},
+		{name: "PackedX5CUntrusted", roots: []*x509.Certificate{otherCA}, x5c: true, fmt: webauthn.FormatPacked, wantAMR: session.AMRSoftwareKey},
+		{name: "PackedX5CTrustedBackupEligible", roots: []*x509.Certificate{ca}, backupEligible: true, x5c: true, fmt: webauthn.FormatPacked, wantAMR: session.AMRSoftwareKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newFakeQuerier()
+			s := newService(t, q, tt.roots...)
+			a := newAuthenticator(t)
+			if tt.backupEligible {
+				a.flags |= flagBE
+			}
+			att := attestation{fmt: tt.fmt}
+			if tt.x5c {
+				cert, key := newAttestationCert(t, ca, caKey, a.aaguid)
+				att.chain, att.key = []*x509.Certificate{cert}, key
+			}
+
+			cred, err := register(t, s, a, att)
+			if err != nil {
+				t.Fatalf("FinishRegistration: %v", err)
+			}
+			if !bytes.Equal(cred.ID, a.id) || cred.AttestationFormat != tt.fmt || cred.AMR != tt.wantAMR || cred.BackupEligible != tt.backupEligible {
+				t.Fatalf("credential = %+v, want ID %x, format %s, AMR %s, backup eligible %v", cred, a.id, tt.fmt, tt.wantAMR, tt.backupEligible)
+			}
+
+			got, err := login(t, s, a)
+			if err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+			want := webauthn.Login{UserID: userID, Username: username, AMR: tt.wantAMR}
+			if *got != want {
+				t.Fatalf("FinishLogin = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestRegisterRejects(t *testing.T) {
+	ca, caKey := newCA(t)
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, a *authenticator, att *attestation)
+		want   error
+	}{
+		{
+			name: "SelfAttestationByAnotherKey",
+			modify: func(t *testing.T, a *authenticator, att *attestation) {
+				att.key = newKey(t)
+			},
+			want: webauthn.ErrInvalidResponse,
+		},
+		{
+			name: "AttestationSignedByAnotherKey",
+			modify: func(t *testing.T, a *authenticator, att *attestation) {
+				cert, _ := newAttestationCert(t, ca, caKey, a.aaguid)
+				att.chain, att.key = []*x509.Certificate{cert}, newKey(t)
+			},
+			want: webauthn.ErrInvalidResponse,
+		},
+		{
+			name: "AttestationCertForAnotherModel",
+			modify: func(t *testing.T, a *authenticator, att *attestation) {
+				cert, key := newAttestationCert(t, ca, caKey, randomBytes(t, 16))
+				att.chain, att.key = []*x509.Certificate{cert}, key
+			},
+			want: webauthn.ErrInvalidResponse,
+		},
+		{
+			name: "UnsupportedFormat",
+			modify: func(t *testing.T, a *authenticator, att *attestation) {
+				att.fmt = "tpm"
+			},
+			want: webauthn.ErrUnsupportedAttestation,
+		},
+		{
+			name: "UserNotPresent",
+			modify: func(t *testing.T, a *authenticator, att *attestation) {
+				a.flags &^= flagUP
+			},
+			want: webauthn.ErrInvalidResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newFakeQuerier()
+			s := newService(t, q, ca)
+			a := newAuthenticator(t)
+			att := attestation{fmt: webauthn.FormatPacked}
+			tt.modify(t, a, &att)
+
+			_, err := register(t, s, a, att)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("FinishRegistration error = %v, want %v", err, tt.want)
+			}
+			if len(q.creds) != 0 {
+				t.Fatalf("%d credentials stored, want none", len(q.creds))
+			}
+		})
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	s := newService(t, newFakeQuerier())
+	a := newAuthenticator(t)
+	if _, err := register(t, s, a, attestation{fmt: webauthn.FormatNone}); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if _, err := register(t, s, a, attestation{fmt: webauthn.FormatNone}); !errors.Is(err, webauthn.ErrCredentialExists) {
+		t.Fatalf("second FinishRegistration error = %v, want %v", err, webauthn.ErrCredentialExists)
+	}
+}
+
+func TestChallengeIsUsedOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, newFakeQuerier())
+	a := newAuthenticator(t)
+	if _, err := register(t, s, a, attestation{fmt: webauthn.FormatNone}); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	opts, err := s.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := a.login(t, opts)
+	if _, err := s.FinishLogin(ctx, "", resp); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if _, err := s.FinishLogin(ctx, "", resp); !errors.Is(err, webauthn.ErrChallengeNotFound) {
+		t.Fatalf("replayed FinishLogin error = %v, want %v", err, webauthn.ErrChallengeNotFound)
+	}
+}
+
+func TestLoginSignCount(t *testing.T) {
+	tests := []struct {
+		name string
+		// counters are what the authenticator reports at registration
+		// and each login after it.
+		counters []uint32
+		want     []error
+	}{
+		{name: "Increasing", counters: []uint32{1, 2, 7}, want: []error{nil, nil}},
+		{name: "Repeated", counters: []uint32{1, 2, 2}, want: []error{nil, webauthn.ErrSignCount}},
+		{name: "Regressed", counters: []uint32{5, 6, 3}, want: []error{nil, webauthn.ErrSignCount}},
+		{name: "Zero", counters: []uint32{0, 0, 0}, want: []error{nil, nil}},
+		{name: "ZeroAfterCounting", counters: []uint32{1, 2, 0}, want: []error{nil, webauthn.ErrSignCount}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(t, newFakeQuerier())
+			a := newAuthenticator(t)
+			a.counter = tt.counters[0]
+			if _, err := register(t, s, a, attestation{fmt: webauthn.FormatNone}); err != nil {
+				t.Fatalf("FinishRegistration: %v", err)
+			}
+			for i, n := range tt.counters[1:] {
+				a.counter = n
+				_, err := login(t, s, a)
+				if !errors.Is(err, tt.want[i]) {
+					t.Fatalf("FinishLogin with counter %d error = %v, want %v", n, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoginRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		tenant string
+		modify func(t *testing.T, resp *webauthn.AssertionResponse)
+		want   error
+	}{
+		{
+			name:   "OtherTenant",
+			tenant: "acme",
+			want:   webauthn.ErrCredentialNotFound,
+		},
+		{
+			name: "AnotherUserHandle",
+			modify: func(t *testing.T, resp *webauthn.AssertionResponse) {
+				resp.Response.UserHandle = []byte("someone-else")
+			},
+			want: webauthn.ErrInvalidResponse,
+		},
+		{
+			name: "BadSignature",
+			modify: func(t *testing.T, resp *webauthn.AssertionResponse) {
+				resp.Response.Signature = sign(t, newKey(t), resp.Response.AuthenticatorData)
+			},
+			want: webauthn.ErrInvalidResponse,
+		},
+		{
+			name: "UnknownCredential",
+			modify: func(t *testing.T, resp *webauthn.AssertionResponse) {
+				resp.RawID = randomBytes(t, 16)
+			},
+			want: webauthn.ErrCredentialNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newService(t, newFakeQuerier())
+			a := newAuthenticator(t)
+			if _, err := register(t, s, a, attestation{fmt: webauthn.FormatNone}); err != nil {
+				t.Fatalf("FinishRegistration: %v", err)
+			}
+
+			opts, err := s.BeginLogin(ctx, tt.tenant)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := a.login(t, opts)
+			if tt.modify != nil {
+				tt.modify(t, &resp)
+			}
+			if _, err := s.FinishLogin(ctx, tt.tenant, resp); !errors.Is(err, tt.want) {
+				t.Fatalf("FinishLogin error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
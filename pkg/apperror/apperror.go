@@ -48,6 +48,7 @@ func New(err error, code int, message string, status ErrorStatus) *AppError {
 	return &AppError{
 		Code:    code,
 		Message: message,
+		Status:  status,
 		Err:     err,
 		Stack:   stack,
 	}
@@ -3,7 +3,21 @@ package apperror
 type ErrorStatus string
 
 var (
-	StatusJWKError ErrorStatus = "JWKS_ERROR"
+	StatusJWKError            ErrorStatus = "JWKS_ERROR"
+	StatusKeyImportError      ErrorStatus = "KEY_IMPORT_ERROR"
+	StatusUserError           ErrorStatus = "USER_ERROR"
+	StatusResourceServerError ErrorStatus = "RESOURCE_SERVER_ERROR"
+	StatusAudienceError       ErrorStatus = "AUDIENCE_ERROR"
+	StatusRBACError           ErrorStatus = "RBAC_ERROR"
+	StatusAuthzError          ErrorStatus = "AUTHZ_ERROR"
+	StatusTenantError         ErrorStatus = "TENANT_ERROR"
+	StatusClientError         ErrorStatus = "CLIENT_ERROR"
+	StatusMFAError            ErrorStatus = "MFA_ERROR"
+	StatusWebAuthnError       ErrorStatus = "WEBAUTHN_ERROR"
+
+	StatusBadRequest   ErrorStatus = "BAD_REQUEST"
+	StatusUnauthorized ErrorStatus = "UNAUTHORIZED"
+	StatusForbidden    ErrorStatus = "FORBIDDEN"
 
 	StatusFiberError ErrorStatus = "FIBER_ERROR"
 
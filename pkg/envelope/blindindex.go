@@ -0,0 +1,76 @@
+package envelope
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"strings"
+)
+
+const minBlindIndexKeySize = 32
+
+// BlindIndex computes deterministic keyed digests of plaintext values so an
+// encrypted column can be searched by equality without decrypting it, e.g.
+// storing BlindIndex.Compute(email) next to Crypter.Encrypt(email).
+//
+// Each index derives its own key from the root key and its name, so digests
+// of the same value in two columns cannot be correlated.
+type BlindIndex struct {
+	key       []byte
+	size      int
+	normalize func(string) string
+}
+
+type BlindIndexOption func(*BlindIndex)
+
+// WithIndexSize truncates digests to size bytes. Shorter digests leak less
+// about the value at the cost of false positives, which callers must filter
+// after decrypting the candidate rows.
+func WithIndexSize(size int) BlindIndexOption {
+	return func(b *BlindIndex) {
+		b.size = min(max(size, 1), sha256.Size)
+	}
+}
+
+// WithNormalizer transforms values before hashing, e.g. to make lookups case
+// insensitive.
+func WithNormalizer(fn func(string) string) BlindIndexOption {
+	return func(b *BlindIndex) {
+		b.normalize = fn
+	}
+}
+
+// NormalizeEmail lower-cases and trims an email address.
+func NormalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func NewBlindIndex(rootKey []byte, name string, opts ...BlindIndexOption) (*BlindIndex, error) {
+	if len(rootKey) < minBlindIndexKeySize {
+		return nil, errors.New("envelope: blind index key must be at least 32 bytes")
+	}
+
+	key, err := hkdf.Key(sha256.New, rootKey, nil, "blind-index:"+name, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &BlindIndex{
+		key:       key,
+		size:      sha256.Size,
+		normalize: func(s string) string { return s },
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b, nil
+}
+
+func (b *BlindIndex) Compute(value string) []byte {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(b.normalize(value)))
+	return mac.Sum(nil)[:b.size]
+}
@@ -0,0 +1,187 @@
+package envelope
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDEKTTL       = 5 * time.Minute
+	defaultDEKCacheSize = 1024
+)
+
+// Crypter encrypts values into self-contained envelopes that carry their
+// wrapped DEK and KEK reference, e.g. for PII columns.
+//
+// To avoid a KeyWrapper round trip per value, the DEK used for encryption is
+// reused until it is older than the configured TTL, and unwrapped DEKs are
+// cached for decryption for the same duration.
+type Crypter struct {
+	wrapper   KeyWrapper
+	suite     string
+	ttl       time.Duration
+	cacheSize int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	current *activeDEK
+	cache   map[[sha256.Size]byte]cachedDEK
+}
+
+type activeDEK struct {
+	dek     []byte
+	wrapped []byte
+	kekRef  string
+	expires time.Time
+}
+
+type cachedDEK struct {
+	dek     []byte
+	expires time.Time
+}
+
+func New(wrapper KeyWrapper, opts ...Option) *Crypter {
+	c := &Crypter{
+		wrapper:   wrapper,
+		suite:     DefaultSuite,
+		ttl:       defaultDEKTTL,
+		cacheSize: defaultDEKCacheSize,
+		now:       time.Now,
+		cache:     make(map[[sha256.Size]byte]cachedDEK),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Encrypt seals plaintext and binds aad, and the KEK reference and wrapped
+// DEK stored with it, to it. The same aad must be given to
+// Decrypt; a common choice is the table, column and row id of the value.
+func (c *Crypter) Encrypt(ctx context.Context, plaintext, aad []byte) ([]byte, error) {
+	d, err := c.activeDEK(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(Header{
+		Version:    CurrentVersion,
+		Suite:      c.suite,
+		KEKRef:     d.kekRef,
+		WrappedDEK: d.wrapped,
+	}, d.dek, plaintext, aad)
+}
+
+// Decrypt opens a ciphertext produced by Encrypt.
+func (c *Crypter) Decrypt(ctx context.Context, ciphertext, aad []byte) ([]byte, error) {
+	s, err := parse(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.WrappedDEK) == 0 {
+		return nil, ErrMalformed
+	}
+
+	dek, err := c.unwrap(ctx, s.WrappedDEK, s.KEKRef)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(s, dek, aad)
+}
+
+func (c *Crypter) activeDEK(ctx context.Context) (*activeDEK, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.current != nil && now.Before(c.current.expires) {
+		return c.current, nil
+	}
+
+	dek, err := NewDEK()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, kekRef, err := c.wrapper.Wrap(ctx, dek)
+	if err != nil {
+		return nil, err
+	}
+
+	c.current = &activeDEK{
+		dek:     dek,
+		wrapped: wrapped,
+		kekRef:  kekRef,
+		expires: now.Add(c.ttl),
+	}
+	c.put(cacheID(wrapped, kekRef), dek, now)
+
+	return c.current, nil
+}
+
+func (c *Crypter) unwrap(ctx context.Context, wrapped []byte, kekRef string) ([]byte, error) {
+	id := cacheID(wrapped, kekRef)
+
+	c.mu.Lock()
+	if e, ok := c.cache[id]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.dek, nil
+	}
+	c.mu.Unlock()
+
+	dek, err := c.wrapper.Unwrap(ctx, wrapped, kekRef)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.put(id, dek, c.now())
+	c.mu.Unlock()
+
+	return dek, nil
+}
+
+func cacheID(wrapped []byte, kekRef string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(kekRef))
+	h.Write([]byte{0})
+	h.Write(wrapped)
+	return [sha256.Size]byte(h.Sum(nil))
+}
+
+// put caches an unwrapped DEK. c.mu must be held.
+func (c *Crypter) put(id [sha256.Size]byte, dek []byte, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	if len(c.cache) >= c.cacheSize {
+		for k, e := range c.cache {
+			if !now.Before(e.expires) {
+				delete(c.cache, k)
+			}
+		}
+	}
+	if len(c.cache) >= c.cacheSize {
+		// Still full of live entries: drop an arbitrary one.
+		for k := range c.cache {
+			delete(c.cache, k)
+			break
+		}
+	}
+
+	c.cache[id] = cachedDEK{dek: dek, expires: now.Add(c.ttl)}
+}
+
+// Purge drops the active DEK and every cached DEK, e.g. after a KEK rotation.
+func (c *Crypter) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current = nil
+	clear(c.cache)
+}
@@ -0,0 +1,180 @@
+// Package envelope implements DEK/KEK envelope encryption for
+// application-level secrets such as signing keys and PII columns.
+//
+// A random data encryption key (DEK) encrypts the payload with an AEAD cipher
+// suite, and the DEK itself is wrapped by a key encryption key (KEK) held by a
+// KeyWrapper (a local key, a KMS, ...). Ciphertexts are self-describing JSON
+// documents so that the suite can change without breaking stored data.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Envelope versions. Version 1 authenticates only the caller's AAD, so the
+// header of a version 1 envelope, including kek_ref and the wrapped DEK,
+// can be changed unnoticed; it is still opened, and should be re-encrypted.
+// Version 2 also authenticates the header.
+const (
+	Version1 = 1
+	Version2 = 2
+
+	CurrentVersion = Version2
+)
+
+// Cipher suites, named after their JOSE "enc" identifiers.
+const (
+	SuiteA256GCM = "A256GCM"
+	SuiteXC20P   = "XC20P"
+)
+
+const DefaultSuite = SuiteA256GCM
+
+// DEKSize is the size of generated DEKs, valid for every suite.
+const DEKSize = 32
+
+var (
+	ErrUnknownSuite   = errors.New("envelope: unknown cipher suite")
+	ErrUnknownVersion = errors.New("envelope: unknown version")
+	ErrMalformed      = errors.New("envelope: malformed ciphertext")
+)
+
+// Header is the plaintext part of an envelope, authenticated from version
+// 2 on.
+type Header struct {
+	Version int    `json:"v"`
+	Suite   string `json:"suite"`
+	// KEKRef and WrappedDEK are set by Crypter so a ciphertext carries
+	// everything needed to decrypt it. Callers that store the wrapped DEK
+	// elsewhere (e.g. jwk_keys.wrapped_dek) leave them empty.
+	KEKRef     string `json:"kek_ref,omitempty"`
+	WrappedDEK []byte `json:"dek,omitempty"`
+}
+
+type sealed struct {
+	Header
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// NewAEAD returns the AEAD for suite keyed with key.
+func NewAEAD(suite string, key []byte) (cipher.AEAD, error) {
+	switch suite {
+	case SuiteA256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case SuiteXC20P:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSuite, suite)
+	}
+}
+
+// NewDEK returns a fresh random DEK.
+func NewDEK() ([]byte, error) {
+	dek := make([]byte, DEKSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	return dek, nil
+}
+
+// Seal encrypts plaintext under dek with suite and returns a CurrentVersion
+// envelope. aad is authenticated but not stored.
+func Seal(suite string, dek, plaintext, aad []byte) ([]byte, error) {
+	return seal(Header{Version: CurrentVersion, Suite: suite}, dek, plaintext, aad)
+}
+
+func seal(h Header, dek, plaintext, aad []byte) ([]byte, error) {
+	aead, err := NewAEAD(h.Suite, dek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	fullAAD, err := h.aad(aad)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(sealed{
+		Header:     h,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, fullAAD),
+	})
+}
+
+// ParseHeader returns the header of an envelope without decrypting it, for
+// callers whose AAD depends on the suite or version.
+func ParseHeader(ciphertext []byte) (Header, error) {
+	s, err := parse(ciphertext)
+	if err != nil {
+		return Header{}, err
+	}
+	return s.Header, nil
+}
+
+// Open decrypts an envelope produced by Seal.
+func Open(dek, ciphertext, aad []byte) ([]byte, error) {
+	s, err := parse(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return open(s, dek, aad)
+}
+
+func parse(ciphertext []byte) (sealed, error) {
+	var s sealed
+	if err := json.Unmarshal(ciphertext, &s); err != nil {
+		return sealed{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	if s.Version != Version1 && s.Version != Version2 {
+		return sealed{}, fmt.Errorf("%w: %d", ErrUnknownVersion, s.Version)
+	}
+	return s, nil
+}
+
+func open(s sealed, dek, aad []byte) ([]byte, error) {
+	aead, err := NewAEAD(s.Suite, dek)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Nonce) != aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	fullAAD, err := s.Header.aad(aad)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, s.Nonce, s.Ciphertext, fullAAD)
+}
+
+// aad returns the associated data of an envelope with header h and the
+// caller's aad: from version 2 on, the length-prefixed encoded header
+// followed by aad.
+func (h Header) aad(aad []byte) ([]byte, error) {
+	if h.Version == Version1 {
+		return aad, nil
+	}
+	encoded, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(encoded)))
+	out = append(out, encoded...)
+	return append(out, aad...), nil
+}
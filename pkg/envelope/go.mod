@@ -0,0 +1,7 @@
+module github.com/yokeTH/yoketh-backend-oss/pkg/envelope
+
+go 1.25.0
+
+require golang.org/x/crypto v0.41.0
+
+require golang.org/x/sys v0.35.0 // indirect
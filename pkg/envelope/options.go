@@ -0,0 +1,27 @@
+package envelope
+
+import "time"
+
+type Option func(*Crypter)
+
+// WithSuite sets the cipher suite used for new ciphertexts
+func WithSuite(suite string) Option {
+	return func(c *Crypter) {
+		c.suite = suite
+	}
+}
+
+// WithDEKCacheTTL sets how long a DEK is reused for encryption and kept
+// unwrapped for decryption. Zero disables caching.
+func WithDEKCacheTTL(ttl time.Duration) Option {
+	return func(c *Crypter) {
+		c.ttl = ttl
+	}
+}
+
+// WithDEKCacheSize sets the maximum number of unwrapped DEKs kept in memory
+func WithDEKCacheSize(size int) Option {
+	return func(c *Crypter) {
+		c.cacheSize = max(size, 1)
+	}
+}
@@ -1,4 +1,4 @@
-package key
+package envelope
 
 import (
 	"context"
@@ -7,24 +7,42 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
 )
 
+// KeyWrapper wraps and unwraps DEKs with a KEK. kekRef identifies the KEK
+// used so that it can be rotated without re-encrypting every payload.
+type KeyWrapper interface {
+	Wrap(ctx context.Context, dek []byte) (wrapped []byte, kekRef string, err error)
+	Unwrap(ctx context.Context, wrapped []byte, kekRef string) (dek []byte, err error)
+}
+
+// LocalWrapper wraps DEKs with AES-GCM under a KEK held in memory.
 type LocalWrapper struct {
 	kek []byte
 	ref string
 }
 
-func NewLocalWrapperFromEnv() (*LocalWrapper, error) {
-	hexKey := os.Getenv("KEK_HEX")
+func NewLocalWrapper(kek []byte, ref string) (*LocalWrapper, error) {
+	if len(kek) != 16 && len(kek) != 24 && len(kek) != 32 {
+		return nil, errors.New("kek must be 16/24/32 bytes")
+	}
+	return &LocalWrapper{kek: kek, ref: ref}, nil
+}
+
+// NewLocalWrapperFromEnv reads a hex encoded KEK from the environment
+// variable name, e.g. KEK_HEX.
+func NewLocalWrapperFromEnv(name string) (*LocalWrapper, error) {
+	hexKey := os.Getenv(name)
 	if len(hexKey) == 0 {
-		return nil, errors.New("KEK_HEX not set")
+		return nil, fmt.Errorf("%s not set", name)
 	}
 	raw, err := hex.DecodeString(hexKey)
 	if err != nil || (len(raw) != 16 && len(raw) != 24 && len(raw) != 32) {
-		return nil, errors.New("KEK_HEX must be 16/24/32 bytes hex")
+		return nil, fmt.Errorf("%s must be 16/24/32 bytes hex", name)
 	}
-	return &LocalWrapper{kek: raw, ref: "env://KEK_HEX"}, nil
+	return &LocalWrapper{kek: raw, ref: "env://" + name}, nil
 }
 
 func (w *LocalWrapper) Wrap(ctx context.Context, dek []byte) ([]byte, string, error) {
@@ -0,0 +1,114 @@
+package httpserver
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/apperror"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/policy"
+	"github.com/yokeTH/yoketh-backend-oss/pkg/verify"
+)
+
+type claimsKey struct{}
+
+// AuthorizeConfig configures Authorize.
+type AuthorizeConfig struct {
+	// Decider makes the decisions: a *policy.Evaluator evaluates policies
+	// in process and a *policy.Remote asks the auth service.
+	Decider policy.Decider
+	// Claims authenticates the request and returns the claims of its
+	// access token, e.g. VerifiedClaims. Requests it fails for get a 401.
+	Claims func(c fiber.Ctx) (map[string]any, error)
+	// Action names the action of a request, its method by default.
+	Action func(c fiber.Ctx) string
+	// Resource returns the attributes of the requested resource, the route
+	// parameters by default. Those are only known when Authorize is added
+	// to the route itself rather than with Use.
+	Resource func(c fiber.Ctx) (map[string]any, error)
+}
+
+// Authorize returns middleware letting a request through only when
+// cfg.Decider allows it. The request attributes seen by policies are its
+// method, path and ip. The claims are kept for handlers, see Claims.
+func Authorize(cfg AuthorizeConfig) fiber.Handler {
+	if cfg.Action == nil {
+		cfg.Action = func(c fiber.Ctx) string { return c.Method() }
+	}
+	if cfg.Resource == nil {
+		cfg.Resource = routeParams
+	}
+
+	return func(c fiber.Ctx) error {
+		claims, err := cfg.Claims(c)
+		if err != nil {
+			c.Set(fiber.HeaderWWWAuthenticate, verify.Challenge(err))
+			return apperror.UnauthorizedError(err, "invalid access token", apperror.StatusUnauthorized)
+		}
+		resource, err := cfg.Resource(c)
+		if err != nil {
+			return apperror.BadRequestError(err, "invalid resource", apperror.StatusBadRequest)
+		}
+
+		d, err := cfg.Decider.Decide(c, policy.Input{
+			Claims:   claims,
+			Token:    accessToken(c),
+			Action:   cfg.Action(c),
+			Resource: resource,
+			Request: map[string]any{
+				"method": c.Method(),
+				"path":   c.Path(),
+				"ip":     c.IP(),
+			},
+		})
+		if err != nil {
+			return apperror.New(err, fiber.StatusServiceUnavailable, "authorization unavailable", apperror.StatusAuthzError)
+		}
+		if !d.Allow {
+			return apperror.ForbiddenError(errors.New("denied by policy"), "access denied", apperror.StatusForbidden)
+		}
+
+		c.Locals(claimsKey{}, claims)
+		return c.Next()
+	}
+}
+
+// Claims returns the claims of a request let through by Authorize.
+func Claims(c fiber.Ctx) map[string]any {
+	return fiber.Locals[map[string]any](c, claimsKey{})
+}
+
+// VerifiedClaims returns an AuthorizeConfig.Claims verifying the access
+// token of the request with v, including its DPoP or certificate binding.
+func VerifiedClaims(v *verify.Verifier) func(c fiber.Ctx) (map[string]any, error) {
+	return func(c fiber.Ctx) (map[string]any, error) {
+		path, _, _ := strings.Cut(c.OriginalURL(), "?")
+		r := verify.Request{
+			Authorization: c.Get(fiber.HeaderAuthorization),
+			DPoP:          c.Get("DPoP"),
+			Method:        c.Method(),
+			URL:           c.BaseURL() + path,
+		}
+		if cs := c.RequestCtx().TLSConnectionState(); cs != nil && len(cs.PeerCertificates) > 0 {
+			r.Certificate = cs.PeerCertificates[0]
+		}
+		claims, err := v.VerifyRequest(c, r)
+		if err != nil {
+			return nil, err
+		}
+		return claims.Map(), nil
+	}
+}
+
+func accessToken(c fiber.Ctx) string {
+	_, token, _ := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	return token
+}
+
+func routeParams(c fiber.Ctx) (map[string]any, error) {
+	params := make(map[string]any)
+	for _, name := range c.Route().Params {
+		params[name] = c.Params(name)
+	}
+	return params, nil
+}
@@ -13,16 +13,17 @@ import (
 )
 
 type Config struct {
-	Env                  string `env:"ENV"`
-	Name                 string `env:"NAME"`
-	Port                 int    `env:"PORT"`
-	BodyLimitMB          int    `env:"BODY_LIMIT_MB"`
-	CorsAllowOrigins     string `env:"CORS_ALLOW_ORIGINS"`
-	CorsAllowMethods     string `env:"CORS_ALLOW_METHODS"`
-	CorsAllowHeaders     string `env:"CORS_ALLOW_HEADERS"`
-	CorsAllowCredentials bool   `env:"CORS_ALLOW_CREDENTIALS"`
-	SwaggerUser          string `env:"SWAGGER_USER"`
-	SwaggerPass          string `env:"SWAGGER_PASS"`
+	Env                  string    `env:"ENV"`
+	Name                 string    `env:"NAME"`
+	Port                 int       `env:"PORT"`
+	BodyLimitMB          int       `env:"BODY_LIMIT_MB"`
+	CorsAllowOrigins     string    `env:"CORS_ALLOW_ORIGINS"`
+	CorsAllowMethods     string    `env:"CORS_ALLOW_METHODS"`
+	CorsAllowHeaders     string    `env:"CORS_ALLOW_HEADERS"`
+	CorsAllowCredentials bool      `env:"CORS_ALLOW_CREDENTIALS"`
+	SwaggerUser          string    `env:"SWAGGER_USER"`
+	SwaggerPass          string    `env:"SWAGGER_PASS"`
+	TLS                  TLSConfig `envPrefix:"TLS_"`
 }
 
 const defaultEnv = "unknown"
@@ -86,7 +87,7 @@ func New(opts ...ServerOption) *Server {
 
 func (s *Server) Start(ctx context.Context, stop context.CancelFunc) {
 	go func() {
-		if err := s.Listen(fmt.Sprintf(":%d", s.config.Port)); err != nil {
+		if err := s.listen(fmt.Sprintf(":%d", s.config.Port)); err != nil {
 			log.Fatalf("failed to start server: %v", err)
 			stop()
 		}
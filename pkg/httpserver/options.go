@@ -77,3 +77,11 @@ func WithConfig(config *Config) ServerOption {
 		s.config = config
 	}
 }
+
+// WithTLS serves HTTPS with the given certificate and client certificate
+// settings
+func WithTLS(tls TLSConfig) ServerOption {
+	return func(s *Server) {
+		s.config.TLS = tls
+	}
+}
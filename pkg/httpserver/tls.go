@@ -0,0 +1,93 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Client certificate modes for TLSConfig.ClientAuth.
+const (
+	ClientAuthNone = "none"
+	// ClientAuthRequest asks for a certificate without verifying it, leaving
+	// verification to handlers (e.g. self-signed mTLS client authentication).
+	ClientAuthRequest = "request"
+	// ClientAuthVerifyIfGiven verifies a certificate against ClientCAFile
+	// when the client sends one.
+	ClientAuthVerifyIfGiven = "verify_if_given"
+	// ClientAuthRequire requires a certificate verified against
+	// ClientCAFile.
+	ClientAuthRequire = "require"
+)
+
+// TLSConfig enables HTTPS when CertFile and KeyFile are set.
+type TLSConfig struct {
+	CertFile     string `env:"CERT_FILE"`
+	KeyFile      string `env:"KEY_FILE"`
+	ClientCAFile string `env:"CLIENT_CA_FILE"`
+	ClientAuth   string `env:"CLIENT_AUTH"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+func (c TLSConfig) clientAuthType() (tls.ClientAuthType, error) {
+	switch c.ClientAuth {
+	case "":
+		if c.ClientCAFile != "" {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.RequestClientCert, nil
+	case ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unknown tls client auth mode %q", c.ClientAuth)
+	}
+}
+
+func (s *Server) listen(addr string) error {
+	cfg := s.config.TLS
+	if !cfg.Enabled() {
+		return s.Listen(addr)
+	}
+
+	clientAuth, err := cfg.clientAuthType()
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+		}
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven && clientCAs == nil {
+		return fmt.Errorf("tls client auth %q needs a client CA file", cfg.ClientAuth)
+	}
+
+	return s.Listen(addr, fiber.ListenConfig{
+		CertFile:      cfg.CertFile,
+		CertKeyFile:   cfg.KeyFile,
+		TLSMinVersion: tls.VersionTLS12,
+		TLSConfigFunc: func(tc *tls.Config) {
+			tc.ClientAuth = clientAuth
+			tc.ClientCAs = clientCAs
+		},
+	})
+}
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+)
+
+// costLimit bounds the work of evaluating a single condition.
+const costLimit = 100000
+
+// Evaluator decides locally. A matching deny policy wins over allow
+// policies, and access is denied when no policy matches. A condition that
+// fails to evaluate, e.g. on a missing claim, does not match an allow
+// policy and does match a deny policy.
+type Evaluator struct {
+	policies []compiled
+}
+
+type compiled struct {
+	Policy
+	prg cel.Program
+}
+
+// NewEvaluator compiles policies.
+func NewEvaluator(policies ...Policy) (*Evaluator, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("action", cel.StringType),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Evaluator{policies: make([]compiled, 0, len(policies))}
+	for _, p := range policies {
+		if p.Effect != EffectAllow && p.Effect != EffectDeny {
+			return nil, fmt.Errorf("%w %q: effect must be allow or deny", ErrInvalidPolicy, p.Name)
+		}
+		c := compiled{Policy: p}
+		if p.Condition != "" {
+			ast, iss := env.Compile(p.Condition)
+			if iss.Err() != nil {
+				return nil, fmt.Errorf("%w %q: %w", ErrInvalidPolicy, p.Name, iss.Err())
+			}
+			if ast.OutputType() != cel.BoolType {
+				return nil, fmt.Errorf("%w %q: condition must be a bool", ErrInvalidPolicy, p.Name)
+			}
+			if c.prg, err = env.Program(ast, cel.CostLimit(costLimit)); err != nil {
+				return nil, fmt.Errorf("%w %q: %w", ErrInvalidPolicy, p.Name, err)
+			}
+		}
+		e.policies = append(e.policies, c)
+	}
+	return e, nil
+}
+
+// policyFile is the document read by LoadFile.
+type policyFile struct {
+	Policies []Policy `json:"policies"`
+}
+
+// LoadFile compiles the policies of a JSON document of the form
+// {"policies": [...]}.
+func LoadFile(path string) (*Evaluator, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f policyFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("policy: %s: %w", path, err)
+	}
+	return NewEvaluator(f.Policies...)
+}
+
+func (e *Evaluator) Decide(ctx context.Context, in Input) (Decision, error) {
+	vars := map[string]any{
+		"claims":   orEmpty(in.Claims),
+		"action":   in.Action,
+		"resource": orEmpty(in.Resource),
+		"request":  orEmpty(in.Request),
+	}
+
+	var allow string
+	for _, p := range e.policies {
+		if len(p.Actions) > 0 && !slices.Contains(p.Actions, in.Action) {
+			continue
+		}
+		if p.Effect == EffectAllow && allow != "" {
+			continue
+		}
+		holds, err := p.holds(ctx, vars)
+		if err := ctx.Err(); err != nil {
+			return Decision{}, err
+		}
+		switch {
+		case p.Effect == EffectDeny && (holds || err != nil):
+			return Decision{Allow: false, Policy: p.Name}, nil
+		case p.Effect == EffectAllow && holds && err == nil:
+			allow = p.Name
+		}
+	}
+	return Decision{Allow: allow != "", Policy: allow}, nil
+}
+
+func (p compiled) holds(ctx context.Context, vars map[string]any) (bool, error) {
+	if p.prg == nil {
+		return true, nil
+	}
+	out, _, err := p.prg.ContextEval(ctx, vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("policy %q: condition is not a bool", p.Name)
+	}
+	return b, nil
+}
+
+func orEmpty(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
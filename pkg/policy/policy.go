@@ -0,0 +1,62 @@
+// Package policy makes attribute-based authorization decisions. Policies are
+// CEL expressions over the claims of the caller's access token, the action
+// and the attributes of the resource and request; they are evaluated
+// locally with an Evaluator or by the auth service's decision endpoint
+// through a Remote.
+package policy
+
+import (
+	"context"
+	"errors"
+)
+
+// Effects of a policy.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+var (
+	ErrInvalidPolicy = errors.New("policy: invalid policy")
+	ErrUnavailable   = errors.New("policy: decision endpoint unavailable")
+)
+
+// Input is what a decision is made on. Policies see its fields as the
+// claims, action, resource and request variables.
+type Input struct {
+	// Claims are the verified claims of the caller's access token.
+	Claims map[string]any `json:"-"`
+	// Token is the access token itself. A Remote sends it in place of the
+	// claims, which the decision endpoint takes from verifying it.
+	Token    string         `json:"token,omitempty"`
+	Action   string         `json:"action"`
+	Resource map[string]any `json:"resource,omitempty"`
+	// Request holds attributes of the request such as method, path and ip.
+	Request map[string]any `json:"request,omitempty"`
+}
+
+// Decision is the outcome of a check. Policy names the policy that decided
+// it, empty when none applied and access is denied by default.
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Policy string `json:"policy,omitempty"`
+}
+
+// Decider makes authorization decisions. An error means no decision could be
+// made, and callers must deny access.
+type Decider interface {
+	Decide(ctx context.Context, in Input) (Decision, error)
+}
+
+// Policy allows or denies Actions when Condition holds. A Policy without
+// Actions applies to every action and one without a Condition always holds.
+//
+// Conditions are CEL expressions evaluating to a bool, for example
+//
+//	claims.org == resource.org && "orders:write" in claims.permissions
+type Policy struct {
+	Name      string   `json:"name"`
+	Effect    string   `json:"effect"`
+	Actions   []string `json:"actions,omitempty"`
+	Condition string   `json:"condition,omitempty"`
+}
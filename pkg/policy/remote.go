@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CheckPath is the path of the auth service's decision endpoint.
+const CheckPath = "/authz/check"
+
+const maxDecisionSize = 1 << 16
+
+// Remote asks the auth service's decision endpoint, which verifies
+// Input.Token and evaluates its own policies. Input.Claims is not sent.
+type Remote struct {
+	url    string
+	client *http.Client
+}
+
+// NewRemote returns a Remote for the decision endpoint at url, e.g.
+// "https://auth.example.com"+CheckPath. client may be nil.
+func NewRemote(url string, client *http.Client) *Remote {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &Remote{url: url, client: client}
+}
+
+func (r *Remote) Decide(ctx context.Context, in Input) (Decision, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return Decision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Decision{}, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Decision{}, fmt.Errorf("%w: %s", ErrUnavailable, resp.Status)
+	}
+
+	var d Decision
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDecisionSize)).Decode(&d); err != nil {
+		return Decision{}, fmt.Errorf("%w: decode decision: %w", ErrUnavailable, err)
+	}
+	return d, nil
+}
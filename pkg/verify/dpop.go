@@ -0,0 +1,189 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	dpopType = "dpop+jwt"
+
+	defaultDPoPMaxAge = 5 * time.Minute
+	defaultLeeway     = 30 * time.Second
+)
+
+// asymmetricAlgs are the JWS algs accepted for DPoP proofs and access tokens.
+var asymmetricAlgs = []string{
+	"ES256", "ES384", "ES512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"EdDSA",
+}
+
+// DPoPAlgs returns the JWS algs accepted for DPoP proofs, as advertised in
+// dpop_signing_alg_values_supported.
+func DPoPAlgs() []string {
+	return slices.Clone(asymmetricAlgs)
+}
+
+// DPoPProof is a verified DPoP proof (RFC 9449).
+type DPoPProof struct {
+	JTI   string
+	HTM   string
+	HTU   string
+	IAT   time.Time
+	Nonce string
+	ATH   string
+	JWK   JWK
+	// JKT is the RFC 7638 thumbprint of JWK, the value bound to tokens as
+	// cnf.jkt.
+	JKT string
+}
+
+type DPoPOptions struct {
+	// Method and URL of the request the proof was sent with.
+	Method string
+	URL    string
+	// AccessToken, when set, must match the proof's ath claim.
+	AccessToken string
+	// MaxAge bounds how old iat may be. Defaults to 5 minutes.
+	MaxAge time.Duration
+	// Leeway allows iat slightly in the future. Defaults to 30 seconds.
+	Leeway time.Duration
+	// Nonce, when set, makes a nonce mandatory and reports whether a nonce
+	// is currently valid. Failures return ErrUseDPoPNonce.
+	Nonce func(nonce string) bool
+	// Replay, when set, rejects proofs whose jti was already used.
+	Replay ReplayCache
+	Now    func() time.Time
+}
+
+type dpopClaims struct {
+	HTM   string `json:"htm"`
+	HTU   string `json:"htu"`
+	Nonce string `json:"nonce,omitempty"`
+	ATH   string `json:"ath,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// VerifyDPoPProof checks the signature, type, method, URL, age, nonce and
+// replay state of a DPoP proof JWT.
+func VerifyDPoPProof(ctx context.Context, proof string, opts DPoPOptions) (*DPoPProof, error) {
+	if proof == "" {
+		return nil, fmt.Errorf("%w: missing DPoP header", ErrInvalidDPoPProof)
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = defaultDPoPMaxAge
+	}
+	if opts.Leeway <= 0 {
+		opts.Leeway = defaultLeeway
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	var jwk JWK
+	claims := &dpopClaims{}
+	_, err := jwt.ParseWithClaims(proof, claims, func(t *jwt.Token) (any, error) {
+		if typ, _ := t.Header["typ"].(string); typ != dpopType {
+			return nil, fmt.Errorf("typ must be %s", dpopType)
+		}
+		raw, err := json.Marshal(t.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &jwk); err != nil {
+			return nil, err
+		}
+		if jwk.D != "" {
+			return nil, fmt.Errorf("jwk must not contain a private key")
+		}
+		return jwk.PublicKey()
+	}, jwt.WithValidMethods(asymmetricAlgs), jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDPoPProof, err)
+	}
+
+	if claims.ID == "" {
+		return nil, fmt.Errorf("%w: missing jti", ErrInvalidDPoPProof)
+	}
+	if claims.HTM != opts.Method {
+		return nil, fmt.Errorf("%w: htm %q does not match %s", ErrInvalidDPoPProof, claims.HTM, opts.Method)
+	}
+	if !sameHTU(claims.HTU, opts.URL) {
+		return nil, fmt.Errorf("%w: htu %q does not match %s", ErrInvalidDPoPProof, claims.HTU, opts.URL)
+	}
+
+	if claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: missing iat", ErrInvalidDPoPProof)
+	}
+	now := opts.Now()
+	iat := claims.IssuedAt.Time
+	if iat.After(now.Add(opts.Leeway)) || iat.Before(now.Add(-opts.MaxAge)) {
+		return nil, fmt.Errorf("%w: iat outside the acceptable window", ErrInvalidDPoPProof)
+	}
+
+	if opts.AccessToken != "" {
+		sum := sha256.Sum256([]byte(opts.AccessToken))
+		ath := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(claims.ATH), []byte(ath)) != 1 {
+			return nil, fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+		}
+	}
+
+	if opts.Nonce != nil && (claims.Nonce == "" || !opts.Nonce(claims.Nonce)) {
+		return nil, ErrUseDPoPNonce
+	}
+
+	jkt, err := jwk.Thumbprint()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDPoPProof, err)
+	}
+
+	if opts.Replay != nil {
+		fresh, err := opts.Replay.Remember(ctx, "dpop:"+jkt+":"+claims.ID, opts.MaxAge+opts.Leeway)
+		if err != nil {
+			return nil, err
+		}
+		if !fresh {
+			return nil, ErrDPoPReplay
+		}
+	}
+
+	return &DPoPProof{
+		JTI:   claims.ID,
+		HTM:   claims.HTM,
+		HTU:   claims.HTU,
+		IAT:   iat,
+		Nonce: claims.Nonce,
+		ATH:   claims.ATH,
+		JWK:   jwk,
+		JKT:   jkt,
+	}, nil
+}
+
+// sameHTU compares URIs ignoring query, fragment and the case of the scheme
+// and host, as RFC 9449 section 4.3 requires.
+func sameHTU(htu, want string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(want)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
@@ -0,0 +1,22 @@
+package verify
+
+import "errors"
+
+var (
+	ErrUnsupportedKey = errors.New("verify: unsupported key")
+	ErrUnknownKID     = errors.New("verify: unknown kid")
+	ErrInvalidToken   = errors.New("verify: invalid token")
+	ErrMissingToken   = errors.New("verify: missing access token")
+
+	ErrInvalidDPoPProof = errors.New("verify: invalid dpop proof")
+	// ErrUseDPoPNonce means the proof lacks a valid server nonce; the
+	// client should retry with the nonce from the DPoP-Nonce header.
+	ErrUseDPoPNonce   = errors.New("verify: dpop nonce required")
+	ErrDPoPReplay     = errors.New("verify: dpop proof replayed")
+	ErrDPoPBinding    = errors.New("verify: token is not bound to the dpop key")
+	ErrTokenBound     = errors.New("verify: sender-constrained token presented as bearer")
+	ErrUnboundDPoP    = errors.New("verify: dpop scheme used with an unbound token")
+	ErrInvalidRequest = errors.New("verify: invalid authorization header")
+
+	ErrCertificateBinding = errors.New("verify: token is not bound to the client certificate")
+)
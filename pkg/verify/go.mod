@@ -0,0 +1,11 @@
+module github.com/yokeTH/yoketh-backend-oss/pkg/verify
+
+go 1.25.0
+
+require (
+	github.com/go-jose/go-jose/v4 v4.1.4
+	github.com/golang-jwt/jwt/v5 v5.3.0
+	golang.org/x/crypto v0.41.0
+)
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,48 @@
+package verify
+
+import (
+	"crypto"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+var (
+	keyAlgs     = []jose.KeyAlgorithm{jose.ECDH_ES_A256KW, jose.RSA_OAEP_256}
+	contentEncs = []jose.ContentEncryption{jose.A256GCM}
+)
+
+// WithDecryptionKey lets the verifier accept nested tokens (signed, then
+// encrypted with ECDH-ES+A256KW or RSA-OAEP-256 and A256GCM) issued for a
+// resource server that registered the public half of key, an
+// *ecdsa.PrivateKey or *rsa.PrivateKey. Tokens that are only signed are
+// still accepted.
+func WithDecryptionKey(key crypto.PrivateKey) Option {
+	return func(v *Verifier) { v.decryptKey = key }
+}
+
+// isEncrypted reports whether token is in JWE compact serialization, which
+// has five parts where a JWS has three.
+func isEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// decrypt returns the signed token nested in an encrypted one.
+func (v *Verifier) decrypt(token string) (string, error) {
+	if v.decryptKey == nil {
+		return "", fmt.Errorf("%w: encrypted token but no decryption key", ErrInvalidToken)
+	}
+	obj, err := jose.ParseEncryptedCompact(token, keyAlgs, contentEncs)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if cty, _ := obj.Header.ExtraHeaders[jose.HeaderContentType].(string); !strings.EqualFold(cty, "JWT") {
+		return "", fmt.Errorf("%w: encrypted content is not a JWT", ErrInvalidToken)
+	}
+	plain, err := obj.Decrypt(v.decryptKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return string(plain), nil
+}
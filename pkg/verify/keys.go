@@ -0,0 +1,121 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// KeySource resolves the public key an access token was signed with.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (JWK, error)
+}
+
+// StaticKeys is a KeySource backed by a fixed JWKS.
+type StaticKeys JWKS
+
+func (s StaticKeys) Key(ctx context.Context, kid string) (JWK, error) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, nil
+		}
+	}
+	return JWK{}, fmt.Errorf("%w: %q", ErrUnknownKID, kid)
+}
+
+const (
+	defaultJWKSTTL        = 10 * time.Minute
+	defaultJWKSMinRefresh = 30 * time.Second
+)
+
+// RemoteJWKS is a KeySource that fetches a JWKS document over HTTP, caches it
+// for a TTL and refetches early when it meets an unknown kid, so rotated keys
+// are picked up without waiting for the cache to expire.
+type RemoteJWKS struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	// decode reads the fetched document, a JWKS unless set.
+	decode func(io.Reader) ([]JWK, error)
+
+	mu      sync.Mutex
+	keys    map[string]JWK
+	fetched time.Time
+}
+
+func NewRemoteJWKS(url string, client *http.Client, ttl time.Duration) *RemoteJWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if ttl <= 0 {
+		ttl = defaultJWKSTTL
+	}
+	return &RemoteJWKS{url: url, client: client, ttl: ttl}
+}
+
+func (r *RemoteJWKS) Key(ctx context.Context, kid string) (JWK, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	age := time.Since(r.fetched)
+	if k, ok := r.keys[kid]; ok && age < r.ttl {
+		return k, nil
+	}
+	// Rate-limit refreshes triggered by unknown kids.
+	if r.keys == nil || age >= defaultJWKSMinRefresh {
+		if err := r.refresh(ctx); err != nil {
+			return JWK{}, err
+		}
+	}
+
+	if k, ok := r.keys[kid]; ok {
+		return k, nil
+	}
+	return JWK{}, fmt.Errorf("%w: %q", ErrUnknownKID, kid)
+}
+
+// refresh refetches the JWKS. r.mu must be held.
+func (r *RemoteJWKS) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("verify: fetch jwks: %s", res.Status)
+	}
+
+	decode := r.decode
+	if decode == nil {
+		decode = decodeJWKS
+	}
+	set, err := decode(res.Body)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]JWK, len(set))
+	for _, k := range set {
+		keys[k.Kid] = k
+	}
+	r.keys = keys
+	r.fetched = time.Now()
+	return nil
+}
+
+func decodeJWKS(body io.Reader) ([]JWK, error) {
+	var set JWKS
+	if err := json.NewDecoder(body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("verify: decode jwks: %w", err)
+	}
+	return set.Keys, nil
+}
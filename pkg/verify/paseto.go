@@ -0,0 +1,232 @@
+package verify
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/blake2b"
+)
+
+// AlgPASETOV4Public is the alg of a JWK holding a PASETO v4.public key.
+// Such keys are only used for PASETO tokens and never for JWTs.
+const AlgPASETOV4Public = "v4.public"
+
+const (
+	pasetoV4PublicHeader = "v4.public."
+	paserkPublicPrefix   = "k4.public."
+	paserkIDPrefix       = "k4.pid."
+	maxPASETOFooter      = 1024
+)
+
+// PASERK is a published PASETO public key: PID is its k4.pid identifier,
+// the kid in the footer of the tokens it signs, and PASERK is the
+// k4.public key itself.
+type PASERK struct {
+	PID    string `json:"pid"`
+	PASERK string `json:"paserk"`
+}
+
+// PASERKSet is the document served by the auth service's PASERK endpoint.
+type PASERKSet struct {
+	Keys []PASERK `json:"keys"`
+}
+
+// JWK returns the key as an OKP JWK with alg AlgPASETOV4Public, checking
+// that PID identifies it.
+func (p PASERK) JWK() (JWK, error) {
+	pub, err := ParsePASERKPublic(p.PASERK)
+	if err != nil {
+		return JWK{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(PASERKID(pub)), []byte(p.PID)) != 1 {
+		return JWK{}, fmt.Errorf("%w: pid does not match paserk", ErrUnsupportedKey)
+	}
+	return JWK{
+		Kty: "OKP",
+		Use: "sig",
+		Alg: AlgPASETOV4Public,
+		Kid: p.PID,
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(pub),
+	}, nil
+}
+
+// ParsePASERKPublic decodes a k4.public PASERK.
+func ParsePASERKPublic(s string) (ed25519.PublicKey, error) {
+	data, ok := strings.CutPrefix(s, paserkPublicPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a k4.public paserk", ErrUnsupportedKey)
+	}
+	pub, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: malformed k4.public paserk", ErrUnsupportedKey)
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+// PASERKID returns the k4.pid PASERK identifying pub.
+func PASERKID(pub ed25519.PublicKey) string {
+	d, _ := blake2b.New(33, nil)
+	d.Write([]byte(paserkIDPrefix))
+	d.Write([]byte(paserkPublicPrefix + base64.RawURLEncoding.EncodeToString(pub)))
+	return paserkIDPrefix + base64.RawURLEncoding.EncodeToString(d.Sum(nil))
+}
+
+// WithPASETOKeys lets the verifier accept PASETO v4.public access tokens,
+// resolving the kid of their footer through keys, e.g. a RemoteJWKS from
+// NewRemotePASERK. Only keys with alg AlgPASETOV4Public are used.
+func WithPASETOKeys(keys KeySource) Option {
+	return func(v *Verifier) { v.pasetoKeys = keys }
+}
+
+// NewRemotePASERK returns a RemoteJWKS that reads the auth service's PASERK
+// endpoint instead of a JWKS, for use with WithPASETOKeys.
+func NewRemotePASERK(url string, client *http.Client, ttl time.Duration) *RemoteJWKS {
+	r := NewRemoteJWKS(url, client, ttl)
+	r.decode = decodePASERKSet
+	return r
+}
+
+func decodePASERKSet(body io.Reader) ([]JWK, error) {
+	var set PASERKSet
+	if err := json.NewDecoder(body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("verify: decode paserk set: %w", err)
+	}
+	keys := make([]JWK, 0, len(set.Keys))
+	for _, p := range set.Keys {
+		k, err := p.JWK()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func isPASETO(token string) bool {
+	return strings.HasPrefix(token, pasetoV4PublicHeader)
+}
+
+// verifyPASETO checks a v4.public token and its registered claims. The
+// footer must be a JSON object naming the key in kid.
+func (v *Verifier) verifyPASETO(ctx context.Context, token string) (*Claims, error) {
+	if v.pasetoKeys == nil {
+		return nil, fmt.Errorf("%w: paseto tokens are not accepted", ErrInvalidToken)
+	}
+
+	body, footerB64, _ := strings.Cut(strings.TrimPrefix(token, pasetoV4PublicHeader), ".")
+	if strings.Contains(footerB64, ".") || len(footerB64) > base64.RawURLEncoding.EncodedLen(maxPASETOFooter) {
+		return nil, fmt.Errorf("%w: malformed paseto", ErrInvalidToken)
+	}
+	sm, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil || len(sm) < ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: malformed paseto", ErrInvalidToken)
+	}
+	footer, err := base64.RawURLEncoding.DecodeString(footerB64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed paseto footer", ErrInvalidToken)
+	}
+	var f struct {
+		KID string `json:"kid"`
+	}
+	if err := json.Unmarshal(footer, &f); err != nil || f.KID == "" {
+		return nil, fmt.Errorf("%w: paseto footer has no kid", ErrInvalidToken)
+	}
+
+	jwk, err := v.pasetoKeys.Key(ctx, f.KID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if jwk.Alg != AlgPASETOV4Public {
+		return nil, fmt.Errorf("%w: key %q is not a paseto key", ErrInvalidToken, f.KID)
+	}
+	pub, err := jwk.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	edPub, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: key %q is not an Ed25519 key", ErrInvalidToken, f.KID)
+	}
+
+	m, sig := sm[:len(sm)-ed25519.SignatureSize], sm[len(sm)-ed25519.SignatureSize:]
+	if !ed25519.Verify(edPub, pae([]byte(pasetoV4PublicHeader), m, footer, nil), sig) {
+		return nil, fmt.Errorf("%w: paseto signature", ErrInvalidToken)
+	}
+
+	claims, err := pasetoClaims(m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(v.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(v.now),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+	if err := jwt.NewValidator(opts...).Validate(claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// pasetoClaims decodes a PASETO payload, whose exp, iat and nbf are RFC 3339
+// strings, into Claims.
+func pasetoClaims(payload []byte) (*Claims, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"exp", "iat", "nbf"} {
+		v, ok := raw[name]
+		if !ok {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			return nil, fmt.Errorf("%s is not a string", name)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		raw[name], _ = json.Marshal(jwt.NewNumericDate(t))
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(b, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// pae is the pre-authentication encoding of PASETO.
+func pae(pieces ...[]byte) []byte {
+	out := binary.LittleEndian.AppendUint64(nil, uint64(len(pieces))&math.MaxInt64)
+	for _, p := range pieces {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(p))&math.MaxInt64)
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package verify
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReplayCache remembers one-time identifiers such as DPoP proof jti values.
+type ReplayCache interface {
+	// Remember records id for ttl and reports whether it was not seen
+	// before.
+	Remember(ctx context.Context, id string, ttl time.Duration) (fresh bool, err error)
+}
+
+// MemoryReplayCache is a ReplayCache for a single process.
+type MemoryReplayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (c *MemoryReplayCache) Remember(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, exp := range c.seen {
+		if now.After(exp) {
+			delete(c.seen, k)
+		}
+	}
+
+	if _, ok := c.seen[id]; ok {
+		return false, nil
+	}
+	c.seen[id] = now.Add(ttl)
+	return true, nil
+}
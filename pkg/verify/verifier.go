@@ -0,0 +1,364 @@
+// Package verify validates access tokens issued by the auth service,
+// including encrypted (JWE), PASETO v4.public, sender-constrained DPoP
+// (RFC 9449) and certificate-bound (RFC 8705) tokens, for use by resource
+// servers.
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Confirmation is the cnf claim (RFC 7800) binding a token to a key.
+type Confirmation struct {
+	// JKT is the JWK thumbprint of a DPoP key (RFC 9449).
+	JKT string `json:"jkt,omitempty"`
+	// X5TS256 is the SHA-256 thumbprint of a mutual-TLS client
+	// certificate (RFC 8705).
+	X5TS256 string `json:"x5t#S256,omitempty"`
+}
+
+// Actor is the act claim (RFC 8693) of a delegated token. The outermost
+// Actor is the current actor; nested ones are prior links of the chain.
+type Actor struct {
+	Sub string `json:"sub"`
+	Act *Actor `json:"act,omitempty"`
+}
+
+// TokenTypeAccessToken is the typ header of JWT access tokens (RFC 9068
+// section 2.1).
+const TokenTypeAccessToken = "at+jwt"
+
+// Claims mirrors the claims the auth service puts in access tokens.
+type Claims struct {
+	Scopes      []string         `json:"scopes,omitempty"`
+	Scope       string           `json:"scope,omitempty"`
+	ClientID    string           `json:"client_id,omitempty"`
+	AuthTime    *jwt.NumericDate `json:"auth_time,omitempty"`
+	ACR         string           `json:"acr,omitempty"`
+	AMR         []string         `json:"amr,omitempty"`
+	SID         string           `json:"sid,omitempty"`
+	Permissions []string         `json:"permissions,omitempty"`
+	Cnf         *Confirmation    `json:"cnf,omitempty"`
+	Act         *Actor           `json:"act,omitempty"`
+	jwt.RegisteredClaims
+	// Extra holds the claims without a field above.
+	Extra map[string]any `json:"-"`
+}
+
+// claimNames are the claims with a field in Claims.
+var claimNames = []string{
+	"scopes", "scope", "client_id", "auth_time", "acr", "amr", "sid", "permissions", "cnf", "act",
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti",
+}
+
+func (c *Claims) UnmarshalJSON(b []byte) error {
+	type plain Claims
+	if err := json.Unmarshal(b, (*plain)(c)); err != nil {
+		return err
+	}
+	var all map[string]any
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	for _, name := range claimNames {
+		delete(all, name)
+	}
+	c.Extra = nil
+	if len(all) > 0 {
+		c.Extra = all
+	}
+	return nil
+}
+
+// Map returns every claim by name, with numbers as float64 as in the JSON
+// payload, e.g. for evaluating policies over them.
+func (c *Claims) Map() map[string]any {
+	m := make(map[string]any, len(c.Extra)+len(claimNames))
+	for name, v := range c.Extra {
+		m[name] = v
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return m
+	}
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+type Verifier struct {
+	keys       KeySource
+	pasetoKeys KeySource
+	issuer     string
+	audience   string
+	tokenType  string
+	decryptKey crypto.PrivateKey
+	leeway     time.Duration
+	dpopMaxAge time.Duration
+	dpopNonce  func(string) bool
+	replay     ReplayCache
+	now        func() time.Time
+}
+
+type Option func(*Verifier)
+
+func WithIssuer(iss string) Option {
+	return func(v *Verifier) { v.issuer = iss }
+}
+
+func WithAudience(aud string) Option {
+	return func(v *Verifier) { v.audience = aud }
+}
+
+// WithTokenType requires the typ header to be typ, such as
+// TokenTypeAccessToken, so that other JWTs signed by the same keys (ID
+// tokens, logout tokens) are not accepted as access tokens (RFC 9068 section
+// 4).
+func WithTokenType(typ string) Option {
+	return func(v *Verifier) { v.tokenType = typ }
+}
+
+func WithLeeway(d time.Duration) Option {
+	return func(v *Verifier) { v.leeway = d }
+}
+
+func WithDPoPMaxAge(d time.Duration) Option {
+	return func(v *Verifier) { v.dpopMaxAge = d }
+}
+
+// WithDPoPNonce requires DPoP proofs to carry a nonce accepted by valid.
+func WithDPoPNonce(valid func(string) bool) Option {
+	return func(v *Verifier) { v.dpopNonce = valid }
+}
+
+// WithReplayCache rejects DPoP proofs whose jti was already seen. Without it
+// a MemoryReplayCache is used, which is only sufficient for a single
+// instance.
+func WithReplayCache(c ReplayCache) Option {
+	return func(v *Verifier) { v.replay = c }
+}
+
+func NewVerifier(keys KeySource, opts ...Option) *Verifier {
+	v := &Verifier{
+		keys:       keys,
+		leeway:     defaultLeeway,
+		dpopMaxAge: defaultDPoPMaxAge,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if v.replay == nil {
+		v.replay = NewMemoryReplayCache()
+	}
+	return v
+}
+
+// Verify checks the signature and registered claims of an access token,
+// decrypting it first when it is encrypted. PASETO tokens are only accepted
+// with WithPASETOKeys and have no typ to check. It does not check sender
+// constraints; use VerifyRequest for that.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if isPASETO(token) {
+		return v.verifyPASETO(ctx, token)
+	}
+	if isEncrypted(token) {
+		var err error
+		if token, err = v.decrypt(token); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(v.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(v.now),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+
+	claims := &Claims{}
+	if err := ParseSigned(ctx, token, v.keys, claims, opts...); err != nil {
+		return nil, err
+	}
+	if v.tokenType != "" && !hasType(token, v.tokenType) {
+		return nil, fmt.Errorf("%w: typ is not %s", ErrInvalidToken, v.tokenType)
+	}
+
+	return claims, nil
+}
+
+// ParseSigned verifies a JWT signed with an asymmetric alg by a key from keys,
+// selected by kid, and decodes it into claims. opts apply on top of the alg
+// restriction, e.g. to check iss or aud.
+func ParseSigned(ctx context.Context, token string, keys KeySource, claims jwt.Claims, opts ...jwt.ParserOption) error {
+	opts = append([]jwt.ParserOption{jwt.WithValidMethods(asymmetricAlgs)}, opts...)
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, err := keys.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if k.Alg == AlgPASETOV4Public {
+			return nil, fmt.Errorf("%w: key %q is a paseto key", ErrUnsupportedKey, kid)
+		}
+		return k.PublicKey()
+	}, opts...)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// hasType reports whether the typ header of token is typ, compared as a
+// media type whose "application/" prefix may be omitted (RFC 7515 section
+// 4.1.9).
+func hasType(token, typ string) bool {
+	t, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return false
+	}
+	got, _ := t.Header["typ"].(string)
+	got = strings.ToLower(got)
+	return strings.TrimPrefix(got, "application/") == strings.ToLower(typ)
+}
+
+// Request is the part of an HTTP request needed to verify a possibly
+// sender-constrained access token.
+type Request struct {
+	Authorization string
+	DPoP          string
+	Method        string
+	// URL is the absolute request URL as the client sees it.
+	URL string
+	// Certificate is the client certificate of the TLS connection, if any.
+	Certificate *x509.Certificate
+}
+
+// RequestFromHTTP builds a Request from r. Behind a proxy, r.Host and the
+// TLS state may not reflect the public URL; build the Request by hand then.
+func RequestFromHTTP(r *http.Request) Request {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	req := Request{
+		Authorization: r.Header.Get("Authorization"),
+		DPoP:          r.Header.Get("DPoP"),
+		Method:        r.Method,
+		URL:           scheme + "://" + r.Host + r.URL.EscapedPath(),
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		req.Certificate = r.TLS.PeerCertificates[0]
+	}
+	return req
+}
+
+// CertificateThumbprint returns the base64url encoded SHA-256 hash of the
+// DER encoding of cert, the x5t#S256 value.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyRequest verifies the access token in r. Tokens carrying cnf.jkt must
+// be presented with the DPoP scheme and a proof signed by the bound key;
+// other tokens must use the Bearer scheme. Tokens carrying cnf.x5t#S256 must
+// arrive over a connection authenticated with the bound certificate.
+func (v *Verifier) VerifyRequest(ctx context.Context, r Request) (*Claims, error) {
+	scheme, token, ok := strings.Cut(r.Authorization, " ")
+	if !ok || token == "" {
+		if r.Authorization == "" {
+			return nil, ErrMissingToken
+		}
+		return nil, ErrInvalidRequest
+	}
+
+	claims, err := v.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCertificate(claims, r.Certificate); err != nil {
+		return nil, err
+	}
+	bound := claims.Cnf != nil && claims.Cnf.JKT != ""
+
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		if bound {
+			return nil, ErrTokenBound
+		}
+		return claims, nil
+	case strings.EqualFold(scheme, "DPoP"):
+		if !bound {
+			return nil, ErrUnboundDPoP
+		}
+	default:
+		return nil, ErrInvalidRequest
+	}
+
+	proof, err := VerifyDPoPProof(ctx, r.DPoP, DPoPOptions{
+		Method:      r.Method,
+		URL:         r.URL,
+		AccessToken: token,
+		MaxAge:      v.dpopMaxAge,
+		Leeway:      v.leeway,
+		Nonce:       v.dpopNonce,
+		Replay:      v.replay,
+		Now:         v.now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if proof.JKT != claims.Cnf.JKT {
+		return nil, ErrDPoPBinding
+	}
+
+	return claims, nil
+}
+
+func checkCertificate(claims *Claims, cert *x509.Certificate) error {
+	if claims.Cnf == nil || claims.Cnf.X5TS256 == "" {
+		return nil
+	}
+	if cert == nil {
+		return ErrCertificateBinding
+	}
+	if subtle.ConstantTimeCompare([]byte(CertificateThumbprint(cert)), []byte(claims.Cnf.X5TS256)) != 1 {
+		return ErrCertificateBinding
+	}
+	return nil
+}
+
+// Challenge returns the WWW-Authenticate value for err per RFC 6750 and
+// RFC 9449 section 7.1.
+func Challenge(err error) string {
+	code := "invalid_token"
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		return `Bearer, DPoP algs="` + strings.Join(asymmetricAlgs, " ") + `"`
+	case errors.Is(err, ErrUseDPoPNonce):
+		code = "use_dpop_nonce"
+	case errors.Is(err, ErrInvalidDPoPProof), errors.Is(err, ErrDPoPReplay):
+		code = "invalid_dpop_proof"
+	case errors.Is(err, ErrInvalidRequest):
+		code = "invalid_request"
+	}
+	return `DPoP error="` + code + `", algs="` + strings.Join(asymmetricAlgs, " ") + `"`
+}